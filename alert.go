@@ -0,0 +1,157 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Critical conditions, as a set of flags
+type criticalAlert int
+
+const (
+	alertNodeDown criticalAlert = 1 << iota
+	alertKESExpiring
+	alertMissedSlots
+)
+
+// Returns the names of the conditions in the set
+func (a criticalAlert) String() string {
+	var names []string
+	if a&alertNodeDown != 0 {
+		names = append(names, "node unreachable")
+	}
+	if a&alertKESExpiring != 0 {
+		names = append(names, "KES expiring")
+	}
+	if a&alertMissedSlots != 0 {
+		names = append(names, "missed slots")
+	}
+	return strings.Join(names, ", ")
+}
+
+// Remaining KES periods at or below which KES expiry is critical
+const kesExpiringPeriods = 5
+
+// Minimum time between alert effects, so a flapping condition doesn't ring
+// the bell on every refresh
+const alertDebounce = 60 * time.Second
+
+// How long the footer stays inverted for an alert
+const alertFlashDuration = time.Second
+
+// Returns the critical conditions for the node, where missed slots are only
+// critical when they've increased since the last check
+func getCriticalAlerts(
+	metrics *PromMetrics,
+	nodeRole string,
+	failures uint32,
+	retries uint32,
+	lastMissedSlots uint64,
+) criticalAlert {
+	var alerts criticalAlert
+	if isNodeDown(failures, retries) {
+		alerts |= alertNodeDown
+	}
+	if metrics == nil || nodeRole != "Core" {
+		return alerts
+	}
+	if metrics.KesPeriod > 0 &&
+		metrics.RemainingKesPeriods <= kesExpiringPeriods {
+		alerts |= alertKESExpiring
+	}
+	if metrics.MissedSlots > lastMissedSlots {
+		alerts |= alertMissedSlots
+	}
+	return alerts
+}
+
+// Tracks the active critical conditions and when an alert last fired
+type alertState struct {
+	active    criticalAlert
+	lastFired time.Time
+}
+
+// Records the current critical conditions and returns whether an alert
+// should fire, which is when a new condition appears outside the debounce
+// window
+func (a *alertState) update(alerts criticalAlert, now time.Time) bool {
+	added := alerts &^ a.active
+	a.active = alerts
+	if added == 0 {
+		return false
+	}
+	if !a.lastFired.IsZero() && now.Sub(a.lastFired) < alertDebounce {
+		return false
+	}
+	a.lastFired = now
+	return true
+}
+
+var (
+	alerts          alertState
+	alertMissedLast uint64
+	alertMissedSeen bool
+	// Set when the bell should ring on the next draw
+	alertBellPending atomic.Bool
+)
+
+// Checks for critical conditions, recording an event and ringing the
+// terminal bell and/or flashing the footer, when enabled, as they appear
+func checkCriticalAlerts() {
+	cfg := config.GetConfig()
+	// Don't alert on slots missed before we started
+	if !alertMissedSeen && promMetrics != nil {
+		alertMissedLast = promMetrics.MissedSlots
+		alertMissedSeen = true
+	}
+	current := getCriticalAlerts(
+		promMetrics,
+		role,
+		promFailures.Load(),
+		cfg.App.Retries,
+		alertMissedLast,
+	)
+	if promMetrics != nil {
+		alertMissedLast = promMetrics.MissedSlots
+	}
+	if !alerts.update(current, time.Now()) {
+		return
+	}
+	recordEvent("Alert: " + current.String())
+	if cfg.App.AlertBell {
+		// The bell rings from the draw loop, which owns the screen
+		alertBellPending.Store(true)
+		app.Draw()
+	}
+	if cfg.App.AlertFlash {
+		app.QueueUpdateDraw(func() {
+			footerTextView.SetBackgroundColor(tcell.ColorRed)
+		})
+		time.AfterFunc(alertFlashDuration, func() {
+			app.QueueUpdateDraw(func() {
+				footerTextView.SetBackgroundColor(
+					tview.Styles.PrimitiveBackgroundColor,
+				)
+			})
+		})
+	}
+}
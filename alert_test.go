@@ -0,0 +1,180 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetCriticalAlerts(t *testing.T) {
+	testDefs := []struct {
+		name     string
+		metrics  *PromMetrics
+		role     string
+		failures uint32
+		missed   uint64
+		expected criticalAlert
+	}{
+		{
+			name:     "healthy",
+			metrics:  &PromMetrics{KesPeriod: 1123, RemainingKesPeriods: 42},
+			role:     "Core",
+			expected: 0,
+		},
+		// The node is down once half the retries have failed
+		{name: "one failure", failures: 1, expected: 0},
+		{name: "down", failures: 5, expected: alertNodeDown},
+		{
+			name:     "KES expiring",
+			metrics:  &PromMetrics{KesPeriod: 1123, RemainingKesPeriods: 5},
+			role:     "Core",
+			expected: alertKESExpiring,
+		},
+		// Without a KES period, the node isn't forging
+		{
+			name:     "no KES",
+			metrics:  &PromMetrics{RemainingKesPeriods: 0},
+			role:     "Core",
+			expected: 0,
+		},
+		{
+			name: "missed slots",
+			metrics: &PromMetrics{
+				KesPeriod:           1123,
+				RemainingKesPeriods: 42,
+				MissedSlots:         4,
+			},
+			role:     "Core",
+			missed:   3,
+			expected: alertMissedSlots,
+		},
+		{
+			name: "no new missed slots",
+			metrics: &PromMetrics{
+				KesPeriod:           1123,
+				RemainingKesPeriods: 42,
+				MissedSlots:         3,
+			},
+			role:     "Core",
+			missed:   3,
+			expected: 0,
+		},
+		// Forging conditions only apply to block producers
+		{
+			name: "relay",
+			metrics: &PromMetrics{
+				KesPeriod:           1123,
+				RemainingKesPeriods: 1,
+				MissedSlots:         4,
+			},
+			role:     "Relay",
+			expected: 0,
+		},
+		{
+			name: "all",
+			metrics: &PromMetrics{
+				KesPeriod:           1123,
+				RemainingKesPeriods: 1,
+				MissedSlots:         4,
+			},
+			role:     "Core",
+			failures: 5,
+			expected: alertNodeDown | alertKESExpiring | alertMissedSlots,
+		},
+	}
+	for _, testDef := range testDefs {
+		got := getCriticalAlerts(
+			testDef.metrics,
+			testDef.role,
+			testDef.failures,
+			10,
+			testDef.missed,
+		)
+		if got != testDef.expected {
+			t.Errorf(
+				"%s: got %q, expected %q",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestCriticalAlertString(t *testing.T) {
+	testDefs := []struct {
+		alerts   criticalAlert
+		expected string
+	}{
+		{alerts: 0, expected: ""},
+		{alerts: alertNodeDown, expected: "node unreachable"},
+		{
+			alerts:   alertKESExpiring | alertMissedSlots,
+			expected: "KES expiring, missed slots",
+		},
+	}
+	for _, testDef := range testDefs {
+		if got := testDef.alerts.String(); got != testDef.expected {
+			t.Errorf("got %q, expected %q", got, testDef.expected)
+		}
+	}
+}
+
+func TestAlertStateUpdate(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var state alertState
+	testDefs := []struct {
+		alerts   criticalAlert
+		offset   time.Duration
+		expected bool
+	}{
+		// Nothing fires without a critical condition
+		{alerts: 0, offset: 0, expected: false},
+		{alerts: alertNodeDown, offset: 0, expected: true},
+		// A condition fires once while it stays active
+		{alerts: alertNodeDown, offset: 10 * time.Second, expected: false},
+		// A new condition within the debounce window doesn't fire
+		{
+			alerts:   alertNodeDown | alertKESExpiring,
+			offset:   20 * time.Second,
+			expected: false,
+		},
+		// A flapping condition doesn't fire again until the window passes
+		{alerts: 0, offset: 30 * time.Second, expected: false},
+		{alerts: alertNodeDown, offset: 40 * time.Second, expected: false},
+		{alerts: 0, offset: 50 * time.Second, expected: false},
+		{alerts: alertNodeDown, offset: 61 * time.Second, expected: true},
+		// Another condition appearing after the window fires
+		{
+			alerts:   alertNodeDown | alertMissedSlots,
+			offset:   3 * time.Minute,
+			expected: true,
+		},
+	}
+	for i, testDef := range testDefs {
+		got := state.update(testDef.alerts, start.Add(testDef.offset))
+		if got != testDef.expected {
+			t.Errorf(
+				"%d: %q at %s: got %v, expected %v",
+				i,
+				testDef.alerts,
+				testDef.offset,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
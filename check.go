@@ -0,0 +1,89 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Result of a health check, for container probes
+type checkResult struct {
+	Synced  bool   `json:"synced"`
+	Status  string `json:"status"`
+	Block   uint64 `json:"block"`
+	Slot    uint64 `json:"slot"`
+	TipRef  uint64 `json:"tipRef"`
+	TipDiff uint64 `json:"tipDiff"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Returns the health check result for node metrics and a tip reference
+func getCheckResult(metrics *PromMetrics, tipRef uint64) checkResult {
+	if metrics == nil {
+		return checkResult{Status: "UNKNOWN"}
+	}
+	ret := checkResult{
+		Block:  metrics.BlockNum,
+		Slot:   metrics.SlotNum,
+		TipRef: tipRef,
+	}
+	if metrics.SlotNum == 0 {
+		ret.Status = "STARTING"
+		return ret
+	}
+	ret.TipDiff = getTipDiff(metrics, tipRef)
+	ret.Status = getTipDiffBucket(ret.TipDiff).String()
+	ret.Synced = isSynced(metrics, tipRef)
+	return ret
+}
+
+// Scrapes the node once and writes a one line status, returning the exit
+// code, which is 0 only when the node is synced
+func runCheck(ctx context.Context, w io.Writer, jsonOutput bool) int {
+	var result checkResult
+	metrics, err := getPromMetrics(ctx)
+	if err != nil {
+		result = checkResult{
+			Status: "UNREACHABLE",
+			Error:  strings.TrimSpace(err.Error()),
+		}
+	} else {
+		result = getCheckResult(metrics, getSlotTipRef())
+	}
+	if jsonOutput {
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			return 1
+		}
+	} else if result.Error != "" {
+		fmt.Fprintf(w, "%s: %s\n", result.Status, result.Error)
+	} else {
+		fmt.Fprintf(
+			w,
+			"%s: block %d, slot %d, tip diff %d\n",
+			result.Status,
+			result.Block,
+			result.Slot,
+			result.TipDiff,
+		)
+	}
+	if !result.Synced {
+		return 1
+	}
+	return 0
+}
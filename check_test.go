@@ -0,0 +1,209 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetCheckResult(t *testing.T) {
+	setSyncedThreshold(t, 20)
+	testDefs := []struct {
+		name     string
+		metrics  *PromMetrics
+		tipRef   uint64
+		expected checkResult
+	}{
+		{
+			name:     "no metrics",
+			tipRef:   1000,
+			expected: checkResult{Status: "UNKNOWN"},
+		},
+		{
+			name:    "starting",
+			metrics: &PromMetrics{},
+			tipRef:  1000,
+			expected: checkResult{
+				Status: "STARTING",
+				TipRef: 1000,
+			},
+		},
+		{
+			name:    "synced",
+			metrics: &PromMetrics{BlockNum: 10, SlotNum: 990},
+			tipRef:  1000,
+			expected: checkResult{
+				Synced:  true,
+				Status:  "OK",
+				Block:   10,
+				Slot:    990,
+				TipRef:  1000,
+				TipDiff: 10,
+			},
+		},
+		// The node's tip can be ahead of our clock
+		{
+			name:    "ahead",
+			metrics: &PromMetrics{BlockNum: 10, SlotNum: 1002},
+			tipRef:  1000,
+			expected: checkResult{
+				Synced: true,
+				Status: "OK",
+				Block:  10,
+				Slot:   1002,
+				TipRef: 1000,
+			},
+		},
+		{
+			name:    "syncing",
+			metrics: &PromMetrics{BlockNum: 10, SlotNum: 100},
+			tipRef:  1000,
+			expected: checkResult{
+				Status:  "SYNCING",
+				Block:   10,
+				Slot:    100,
+				TipRef:  1000,
+				TipDiff: 900,
+			},
+		},
+	}
+	for _, testDef := range testDefs {
+		got := getCheckResult(testDef.metrics, testDef.tipRef)
+		if got != testDef.expected {
+			t.Errorf(
+				"%s: got %+v, expected %+v",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+// Serves metrics for a node at a slot, with the clock at a tip diff from it
+func setCheckFixture(t *testing.T, slot uint64, tipDiff uint64) {
+	t.Helper()
+	oldSource := metricsSource
+	oldNow := timeNow
+	oldFailCount := failCount
+	t.Cleanup(func() {
+		metricsSource = oldSource
+		timeNow = oldNow
+		failCount = oldFailCount
+		scrapeFailures = 0
+		scrapeErrLast = ""
+	})
+	setTestGenesis(t, "mainnet")
+	setSyncedThreshold(t, 20)
+	metricsSource = &fakeMetricsSource{
+		data: fmt.Sprintf(
+			"cardano_node_metrics_blockNum_int 11612345\n"+
+				"cardano_node_metrics_slotNum_int %d\n",
+			slot,
+		),
+		status: 200,
+	}
+	// The tip reference is a second behind the clock
+	now := getSlotTime(slot + tipDiff).Add(time.Second)
+	timeNow = func() time.Time { return now }
+}
+
+func TestRunCheck(t *testing.T) {
+	testDefs := []struct {
+		tipDiff  uint64
+		expected string
+		code     int
+	}{
+		{
+			tipDiff:  0,
+			expected: "OK: block 11612345, slot 150121185, tip diff 0\n",
+			code:     0,
+		},
+		{
+			tipDiff:  20,
+			expected: "OK: block 11612345, slot 150121185, tip diff 20\n",
+			code:     0,
+		},
+		{
+			tipDiff:  21,
+			expected: "SLOW: block 11612345, slot 150121185, tip diff 21\n",
+			code:     1,
+		},
+		{
+			tipDiff: 5000,
+			expected: "SYNCING: block 11612345, slot 150121185," +
+				" tip diff 5000\n",
+			code: 1,
+		},
+	}
+	for _, testDef := range testDefs {
+		setCheckFixture(t, 150121185, testDef.tipDiff)
+		var buf bytes.Buffer
+		code := runCheck(context.Background(), &buf, false)
+		if code != testDef.code || buf.String() != testDef.expected {
+			t.Errorf(
+				"tip diff %d: got %d %q, expected %d %q",
+				testDef.tipDiff,
+				code,
+				buf.String(),
+				testDef.code,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestRunCheckJSON(t *testing.T) {
+	setCheckFixture(t, 150121185, 5)
+	var buf bytes.Buffer
+	if code := runCheck(context.Background(), &buf, true); code != 0 {
+		t.Errorf("got exit code %d, expected 0", code)
+	}
+	var got checkResult
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := checkResult{
+		Synced:  true,
+		Status:  "OK",
+		Block:   11612345,
+		Slot:    150121185,
+		TipRef:  150121190,
+		TipDiff: 5,
+	}
+	if got != expected {
+		t.Errorf("got %+v, expected %+v", got, expected)
+	}
+}
+
+func TestRunCheckUnreachable(t *testing.T) {
+	setCheckFixture(t, 150121185, 0)
+	setLogCapture(t)
+	metricsSource = &fakeMetricsSource{err: errors.New("connection refused")}
+	var buf bytes.Buffer
+	if code := runCheck(context.Background(), &buf, false); code != 1 {
+		t.Errorf("got exit code %d, expected 1", code)
+	}
+	expected := "UNREACHABLE: Failed getNodeMetrics: connection refused\n"
+	if buf.String() != expected {
+		t.Errorf("got %q, expected %q", buf.String(), expected)
+	}
+}
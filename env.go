@@ -18,13 +18,30 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/blinklabs-io/nview/internal/config"
 )
 
+// HTTP client for scraping node metrics, which keeps connections alive
+// between scrapes
+var metricsClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          2,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+	},
+}
+
 // Fetches the node metrics and return a byte array
 func getNodeMetrics(ctx context.Context) ([]byte, int, error) {
 	// Load our config and get host/port
@@ -44,7 +61,7 @@ func getNodeMetrics(ctx context.Context) ([]byte, int, error) {
 	if err != nil {
 		return respBodyBytes, http.StatusInternalServerError, err
 	}
-	// Set a 3 second timeout
+	// Set our configured timeout, which is never more than our refresh
 	ctx, cancel := context.WithTimeout(
 		ctx,
 		time.Second*time.Duration(cfg.Prometheus.Timeout),
@@ -52,7 +69,7 @@ func getNodeMetrics(ctx context.Context) ([]byte, int, error) {
 	defer cancel()
 	req = req.WithContext(ctx)
 	// Get metrics from the node
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := metricsClient.Do(req)
 	if err != nil {
 		return respBodyBytes, http.StatusInternalServerError, err
 	}
@@ -61,38 +78,163 @@ func getNodeMetrics(ctx context.Context) ([]byte, int, error) {
 			"empty response",
 		)
 	}
-	// Read the entire response body and close it to prevent a memory leak
+	// Close the response body to prevent a memory leak and allow the
+	// connection to be reused
+	defer resp.Body.Close()
+	// Read the entire response body
 	respBodyBytes, err = io.ReadAll(resp.Body)
 	if err != nil {
 		return respBodyBytes, http.StatusInternalServerError, err
 	}
-	defer resp.Body.Close()
 	return respBodyBytes, resp.StatusCode, nil
 }
 
 // Calculate slot number
 func getSlotTipRef() uint64 {
+	return getSlotAtTime(time.Unix(timeNow().Unix()-1, 0))
+}
+
+// Calculate the slot number at a wall-clock time
+func getSlotAtTime(t time.Time) uint64 {
+	cfg := config.GetConfig()
+	// Guard against division by zero with unpopulated genesis values
+	if cfg.Node.ByronGenesis.SlotLength == 0 ||
+		cfg.Node.ShelleyGenesis.SlotLength == 0 {
+		return 0
+	}
+	// We can't have a slot before the UNIX epoch
+	if t.UnixMilli() < 0 {
+		return 0
+	}
+	currentTimeMs := uint64(t.UnixMilli())
+	startTimeMs := cfg.Node.ByronGenesis.StartTime * 1000
+	// We can't have a tip before genesis
+	if currentTimeMs < startTimeMs {
+		return 0
+	}
+	byronSlots := uint64(
+		cfg.Node.ShelleyTransEpoch,
+	) * cfg.Node.ByronGenesis.EpochLength
+	byronEndTimeMs := startTimeMs + (byronSlots * cfg.Node.ByronGenesis.SlotLength)
+	if currentTimeMs < byronEndTimeMs {
+		return (currentTimeMs - startTimeMs) / cfg.Node.ByronGenesis.SlotLength
+	}
+	return byronSlots + ((currentTimeMs - byronEndTimeMs) / cfg.Node.ShelleyGenesis.SlotLength)
+}
+
+// Calculate the epoch containing a slot
+func getEpochFromSlot(slot uint64) uint64 {
 	cfg := config.GetConfig()
-	currentTimeSec := uint64(time.Now().Unix() - 1)
+	// Guard against division by zero with unpopulated genesis values
+	if cfg.Node.ByronGenesis.EpochLength == 0 ||
+		cfg.Node.ShelleyGenesis.EpochLength == 0 ||
+		cfg.Node.ShelleyTransEpoch < 0 {
+		return 0
+	}
 	byronSlots := uint64(
 		cfg.Node.ShelleyTransEpoch,
 	) * cfg.Node.ByronGenesis.EpochLength
-	byronEndTime := cfg.Node.ByronGenesis.StartTime + ((uint64(cfg.Node.ShelleyTransEpoch) * cfg.Node.ByronGenesis.EpochLength * cfg.Node.ByronGenesis.SlotLength) / 1000)
-	if currentTimeSec < byronEndTime {
-		return ((currentTimeSec - cfg.Node.ByronGenesis.StartTime) * 1000) / cfg.Node.ByronGenesis.SlotLength
+	if slot < byronSlots {
+		return slot / cfg.Node.ByronGenesis.EpochLength
+	}
+	return uint64(cfg.Node.ShelleyTransEpoch) +
+		((slot - byronSlots) / cfg.Node.ShelleyGenesis.EpochLength)
+}
+
+// Calculate the current epoch from the wall clock and genesis values
+func computeEpochFromSlot() uint64 {
+	slot := getSlotTipRef()
+	if slot == 0 {
+		return 0
 	}
-	return byronSlots + ((currentTimeSec - byronEndTime) / uint64(cfg.Node.ShelleyGenesis.SlotLength/1000))
+	return getEpochFromSlot(slot)
+}
+
+// Calculate wall-clock time of a slot
+func getSlotTime(slot uint64) time.Time {
+	cfg := config.GetConfig()
+	byronSlots := uint64(
+		cfg.Node.ShelleyTransEpoch,
+	) * cfg.Node.ByronGenesis.EpochLength
+	startTimeMs := cfg.Node.ByronGenesis.StartTime * 1000
+	if slot < byronSlots {
+		return time.UnixMilli(
+			int64(startTimeMs + (slot * cfg.Node.ByronGenesis.SlotLength)),
+		)
+	}
+	byronEndTimeMs := startTimeMs + (byronSlots * cfg.Node.ByronGenesis.SlotLength)
+	return time.UnixMilli(
+		int64(byronEndTimeMs + ((slot - byronSlots) * cfg.Node.ShelleyGenesis.SlotLength)),
+	)
+}
+
+// Writes the UTC time of a slot on the configured network, returning the exit
+// code
+func runSlotToTime(w io.Writer, slotArg string) int {
+	slot, err := strconv.ParseUint(strings.TrimSpace(slotArg), 10, 64)
+	if err != nil {
+		fmt.Fprintf(w, "invalid slot: %s\n", slotArg)
+		return 1
+	}
+	cfg := config.GetConfig()
+	if !hasGenesisValues() {
+		fmt.Fprintf(
+			w,
+			"unknown genesis values for network: %s\n",
+			cfg.Node.Network,
+		)
+		return 1
+	}
+	fmt.Fprintln(w, getSlotTime(slot).UTC().Format(time.RFC3339))
+	return 0
+}
+
+// Writes the slot at a time in RFC 3339 format on the configured network,
+// returning the exit code
+func runTimeToSlot(w io.Writer, timeArg string) int {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(timeArg))
+	if err != nil {
+		fmt.Fprintf(w, "invalid time: %s\n", timeArg)
+		return 1
+	}
+	cfg := config.GetConfig()
+	if !hasGenesisValues() {
+		fmt.Fprintf(
+			w,
+			"unknown genesis values for network: %s\n",
+			cfg.Node.Network,
+		)
+		return 1
+	}
+	if t.Unix() < int64(cfg.Node.ByronGenesis.StartTime) {
+		fmt.Fprintf(w, "time is before genesis: %s\n", timeArg)
+		return 1
+	}
+	fmt.Fprintln(w, getSlotAtTime(t))
+	return 0
+}
+
+// Returns whether the genesis values needed to convert between slots and
+// times are known for the configured network
+func hasGenesisValues() bool {
+	cfg := config.GetConfig()
+	return cfg.Node.ByronGenesis.StartTime != 0 &&
+		cfg.Node.ByronGenesis.SlotLength != 0 &&
+		cfg.Node.ShelleyGenesis.SlotLength != 0 &&
+		cfg.Node.ShelleyTransEpoch >= 0
 }
 
 // Time is in seconds
 func timeFromSeconds(t uint64) string {
-	d := t / 60 / 60 / 24
-	h := math.Mod(float64(t/60/60), 24)
-	m := math.Mod(float64(t/60), 60)
-	s := math.Mod(float64(t), 60)
+	// Use integer math throughout, since float64 loses precision for large
+	// values
+	d := t / 86400
+	h := (t / 3600) % 24
+	m := (t / 60) % 60
+	s := t % 60
 	var result string
 	if d > 0 {
 		result = fmt.Sprintf("%dd ", d)
 	}
-	return fmt.Sprintf("%s%02d:%02d:%02d", result, int(h), int(m), int(s))
+	return fmt.Sprintf("%s%02d:%02d:%02d", result, h, m, s)
 }
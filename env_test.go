@@ -0,0 +1,464 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// Sets the genesis values for a network for the duration of a test
+func setTestGenesis(t *testing.T, network string) {
+	t.Helper()
+	cfg := config.GetConfig()
+	oldNode := cfg.Node
+	t.Cleanup(func() {
+		cfg.Node = oldNode
+	})
+	cfg.Node.Network = network
+	cfg.Node.ByronGenesis.SlotLength = 20000
+	cfg.Node.ShelleyGenesis.SlotLength = 1000
+	switch network {
+	case "mainnet":
+		cfg.Node.ByronGenesis.StartTime = 1506203091
+		cfg.Node.ByronGenesis.EpochLength = 21600
+		cfg.Node.ShelleyGenesis.EpochLength = 432000
+		cfg.Node.ShelleyTransEpoch = 208
+	case "preview":
+		cfg.Node.ByronGenesis.StartTime = 1666656000
+		cfg.Node.ByronGenesis.EpochLength = 4320
+		cfg.Node.ShelleyGenesis.EpochLength = 86400
+		cfg.Node.ShelleyTransEpoch = 0
+	default:
+		t.Fatalf("no genesis values for network: %s", network)
+	}
+}
+
+// Parses an RFC 3339 time for a test
+func mustParseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	ret, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return ret
+}
+
+func TestGetSlotTime(t *testing.T) {
+	testDefs := []struct {
+		network  string
+		slot     uint64
+		expected string
+	}{
+		// Byron genesis
+		{"mainnet", 0, "2017-09-23T21:44:51Z"},
+		{"mainnet", 1, "2017-09-23T21:45:11Z"},
+		// Last Byron slot and first Shelley slot
+		{"mainnet", 4492799, "2020-07-29T21:44:31Z"},
+		{"mainnet", 4492800, "2020-07-29T21:44:51Z"},
+		{"mainnet", 4492801, "2020-07-29T21:44:52Z"},
+		// Preview starts in Shelley
+		{"preview", 0, "2022-10-25T00:00:00Z"},
+		{"preview", 86400, "2022-10-26T00:00:00Z"},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.network, func(t *testing.T) {
+			setTestGenesis(t, testDef.network)
+			got := getSlotTime(testDef.slot).UTC().Format(time.RFC3339)
+			if got != testDef.expected {
+				t.Errorf(
+					"slot %d got %s, expected %s",
+					testDef.slot,
+					got,
+					testDef.expected,
+				)
+			}
+		})
+	}
+}
+
+func TestGetSlotAtTime(t *testing.T) {
+	testDefs := []struct {
+		network  string
+		time     string
+		expected uint64
+	}{
+		{"mainnet", "2017-09-23T21:44:51Z", 0},
+		// Byron slots are 20 seconds
+		{"mainnet", "2017-09-23T21:45:10Z", 0},
+		{"mainnet", "2017-09-23T21:45:11Z", 1},
+		{"mainnet", "2020-07-29T21:44:50Z", 4492799},
+		{"mainnet", "2020-07-29T21:44:51Z", 4492800},
+		{"mainnet", "2020-07-29T21:44:52Z", 4492801},
+		// Before genesis
+		{"mainnet", "2017-09-23T21:44:50Z", 0},
+		// Before the UNIX epoch
+		{"mainnet", "1969-12-31T23:59:59Z", 0},
+		{"preview", "2022-10-25T00:00:00Z", 0},
+		{"preview", "2022-10-25T00:00:10Z", 10},
+		{"preview", "2022-10-24T23:59:59Z", 0},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.network, func(t *testing.T) {
+			setTestGenesis(t, testDef.network)
+			got := getSlotAtTime(mustParseTime(t, testDef.time))
+			if got != testDef.expected {
+				t.Errorf(
+					"time %s got slot %d, expected %d",
+					testDef.time,
+					got,
+					testDef.expected,
+				)
+			}
+		})
+	}
+}
+
+func TestGetSlotAtTimeMissingGenesis(t *testing.T) {
+	setTestGenesis(t, "mainnet")
+	cfg := config.GetConfig()
+	cfg.Node.ShelleyGenesis.SlotLength = 0
+	now := mustParseTime(t, "2025-01-01T00:00:00Z")
+	if got := getSlotAtTime(now); got != 0 {
+		t.Errorf("got slot %d, expected 0 with no Shelley slot length", got)
+	}
+	cfg.Node.ShelleyGenesis.SlotLength = 1000
+	cfg.Node.ByronGenesis.SlotLength = 0
+	if got := getSlotAtTime(now); got != 0 {
+		t.Errorf("got slot %d, expected 0 with no Byron slot length", got)
+	}
+}
+
+func TestComputeEpochFromSlot(t *testing.T) {
+	testDefs := []struct {
+		network  string
+		now      string
+		expected uint64
+	}{
+		// Byron era, with the first epoch boundary
+		{"mainnet", "2017-09-23T21:45:12Z", 0},
+		{"mainnet", "2017-09-28T21:44:51Z", 0},
+		{"mainnet", "2017-09-28T21:45:11Z", 1},
+		{"mainnet", "2019-01-01T00:00:00Z", 92},
+		// The tip reference is a second behind the clock
+		{"mainnet", "2020-07-29T21:44:51Z", 207},
+		{"mainnet", "2020-07-29T21:44:52Z", 208},
+		{"mainnet", "2020-08-03T21:44:52Z", 209},
+		{"mainnet", "2025-06-01T12:00:00Z", 561},
+		{"preview", "2022-10-26T00:00:00Z", 0},
+		{"preview", "2022-10-26T00:00:01Z", 1},
+		// Before genesis
+		{"mainnet", "2017-09-23T21:44:50Z", 0},
+		{"preview", "2022-10-24T00:00:00Z", 0},
+	}
+	oldNow := timeNow
+	t.Cleanup(func() {
+		timeNow = oldNow
+	})
+	for _, testDef := range testDefs {
+		t.Run(testDef.network, func(t *testing.T) {
+			setTestGenesis(t, testDef.network)
+			now := mustParseTime(t, testDef.now)
+			timeNow = func() time.Time { return now }
+			if got := computeEpochFromSlot(); got != testDef.expected {
+				t.Errorf(
+					"time %s got epoch %d, expected %d",
+					testDef.now,
+					got,
+					testDef.expected,
+				)
+			}
+		})
+	}
+}
+
+func TestGetEpochFromSlot(t *testing.T) {
+	testDefs := []struct {
+		network  string
+		slot     uint64
+		expected uint64
+	}{
+		{"mainnet", 0, 0},
+		{"mainnet", 21599, 0},
+		{"mainnet", 21600, 1},
+		{"mainnet", 4492799, 207},
+		{"mainnet", 4492800, 208},
+		{"mainnet", 4924799, 208},
+		{"mainnet", 4924800, 209},
+		{"preview", 86399, 0},
+		{"preview", 86400, 1},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.network, func(t *testing.T) {
+			setTestGenesis(t, testDef.network)
+			if got := getEpochFromSlot(testDef.slot); got != testDef.expected {
+				t.Errorf(
+					"slot %d got epoch %d, expected %d",
+					testDef.slot,
+					got,
+					testDef.expected,
+				)
+			}
+		})
+	}
+}
+
+func TestRunSlotToTime(t *testing.T) {
+	testDefs := []struct {
+		arg      string
+		expected string
+		code     int
+	}{
+		{"4492799", "2020-07-29T21:44:31Z\n", 0},
+		{"4492800", "2020-07-29T21:44:51Z\n", 0},
+		{" 4492801\n", "2020-07-29T21:44:52Z\n", 0},
+		{"-1", "invalid slot: -1\n", 1},
+		{"abc", "invalid slot: abc\n", 1},
+	}
+	setTestGenesis(t, "mainnet")
+	for _, testDef := range testDefs {
+		var sb strings.Builder
+		code := runSlotToTime(&sb, testDef.arg)
+		if code != testDef.code || sb.String() != testDef.expected {
+			t.Errorf(
+				"slot %q got %q (%d), expected %q (%d)",
+				testDef.arg,
+				sb.String(),
+				code,
+				testDef.expected,
+				testDef.code,
+			)
+		}
+	}
+}
+
+func TestRunSlotToTimeMissingGenesis(t *testing.T) {
+	setTestGenesis(t, "mainnet")
+	config.GetConfig().Node.ByronGenesis.StartTime = 0
+	var sb strings.Builder
+	if code := runSlotToTime(&sb, "1"); code != 1 {
+		t.Errorf("got exit code %d, expected 1", code)
+	}
+	expected := "unknown genesis values for network: mainnet\n"
+	if sb.String() != expected {
+		t.Errorf("got %q, expected %q", sb.String(), expected)
+	}
+}
+
+func TestRunTimeToSlot(t *testing.T) {
+	testDefs := []struct {
+		arg      string
+		expected string
+		code     int
+	}{
+		{"2020-07-29T21:44:31Z", "4492799\n", 0},
+		{"2020-07-29T21:44:51Z", "4492800\n", 0},
+		{"2020-07-29T23:44:52+02:00", "4492801\n", 0},
+		{
+			"2017-09-23T21:44:50Z",
+			"time is before genesis: 2017-09-23T21:44:50Z\n",
+			1,
+		},
+		{"2020-07-29", "invalid time: 2020-07-29\n", 1},
+	}
+	setTestGenesis(t, "mainnet")
+	for _, testDef := range testDefs {
+		var sb strings.Builder
+		code := runTimeToSlot(&sb, testDef.arg)
+		if code != testDef.code || sb.String() != testDef.expected {
+			t.Errorf(
+				"time %q got %q (%d), expected %q (%d)",
+				testDef.arg,
+				sb.String(),
+				code,
+				testDef.expected,
+				testDef.code,
+			)
+		}
+	}
+}
+
+// Converting a slot to a time and back gives the same slot
+func TestSlotTimeRoundTrip(t *testing.T) {
+	for _, network := range []string{"mainnet", "preview"} {
+		t.Run(network, func(t *testing.T) {
+			setTestGenesis(t, network)
+			slots := []uint64{0, 1, 21599, 21600, 4492799, 4492800, 4492801}
+			for slot := uint64(0); slot < 200_000_000; slot += 9_876_543 {
+				slots = append(slots, slot)
+			}
+			for _, slot := range slots {
+				var sb strings.Builder
+				arg := strconv.FormatUint(slot, 10)
+				if code := runSlotToTime(&sb, arg); code != 0 {
+					t.Fatalf("slot %s failed: %s", arg, sb.String())
+				}
+				slotTime := strings.TrimSpace(sb.String())
+				sb.Reset()
+				if code := runTimeToSlot(&sb, slotTime); code != 0 {
+					t.Fatalf("time %s failed: %s", slotTime, sb.String())
+				}
+				if got := strings.TrimSpace(sb.String()); got != arg {
+					t.Errorf("slot %s round-trips via %s to %s", arg, slotTime, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeFromSeconds(t *testing.T) {
+	testDefs := []struct {
+		seconds  uint64
+		expected string
+	}{
+		{0, "00:00:00"},
+		{59, "00:00:59"},
+		{61, "00:01:01"},
+		{3661, "01:01:01"},
+		{86400, "1d 00:00:00"},
+		{93784, "1d 02:03:04"},
+		{10 * 86400, "10d 00:00:00"},
+	}
+	for _, testDef := range testDefs {
+		if got := timeFromSeconds(testDef.seconds); got != testDef.expected {
+			t.Errorf(
+				"%d seconds got %q, expected %q",
+				testDef.seconds,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestTimeFromSecondsRollover(t *testing.T) {
+	testDefs := []struct {
+		seconds  uint64
+		expected string
+	}{
+		{3599, "00:59:59"},
+		{3600, "01:00:00"},
+		{86399, "23:59:59"},
+		{86401, "1d 00:00:01"},
+		{90061, "1d 01:01:01"},
+		// Larger than float64 can represent exactly
+		{math.MaxUint64, "213503982334601d 07:00:15"},
+	}
+	for _, testDef := range testDefs {
+		if got := timeFromSeconds(testDef.seconds); got != testDef.expected {
+			t.Errorf(
+				"%d seconds got %q, expected %q",
+				testDef.seconds,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+// Points the metrics scrape at a test server for the duration of a test
+func setMetricsServer(t *testing.T, server *httptest.Server) {
+	t.Helper()
+	cfg := config.GetConfig()
+	oldHost := cfg.Prometheus.Host
+	oldPort := cfg.Prometheus.Port
+	oldTimeout := cfg.Prometheus.Timeout
+	t.Cleanup(func() {
+		cfg.Prometheus.Host = oldHost
+		cfg.Prometheus.Port = oldPort
+		cfg.Prometheus.Timeout = oldTimeout
+		metricsClient.CloseIdleConnections()
+	})
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	cfg.Prometheus.Host = addr.IP.String()
+	cfg.Prometheus.Port = uint32(addr.Port)
+}
+
+func TestGetNodeMetricsReusesConnection(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/metrics" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprintln(w, "cardano_node_metrics_blockNum_int 11612345")
+		},
+	))
+	var newConns atomic.Int32
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	setMetricsServer(t, server)
+	for i := 0; i < 3; i++ {
+		body, status, err := getNodeMetrics(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if status != http.StatusOK ||
+			string(body) != "cardano_node_metrics_blockNum_int 11612345\n" {
+			t.Errorf("got %d %q, expected the metrics", status, body)
+		}
+	}
+	if got := newConns.Load(); got != 1 {
+		t.Errorf("got %d connections, expected 1 reused for every scrape", got)
+	}
+}
+
+func TestGetNodeMetricsTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+		},
+	))
+	defer server.Close()
+	defer close(done)
+	setMetricsServer(t, server)
+	config.GetConfig().Prometheus.Timeout = 1
+	start := time.Now()
+	_, status, err := getNodeMetrics(context.Background())
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, expected the deadline to be exceeded", err)
+	}
+	if status != http.StatusInternalServerError {
+		t.Errorf(
+			"got status %d, expected %d",
+			status,
+			http.StatusInternalServerError,
+		)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("got %s, expected the scrape to stop at the timeout", elapsed)
+	}
+}
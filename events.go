@@ -0,0 +1,166 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// A meaningful event in the node's timeline, such as an epoch change
+type nodeEvent struct {
+	Time    time.Time
+	Message string
+}
+
+// Ring buffer of the most recent events
+type eventLog struct {
+	mutex  sync.Mutex
+	events []nodeEvent
+	size   int
+}
+
+func newEventLog(size int) *eventLog {
+	return &eventLog{size: max(size, 1)}
+}
+
+// Records an event, dropping the oldest events beyond the log size
+func (l *eventLog) add(t time.Time, msg string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.events = append(l.events, nodeEvent{Time: t, Message: msg})
+	if len(l.events) > l.size {
+		l.events = append(
+			l.events[:0:0],
+			l.events[len(l.events)-l.size:]...,
+		)
+	}
+}
+
+// Returns a copy of the events, oldest first
+func (l *eventLog) list() []nodeEvent {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]nodeEvent(nil), l.events...)
+}
+
+var nodeEvents = newEventLog(1)
+
+// Records an event in the node's timeline
+func recordEvent(msg string) {
+	nodeEvents.add(time.Now(), msg)
+}
+
+// State from the last event check, used to detect transitions
+var (
+	eventsLastPid    int32
+	eventsLastSynced bool
+	eventsLastKES    uint64
+)
+
+// Records events for node restarts, sync completion, and KES rotation
+func checkEvents() {
+	if processMetrics != nil && processMetrics.Pid() != 0 {
+		pid := processMetrics.Pid()
+		if eventsLastPid != 0 && pid != eventsLastPid {
+			recordEvent(fmt.Sprintf("Node restarted (PID %d)", pid))
+		}
+		eventsLastPid = pid
+	}
+	if promMetrics == nil || promMetrics.SlotNum == 0 {
+		return
+	}
+	synced := isSynced(promMetrics, getSlotTipRef())
+	if synced && !eventsLastSynced {
+		recordEvent(
+			fmt.Sprintf("Sync complete at block %d", promMetrics.BlockNum),
+		)
+	}
+	eventsLastSynced = synced
+	if promMetrics.KesPeriod != 0 {
+		if eventsLastKES != 0 && promMetrics.KesPeriod > eventsLastKES {
+			recordEvent(
+				fmt.Sprintf("KES period %d started", promMetrics.KesPeriod),
+			)
+		}
+		eventsLastKES = promMetrics.KesPeriod
+	}
+}
+
+// Full-screen events page
+var eventsPageTextView = tview.NewTextView().
+	SetDynamicColors(true).
+	SetChangedFunc(func() {
+		app.Draw()
+	})
+var eventsPageText string
+
+// Sets up the full-screen events page, which returns to the main page on esc
+func setupEventsPage() {
+	eventsPageTextView.SetTitle("Events (esc to return)").SetBorder(true)
+	eventsPageTextView.SetInputCapture(
+		func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyEscape || event.Rune() == 'e' {
+				pages.SwitchToPage("Main")
+				app.SetFocus(flex)
+				return nil
+			}
+			return event
+		},
+	)
+	pages.AddPage("Events", eventsPageTextView, true, false)
+}
+
+// Shows the full-screen events page
+func showEventsPage() {
+	updateEventsPage()
+	pages.SwitchToPage("Events")
+	app.SetFocus(eventsPageTextView)
+	eventsPageTextView.ScrollToEnd()
+}
+
+// Updates the full-screen events page when it's shown
+func updateEventsPage() {
+	if name, _ := pages.GetFrontPage(); name != "Events" {
+		return
+	}
+	tmpText := getEventsPageText(nodeEvents.list())
+	if tmpText != eventsPageText {
+		eventsPageText = tmpText
+		eventsPageTextView.SetText(eventsPageText)
+	}
+}
+
+// Returns the events text with a timestamped row per event, oldest first
+func getEventsPageText(events []nodeEvent) string {
+	if len(events) == 0 {
+		return " [yellow]No events yet\n"
+	}
+	var sb strings.Builder
+	for _, event := range events {
+		fmt.Fprintf(
+			&sb,
+			" [green]%s  [white]%s\n",
+			formatTime(event.Time, time.DateTime),
+			tview.Escape(event.Message),
+		)
+	}
+	return sb.String()
+}
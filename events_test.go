@@ -0,0 +1,219 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// Replaces the node events with an empty log of the given size
+func setEventLogFixture(t *testing.T, size int) {
+	t.Helper()
+	oldEvents := nodeEvents
+	t.Cleanup(func() {
+		nodeEvents = oldEvents
+	})
+	nodeEvents = newEventLog(size)
+}
+
+// Returns the messages of the recorded node events, oldest first
+func getEventMessages() []string {
+	var messages []string
+	for _, event := range nodeEvents.list() {
+		messages = append(messages, event.Message)
+	}
+	return messages
+}
+
+func TestEventLog(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	testDefs := []struct {
+		size     int
+		count    int
+		expected []string
+	}{
+		{size: 3, count: 2, expected: []string{"event 0", "event 1"}},
+		{size: 3, count: 3, expected: []string{"event 0", "event 1", "event 2"}},
+		// The oldest events are dropped beyond the log size
+		{size: 3, count: 5, expected: []string{"event 2", "event 3", "event 4"}},
+		// The log always keeps at least one event
+		{size: 0, count: 2, expected: []string{"event 1"}},
+	}
+	for _, testDef := range testDefs {
+		l := newEventLog(testDef.size)
+		for i := 0; i < testDef.count; i++ {
+			l.add(
+				start.Add(time.Duration(i)*time.Minute),
+				fmt.Sprintf("event %d", i),
+			)
+		}
+		events := l.list()
+		var got []string
+		for i, event := range events {
+			got = append(got, event.Message)
+			if i > 0 && !event.Time.After(events[i-1].Time) {
+				t.Errorf("got %v, expected the oldest event first", events)
+			}
+		}
+		if !reflect.DeepEqual(got, testDef.expected) {
+			t.Errorf(
+				"size %d, %d events: got %v, expected %v",
+				testDef.size,
+				testDef.count,
+				got,
+				testDef.expected,
+			)
+		}
+		// The list is a copy
+		events[0].Message = "changed"
+		if l.list()[0].Message == "changed" {
+			t.Errorf("expected the list to be a copy")
+		}
+	}
+}
+
+func TestCheckEvents(t *testing.T) {
+	setPanelFixtures(t)
+	setSyncedThreshold(t, 20)
+	setEventLogFixture(t, 100)
+	oldPid, oldSynced, oldKES := eventsLastPid, eventsLastSynced, eventsLastKES
+	t.Cleanup(func() {
+		eventsLastPid, eventsLastSynced, eventsLastKES = oldPid, oldSynced, oldKES
+	})
+	eventsLastPid, eventsLastSynced, eventsLastKES = 0, false, 0
+	syncedSlot := promMetrics.SlotNum
+	testDefs := []struct {
+		name     string
+		pid      int32
+		slot     uint64
+		kes      uint64
+		expected []string
+	}{
+		{
+			name:     "start",
+			pid:      1234,
+			slot:     syncedSlot,
+			kes:      1123,
+			expected: []string{"Sync complete at block 11612345"},
+		},
+		{name: "unchanged", pid: 1234, slot: syncedSlot, kes: 1123},
+		{
+			name: "restart",
+			pid:  5678,
+			slot: syncedSlot,
+			kes:  1124,
+			expected: []string{
+				"Node restarted (PID 5678)",
+				"KES period 1124 started",
+			},
+		},
+		// Sync completes again after falling behind
+		{name: "behind", pid: 5678, slot: syncedSlot - 1000, kes: 1124},
+		{
+			name:     "resynced",
+			pid:      5678,
+			slot:     syncedSlot,
+			kes:      1124,
+			expected: []string{"Sync complete at block 11612345"},
+		},
+	}
+	for _, testDef := range testDefs {
+		setEventLogFixture(t, 100)
+		processMetrics = &fakeProcess{pid: testDef.pid}
+		promMetrics.SlotNum = testDef.slot
+		promMetrics.KesPeriod = testDef.kes
+		checkEvents()
+		if got := getEventMessages(); !reflect.DeepEqual(got, testDef.expected) {
+			t.Errorf(
+				"%s: got %v, expected %v",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestCheckCriticalAlertsEvent(t *testing.T) {
+	setEventLogFixture(t, 100)
+	cfg := config.GetConfig()
+	oldRetries := cfg.App.Retries
+	oldBell, oldFlash := cfg.App.AlertBell, cfg.App.AlertFlash
+	oldAlerts := alerts
+	oldMetrics := promMetrics
+	t.Cleanup(func() {
+		cfg.App.Retries = oldRetries
+		cfg.App.AlertBell, cfg.App.AlertFlash = oldBell, oldFlash
+		alerts = oldAlerts
+		promMetrics = oldMetrics
+		promFailures.Store(0)
+	})
+	// Alerts are recorded even without the bell or flash
+	cfg.App.Retries = 10
+	cfg.App.AlertBell, cfg.App.AlertFlash = false, false
+	alerts = alertState{}
+	promMetrics = nil
+	promFailures.Store(5)
+	checkCriticalAlerts()
+	checkCriticalAlerts()
+	expected := []string{"Alert: node unreachable"}
+	if got := getEventMessages(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestGetEventsPageText(t *testing.T) {
+	cfg := config.GetConfig()
+	oldCfg := *cfg
+	t.Cleanup(func() {
+		*cfg = oldCfg
+	})
+	t.Setenv("TIMEZONE", "UTC")
+	if _, err := config.LoadConfig(""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testDefs := []struct {
+		events   []nodeEvent
+		expected string
+	}{
+		{events: nil, expected: " [yellow]No events yet\n"},
+		{
+			events: []nodeEvent{
+				{
+					Time:    time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+					Message: "Epoch 556 started",
+				},
+				// Tags in messages are shown as is
+				{
+					Time:    time.Date(2025, 6, 1, 12, 5, 0, 0, time.UTC),
+					Message: "Alert: [red]",
+				},
+			},
+			expected: " [green]2025-06-01 12:00:00  [white]Epoch 556 started\n" +
+				" [green]2025-06-01 12:05:00  [white]Alert: [red[]\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		got := getEventsPageText(testDef.events)
+		if got != testDef.expected {
+			t.Errorf("got %q, expected %q", got, testDef.expected)
+		}
+	}
+}
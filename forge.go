@@ -0,0 +1,175 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// Blocks forged in an epoch, as the slots in the epoch they were forged at,
+// along with the first slot in the epoch we observed
+type forgeEpoch struct {
+	Start uint64   `json:"start"`
+	Slots []uint64 `json:"slots"`
+}
+
+// Blocks forged in the current and previous epochs
+type forgeHistory struct {
+	Epochs map[uint64]*forgeEpoch `json:"epochs"`
+	// Adopted blocks at our last update, since the node only reports adopted
+	// blocks since it started
+	lastAdopted uint64
+	seen        bool
+}
+
+// Records blocks adopted since the last update in the given epoch, returning
+// whether anything changed
+func (h *forgeHistory) update(
+	epoch uint64,
+	slotInEpoch uint64,
+	adopted uint64,
+) bool {
+	if h.Epochs == nil {
+		h.Epochs = make(map[uint64]*forgeEpoch)
+	}
+	changed := false
+	e, ok := h.Epochs[epoch]
+	if !ok {
+		e = &forgeEpoch{Start: slotInEpoch}
+		h.Epochs[epoch] = e
+		changed = true
+		// Only the previous epoch is needed for comparison
+		for k := range h.Epochs {
+			if k+1 < epoch {
+				delete(h.Epochs, k)
+			}
+		}
+	}
+	if !h.seen {
+		h.lastAdopted = adopted
+		h.seen = true
+		return changed
+	}
+	// Adopted blocks start over when the node restarts
+	if adopted < h.lastAdopted {
+		h.lastAdopted = 0
+	}
+	for ; h.lastAdopted < adopted; h.lastAdopted++ {
+		e.Slots = append(e.Slots, slotInEpoch)
+		changed = true
+	}
+	return changed
+}
+
+// Returns the blocks forged this epoch, along with the blocks forged in the
+// previous epoch by the same slot in the epoch, which isn't known when we
+// didn't observe the previous epoch up to that slot
+func (h *forgeHistory) compare(
+	epoch uint64,
+	slotInEpoch uint64,
+) (int, int, bool) {
+	var current int
+	if e, ok := h.Epochs[epoch]; ok {
+		current = len(e.Slots)
+	}
+	if epoch == 0 {
+		return current, 0, false
+	}
+	prev, ok := h.Epochs[epoch-1]
+	if !ok || prev.Start > slotInEpoch {
+		return current, 0, false
+	}
+	var last int
+	for _, slot := range prev.Slots {
+		if slot <= slotInEpoch {
+			last++
+		}
+	}
+	return current, last, true
+}
+
+var forges forgeHistory
+
+// Loads the forge history from the configured file, if any
+func loadForgeHistory() {
+	path := config.GetConfig().App.ForgeHistoryFile
+	if path == "" {
+		return
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("failed to read forge history", "error", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(buf, &forges); err != nil {
+		slog.Warn("failed to parse forge history", "error", err)
+	}
+}
+
+// Saves the forge history to the configured file, if any
+func saveForgeHistory() {
+	path := config.GetConfig().App.ForgeHistoryFile
+	if path == "" {
+		return
+	}
+	buf, err := json.Marshal(&forges)
+	if err != nil {
+		slog.Warn("failed to encode forge history", "error", err)
+		return
+	}
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		slog.Warn("failed to write forge history", "error", err)
+	}
+}
+
+// Records blocks forged since the last check, saving the history when it
+// changes, so it keeps recording while the Core panel is hidden or paused
+func recordForges() {
+	if role != "Core" || !isMetricsPopulated(promMetrics) {
+		return
+	}
+	if forges.update(
+		promMetrics.EpochNum,
+		promMetrics.SlotInEpoch,
+		promMetrics.Adopted,
+	) {
+		saveForgeHistory()
+	}
+}
+
+// Returns the forged blocks text for the Core panel, comparing this epoch to
+// the previous epoch at the same point
+func getForgeText(metrics *PromMetrics) string {
+	current, last, ok := forges.compare(metrics.EpochNum, metrics.SlotInEpoch)
+	if !ok {
+		return fmt.Sprintf(
+			" [green]This epoch : [white]%d [blue]([white]last: -[blue])\n",
+			current,
+		)
+	}
+	return fmt.Sprintf(
+		" [green]This epoch : [white]%d [blue]([white]last: %d[blue])\n",
+		current,
+		last,
+	)
+}
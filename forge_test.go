@@ -0,0 +1,253 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// Returns the epochs in a forge history, in order
+func getForgeEpochs(h *forgeHistory) []uint64 {
+	var epochs []uint64
+	for epoch := range h.Epochs {
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+	return epochs
+}
+
+func TestForgeHistoryUpdate(t *testing.T) {
+	var h forgeHistory
+	testDefs := []struct {
+		epoch   uint64
+		slot    uint64
+		adopted uint64
+		changed bool
+		slots   []uint64
+	}{
+		// Blocks adopted before we started aren't counted
+		{epoch: 556, slot: 1000, adopted: 10, changed: true},
+		{epoch: 556, slot: 1100, adopted: 10, changed: false},
+		{
+			epoch:   556,
+			slot:    1200,
+			adopted: 12,
+			changed: true,
+			slots:   []uint64{1200, 1200},
+		},
+		// Adopted blocks start over when the node restarts
+		{
+			epoch:   556,
+			slot:    1300,
+			adopted: 1,
+			changed: true,
+			slots:   []uint64{1200, 1200, 1300},
+		},
+		// A new epoch starts with no blocks
+		{epoch: 557, slot: 50, adopted: 1, changed: true},
+		{
+			epoch:   557,
+			slot:    400,
+			adopted: 3,
+			changed: true,
+			slots:   []uint64{400, 400},
+		},
+	}
+	for i, testDef := range testDefs {
+		changed := h.update(testDef.epoch, testDef.slot, testDef.adopted)
+		if changed != testDef.changed {
+			t.Errorf(
+				"update %d: got changed %v, expected %v",
+				i,
+				changed,
+				testDef.changed,
+			)
+		}
+		got := h.Epochs[testDef.epoch].Slots
+		if !reflect.DeepEqual(got, testDef.slots) {
+			t.Errorf("update %d: got %v, expected %v", i, got, testDef.slots)
+		}
+	}
+	if start := h.Epochs[557].Start; start != 50 {
+		t.Errorf("got start %d, expected 50", start)
+	}
+	// Only the previous epoch is kept
+	h.update(558, 10, 3)
+	expected := []uint64{557, 558}
+	if got := getForgeEpochs(&h); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got epochs %v, expected %v", got, expected)
+	}
+}
+
+func TestForgeHistoryCompare(t *testing.T) {
+	testDefs := []struct {
+		name    string
+		epochs  map[uint64]*forgeEpoch
+		epoch   uint64
+		slot    uint64
+		current int
+		last    int
+		ok      bool
+	}{
+		{
+			name: "same point",
+			epochs: map[uint64]*forgeEpoch{
+				556: {Start: 0, Slots: []uint64{100, 200, 300}},
+				557: {Start: 0, Slots: []uint64{150}},
+			},
+			epoch:   557,
+			slot:    250,
+			current: 1,
+			last:    2,
+			ok:      true,
+		},
+		{
+			name: "none yet",
+			epochs: map[uint64]*forgeEpoch{
+				556: {Start: 0, Slots: []uint64{100, 200, 300}},
+			},
+			epoch:   557,
+			slot:    50,
+			current: 0,
+			last:    0,
+			ok:      true,
+		},
+		// The previous epoch is unknown when first observed after this point
+		{
+			name: "observed late",
+			epochs: map[uint64]*forgeEpoch{
+				556: {Start: 400, Slots: []uint64{500}},
+				557: {Start: 0, Slots: []uint64{150}},
+			},
+			epoch:   557,
+			slot:    250,
+			current: 1,
+		},
+		{
+			name: "first epoch",
+			epochs: map[uint64]*forgeEpoch{
+				557: {Start: 100, Slots: []uint64{150, 200}},
+			},
+			epoch:   557,
+			slot:    250,
+			current: 2,
+		},
+		{name: "genesis", epoch: 0, slot: 250},
+	}
+	for _, testDef := range testDefs {
+		h := forgeHistory{Epochs: testDef.epochs}
+		current, last, ok := h.compare(testDef.epoch, testDef.slot)
+		if current != testDef.current || last != testDef.last ||
+			ok != testDef.ok {
+			t.Errorf(
+				"%s: got %d, %d, %v, expected %d, %d, %v",
+				testDef.name,
+				current,
+				last,
+				ok,
+				testDef.current,
+				testDef.last,
+				testDef.ok,
+			)
+		}
+	}
+}
+
+func TestGetForgeText(t *testing.T) {
+	oldForges := forges
+	t.Cleanup(func() {
+		forges = oldForges
+	})
+	metrics := &PromMetrics{EpochNum: 557, SlotInEpoch: 250}
+	testDefs := []struct {
+		epochs   map[uint64]*forgeEpoch
+		expected string
+	}{
+		{
+			epochs: map[uint64]*forgeEpoch{
+				556: {Start: 0, Slots: []uint64{100, 200, 240, 250, 300}},
+				557: {Start: 0, Slots: []uint64{10, 20, 30}},
+			},
+			// Blocks up to and including the same slot are counted
+			expected: " [green]This epoch : [white]3 " +
+				"[blue]([white]last: 4[blue])\n",
+		},
+		// The first observed epoch has nothing to compare to
+		{
+			epochs: map[uint64]*forgeEpoch{
+				557: {Start: 100, Slots: []uint64{150}},
+			},
+			expected: " [green]This epoch : [white]1 " +
+				"[blue]([white]last: -[blue])\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		forges = forgeHistory{Epochs: testDef.epochs}
+		if got := getForgeText(metrics); got != testDef.expected {
+			t.Errorf("got %q, expected %q", got, testDef.expected)
+		}
+	}
+}
+
+func TestForgeHistoryFile(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldPath := cfg.App.ForgeHistoryFile
+	oldForges := forges
+	t.Cleanup(func() {
+		cfg.App.ForgeHistoryFile = oldPath
+		forges = oldForges
+	})
+	logs := setLogCapture(t)
+	cfg.App.ForgeHistoryFile = filepath.Join(t.TempDir(), "forges.json")
+	forges = forgeHistory{}
+	// A missing file is expected on the first run
+	loadForgeHistory()
+	if len(forges.Epochs) != 0 || logs.Len() != 0 {
+		t.Errorf("got %v, %q, expected an empty history", forges.Epochs, logs)
+	}
+	// Recorded blocks are saved as they're forged
+	promMetrics.Adopted = 4
+	recordForges()
+	promMetrics.Adopted = 6
+	recordForges()
+	forges = forgeHistory{}
+	loadForgeHistory()
+	got := forges.Epochs[promMetrics.EpochNum]
+	expected := &forgeEpoch{
+		Start: promMetrics.SlotInEpoch,
+		Slots: []uint64{promMetrics.SlotInEpoch, promMetrics.SlotInEpoch},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, expected %+v", got, expected)
+	}
+	// A corrupt file is ignored with a warning
+	err := os.WriteFile(cfg.App.ForgeHistoryFile, []byte("{"), 0o600)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	forges = forgeHistory{}
+	loadForgeHistory()
+	if !strings.Contains(logs.String(), "failed to parse forge history") {
+		t.Errorf("got %q, expected a parse warning", logs)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+type healthState int
+
+const (
+	healthGood healthState = iota
+	healthDegraded
+	healthDown
+)
+
+// Returns the display color for a health state
+func (h healthState) Color() string {
+	switch h {
+	case healthGood:
+		return "green"
+	case healthDegraded:
+		return "yellow"
+	default:
+		return "red"
+	}
+}
+
+func (h healthState) String() string {
+	switch h {
+	case healthGood:
+		return "HEALTHY"
+	case healthDegraded:
+		return "DEGRADED"
+	default:
+		return "DOWN"
+	}
+}
+
+// How long a missed slot or unadopted block affects the health state
+const healthQuietWindow = 10 * time.Minute
+
+// Tracks when the cumulative forging counters last increased, so a missed
+// slot or unadopted block only affects the health state until things have
+// been quiet for a while
+type forgeHealth struct {
+	sync.Mutex
+	seen          bool
+	lastMissed    uint64
+	lastUnadopted uint64
+	missedAt      time.Time
+	unadoptedAt   time.Time
+}
+
+// Returns the number of blocks we led but which weren't adopted
+func getUnadopted(metrics *PromMetrics) uint64 {
+	if metrics.IsLeader < metrics.Adopted {
+		return 0
+	}
+	return metrics.IsLeader - metrics.Adopted
+}
+
+// Records the current forging counters, noting when they've increased since
+// the last sample
+func (f *forgeHealth) update(metrics *PromMetrics, now time.Time) {
+	if metrics == nil {
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	unadopted := getUnadopted(metrics)
+	// Don't count slots missed or blocks lost before we started
+	if f.seen {
+		if metrics.MissedSlots > f.lastMissed {
+			f.missedAt = now
+		}
+		if unadopted > f.lastUnadopted {
+			f.unadoptedAt = now
+		}
+	}
+	f.seen = true
+	f.lastMissed = metrics.MissedSlots
+	f.lastUnadopted = unadopted
+}
+
+// Returns whether slots were missed or blocks went unadopted within the
+// quiet window
+func (f *forgeHealth) recent(now time.Time) (missed bool, unadopted bool) {
+	f.Lock()
+	defer f.Unlock()
+	missed = !f.missedAt.IsZero() && now.Sub(f.missedAt) < healthQuietWindow
+	unadopted = !f.unadoptedAt.IsZero() &&
+		now.Sub(f.unadoptedAt) < healthQuietWindow
+	return missed, unadopted
+}
+
+var nodeForgeHealth forgeHealth
+
+// Classifies overall node health from sync status, recent forging problems,
+// failures, and peer RTT
+func getHealthState(
+	metrics *PromMetrics,
+	tipRef uint64,
+	nodeRole string,
+	failures uint32,
+	rttAvg int,
+	forging *forgeHealth,
+	now time.Time,
+) healthState {
+	if metrics == nil || metrics.SlotNum == 0 {
+		return healthDown
+	}
+	missed, unadopted := forging.recent(now)
+	if nodeRole == "Core" && missed {
+		return healthDown
+	}
+	if !isSynced(metrics, tipRef) {
+		return healthDegraded
+	}
+	if failures > 0 {
+		return healthDegraded
+	}
+	if nodeRole == "Core" && unadopted {
+		return healthDegraded
+	}
+	if rttAvg >= 200 {
+		return healthDegraded
+	}
+	return healthGood
+}
+
+// Returns whether the node is unreachable, which is once consecutive scrape
+// failures reach half of the retry limit, until a scrape succeeds
+func isNodeDown(failures uint32, retries uint32) bool {
+	return failures > 0 && failures >= max(retries/2, 1)
+}
+
+// Temporary footer notice, shown on the second footer line until it expires
+var footerNotice string
+var footerNoticeExpires time.Time
+
+const footerNoticeDuration = 10 * time.Second
+
+// Shows a notice in the footer for a short time
+func setFooterNotice(msg string) {
+	footerNotice = msg
+	footerNoticeExpires = time.Now().Add(footerNoticeDuration)
+}
+
+// Returns our default footer with the current health state
+func getFooterText() string {
+	health := getHealthState(
+		promMetrics,
+		getSlotTipRef(),
+		role,
+		failCount,
+		peerStats.RTTAVG,
+		&nodeForgeHealth,
+		timeNow(),
+	)
+	ret := fmt.Sprintf(
+		"%s | [white]Status: [%s]%s",
+		defaultFooterText,
+		health.Color(),
+		health,
+	)
+	if footerNotice != "" && time.Now().Before(footerNoticeExpires) {
+		ret += fmt.Sprintf("\n [yellow]%s", tview.Escape(footerNotice))
+	}
+	return ret
+}
@@ -0,0 +1,304 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+func TestGetHealthState(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Minute)
+	quiet := now.Add(-healthQuietWindow)
+	synced := &PromMetrics{SlotNum: 1000}
+	testDefs := []struct {
+		name        string
+		metrics     *PromMetrics
+		tipRef      uint64
+		role        string
+		failures    uint32
+		rttAvg      int
+		missedAt    time.Time
+		unadoptedAt time.Time
+		expected    healthState
+	}{
+		{name: "no metrics", expected: healthDown},
+		{
+			name:     "not started",
+			metrics:  &PromMetrics{},
+			tipRef:   1000,
+			expected: healthDown,
+		},
+		{
+			name:     "synced relay",
+			metrics:  synced,
+			tipRef:   1020,
+			role:     "Relay",
+			expected: healthGood,
+		},
+		{
+			name:     "slow tip",
+			metrics:  synced,
+			tipRef:   1021,
+			role:     "Relay",
+			expected: healthDegraded,
+		},
+		{
+			name:     "syncing",
+			metrics:  synced,
+			tipRef:   5000,
+			role:     "Relay",
+			expected: healthDegraded,
+		},
+		{
+			name:     "failures",
+			metrics:  synced,
+			tipRef:   1000,
+			role:     "Relay",
+			failures: 1,
+			expected: healthDegraded,
+		},
+		{
+			name:     "slow peers",
+			metrics:  synced,
+			tipRef:   1000,
+			role:     "Relay",
+			rttAvg:   200,
+			expected: healthDegraded,
+		},
+		{
+			name:     "fast peers",
+			metrics:  synced,
+			tipRef:   1000,
+			role:     "Relay",
+			rttAvg:   199,
+			expected: healthGood,
+		},
+		{
+			name:     "core missed slot",
+			metrics:  synced,
+			tipRef:   1000,
+			role:     "Core",
+			missedAt: recent,
+			expected: healthDown,
+		},
+		{
+			name:     "core missed slot before quiet window",
+			metrics:  synced,
+			tipRef:   1000,
+			role:     "Core",
+			missedAt: quiet,
+			expected: healthGood,
+		},
+		{
+			name:     "relay missed slot",
+			metrics:  synced,
+			tipRef:   1000,
+			role:     "Relay",
+			missedAt: recent,
+			expected: healthGood,
+		},
+		{
+			name:        "core unadopted block",
+			metrics:     synced,
+			tipRef:      1000,
+			role:        "Core",
+			unadoptedAt: recent,
+			expected:    healthDegraded,
+		},
+		{
+			name:        "core unadopted block before quiet window",
+			metrics:     synced,
+			tipRef:      1000,
+			role:        "Core",
+			unadoptedAt: quiet,
+			expected:    healthGood,
+		},
+	}
+	for _, testDef := range testDefs {
+		forging := &forgeHealth{
+			missedAt:    testDef.missedAt,
+			unadoptedAt: testDef.unadoptedAt,
+		}
+		got := getHealthState(
+			testDef.metrics,
+			testDef.tipRef,
+			testDef.role,
+			testDef.failures,
+			testDef.rttAvg,
+			forging,
+			now,
+		)
+		if got != testDef.expected {
+			t.Errorf(
+				"%s: got %s, expected %s",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+// Missed slots and unadopted blocks only count when they increase, and the
+// health state clears after a quiet window
+func TestForgeHealth(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	testDefs := []struct {
+		metrics   *PromMetrics
+		offset    time.Duration
+		missed    bool
+		unadopted bool
+	}{
+		// Counters from before we started are ignored
+		{metrics: &PromMetrics{MissedSlots: 5, IsLeader: 10, Adopted: 8}},
+		{
+			metrics: &PromMetrics{MissedSlots: 5, IsLeader: 11, Adopted: 9},
+			offset:  time.Minute,
+		},
+		{
+			metrics: &PromMetrics{MissedSlots: 6, IsLeader: 11, Adopted: 9},
+			offset:  2 * time.Minute,
+			missed:  true,
+		},
+		{
+			metrics:   &PromMetrics{MissedSlots: 6, IsLeader: 12, Adopted: 9},
+			offset:    3 * time.Minute,
+			missed:    true,
+			unadopted: true,
+		},
+		{
+			metrics:   &PromMetrics{MissedSlots: 6, IsLeader: 12, Adopted: 9},
+			offset:    2*time.Minute + healthQuietWindow,
+			unadopted: true,
+		},
+		{
+			metrics: &PromMetrics{MissedSlots: 6, IsLeader: 12, Adopted: 9},
+			offset:  3*time.Minute + healthQuietWindow,
+		},
+		// A node restart resets the counters, which isn't an increase
+		{
+			metrics: &PromMetrics{MissedSlots: 0, IsLeader: 0, Adopted: 0},
+			offset:  4*time.Minute + healthQuietWindow,
+		},
+		{
+			metrics: &PromMetrics{MissedSlots: 1, IsLeader: 0, Adopted: 0},
+			offset:  5*time.Minute + healthQuietWindow,
+			missed:  true,
+		},
+	}
+	var forging forgeHealth
+	for i, testDef := range testDefs {
+		now := start.Add(testDef.offset)
+		forging.update(testDef.metrics, now)
+		missed, unadopted := forging.recent(now)
+		if missed != testDef.missed || unadopted != testDef.unadopted {
+			t.Errorf(
+				"sample %d: got missed %v, unadopted %v, expected %v, %v",
+				i,
+				missed,
+				unadopted,
+				testDef.missed,
+				testDef.unadopted,
+			)
+		}
+	}
+}
+
+func TestIsNodeDown(t *testing.T) {
+	testDefs := []struct {
+		failures uint32
+		retries  uint32
+		expected bool
+	}{
+		{failures: 0, retries: 1, expected: false},
+		{failures: 1, retries: 1, expected: true},
+		{failures: 0, retries: 3, expected: false},
+		{failures: 1, retries: 3, expected: true},
+		{failures: 2, retries: 3, expected: true},
+		{failures: 4, retries: 10, expected: false},
+		{failures: 5, retries: 10, expected: true},
+		// Scrape failures don't stop at the retry limit
+		{failures: 12, retries: 10, expected: true},
+	}
+	for _, testDef := range testDefs {
+		got := isNodeDown(testDef.failures, testDef.retries)
+		if got != testDef.expected {
+			t.Errorf(
+				"%d/%d: got %v, expected %v",
+				testDef.failures,
+				testDef.retries,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+// The banner appears after enough consecutive scrape failures and clears on
+// the next successful scrape, regardless of other failures
+func TestNodeDownBanner(t *testing.T) {
+	cfg := config.GetConfig()
+	oldRetries, oldFailCount := cfg.App.Retries, failCount
+	t.Cleanup(func() {
+		cfg.App.Retries = oldRetries
+		failCount = oldFailCount
+		promFailures.Store(0)
+	})
+	cfg.App.Retries = 6
+	banner := "NODE UNREACHABLE"
+	testDefs := []struct {
+		err      error
+		expected string
+	}{
+		{err: errors.New("connection refused")},
+		{err: errors.New("connection refused")},
+		{
+			err:      errors.New("connection refused"),
+			expected: "retrying (3/6)",
+		},
+		{
+			err:      errors.New("connection refused"),
+			expected: "retrying (4/6)",
+		},
+		{err: nil},
+		{err: errors.New("connection refused")},
+	}
+	for i, testDef := range testDefs {
+		recordScrapeResult(testDef.err)
+		// Other workers' failures and successes don't affect the banner
+		failCount = uint32(i % 2)
+		header := getHeaderText()
+		if testDef.expected == "" {
+			if strings.Contains(header, banner) {
+				t.Errorf("scrape %d: unexpected banner: %q", i, header)
+			}
+			continue
+		}
+		if !strings.Contains(header, banner+" - "+testDef.expected) {
+			t.Errorf(
+				"scrape %d: got %q, expected banner with %q",
+				i,
+				header,
+				testDef.expected,
+			)
+		}
+	}
+}
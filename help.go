@@ -0,0 +1,94 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// A key binding on the main page, as shown on the help page
+type keyBinding struct {
+	Keys        string
+	Description string
+}
+
+var keyBindings = []keyBinding{
+	{"q, esc", "Quit"},
+	{"h, ?", "Show this help"},
+	{"r", "Refresh all panels now"},
+	{"p", "Run a new peer analysis"},
+	{"f", "Show the full-screen peers page"},
+	{"e", "Show the full-screen events page"},
+	{"x", "Show the full-screen raw metrics page"},
+	{"c", "Copy a snapshot of the node to the clipboard"},
+	{"m", "Toggle mouse support"},
+	{"1-7", "Toggle panels"},
+	{"s", "Toggle sorting peers by best or worst RTT first"},
+	{"z", "Toggle peers-only focus"},
+}
+
+// Full-screen help page
+var helpTextView = tview.NewTextView().
+	SetDynamicColors(true)
+
+// Sets up the full-screen help page, which returns to the main page on esc,
+// h, or ?
+func setupHelpPage() {
+	helpTextView.SetTitle("Help (esc to return)").SetBorder(true)
+	helpTextView.SetText(getHelpText(keyBindings))
+	helpTextView.SetInputCapture(
+		func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyEscape || event.Rune() == 'h' ||
+				event.Rune() == '?' {
+				pages.SwitchToPage("Main")
+				app.SetFocus(flex)
+				return nil
+			}
+			return event
+		},
+	)
+	pages.AddPage("Help", helpTextView, true, false)
+}
+
+// Shows the full-screen help page
+func showHelpPage() {
+	pages.SwitchToPage("Help")
+	app.SetFocus(helpTextView)
+	helpTextView.ScrollToBeginning()
+}
+
+// Returns the help text with a row per key binding
+func getHelpText(bindings []keyBinding) string {
+	width := 0
+	for _, binding := range bindings {
+		width = max(width, len(binding.Keys))
+	}
+	var sb strings.Builder
+	sb.WriteString(" [green]Key bindings\n\n")
+	for _, binding := range bindings {
+		fmt.Fprintf(
+			&sb,
+			" [yellow]%-*s  [white]%s\n",
+			width,
+			binding.Keys,
+			binding.Description,
+		)
+	}
+	return sb.String()
+}
@@ -16,29 +16,103 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strings"
+	"sync"
+	"time"
+	// Embed time zone data for systems without it, such as minimal containers
+	_ "time/tzdata"
 
 	ouroboros "github.com/blinklabs-io/gouroboros"
 	"github.com/kelseyhightower/envconfig"
 	"gopkg.in/yaml.v2"
 )
 
+// Default cardano-node NtN port
+const DefaultNodePort uint32 = 3001
+
+// Default display name for the node
+const DefaultNodeName = "Cardano Node"
+
+// Default named network, used when neither a network nor a known network
+// magic is configured
+const DefaultNetwork = "mainnet"
+
+// Default node binary, used when none is configured
+const DefaultNodeBinary = "cardano-node"
+
 type Config struct {
 	App        AppConfig        `yaml:"app"`
 	Node       NodeConfig       `yaml:"node"`
 	Prometheus PrometheusConfig `yaml:"prometheus"`
+	// Track explicitly configured values which shouldn't be overridden by
+	// values discovered from the running node
+	nodePortSet   bool
+	nodeMagicSet  bool
+	genesisSet    bool
+	nodeBinarySet bool
+	// Time zone for displaying timestamps
+	location *time.Location
 }
 
 type AppConfig struct {
-	NodeName string `yaml:"nodeName" envconfig:"NODE_NAME"`
-	Network  string `yaml:"network"  envconfig:"NETWORK"`
-	Refresh  uint32 `yaml:"refresh"  envconfig:"REFRESH"`
-	Retries  uint32 `yaml:"retries"  envconfig:"RETRIES"`
+	NodeName              string            `yaml:"nodeName"              envconfig:"NODE_NAME"`
+	NodeNameMode          string            `yaml:"nodeNameMode"          envconfig:"NODE_NAME_MODE"`
+	Title                 string            `yaml:"title"                 envconfig:"TITLE"`
+	HideTitleVersion      bool              `yaml:"hideTitleVersion"      envconfig:"HIDE_TITLE_VERSION"`
+	AlertBell             bool              `yaml:"alertBell"             envconfig:"ALERT_BELL"`
+	AlertFlash            bool              `yaml:"alertFlash"            envconfig:"ALERT_FLASH"`
+	EventsSize            int               `yaml:"eventsSize"            envconfig:"EVENTS_SIZE"`
+	Network               string            `yaml:"network"               envconfig:"NETWORK"`
+	Refresh               uint32            `yaml:"refresh"               envconfig:"REFRESH"`
+	Retries               uint32            `yaml:"retries"               envconfig:"RETRIES"`
+	LogFormat             string            `yaml:"logFormat"             envconfig:"LOG_FORMAT"`
+	LogFile               string            `yaml:"logFile"               envconfig:"LOG_FILE"`
+	LogLevel              string            `yaml:"logLevel"              envconfig:"LOG_LEVEL"`
+	MetricsCsvPath        string            `yaml:"metricsCsvPath"        envconfig:"METRICS_CSV_PATH"`
+	MetricsCsvMaxSize     int64             `yaml:"metricsCsvMaxSize"     envconfig:"METRICS_CSV_MAX_SIZE"`
+	PublicIPResolver      string            `yaml:"publicIPResolver"      envconfig:"PUBLIC_IP_RESOLVER"`
+	PublicIPQuery         string            `yaml:"publicIPQuery"         envconfig:"PUBLIC_IP_QUERY"`
+	DisablePublicIP       bool              `yaml:"disablePublicIP"       envconfig:"DISABLE_PUBLIC_IP"`
+	AirGapped             bool              `yaml:"airGapped"             envconfig:"AIR_GAPPED"`
+	DisableVersionExec    bool              `yaml:"disableVersionExec"    envconfig:"DISABLE_VERSION_EXEC"`
+	RemoteMode            bool              `yaml:"remoteMode"            envconfig:"REMOTE_MODE"`
+	AggregateChildren     bool              `yaml:"aggregateChildren"     envconfig:"AGGREGATE_CHILDREN"`
+	PeerRTTTimeout        uint32            `yaml:"peerRTTTimeout"        envconfig:"PEER_RTT_TIMEOUT"`
+	PeerEnrichConcurrency int               `yaml:"peerEnrichConcurrency" envconfig:"PEER_ENRICH_CONCURRENCY"`
+	PeerPingSampleSize    int               `yaml:"peerPingSampleSize"    envconfig:"PEER_PING_SAMPLE_SIZE"`
+	PeerCacheFile         string            `yaml:"peerCacheFile"         envconfig:"PEER_CACHE_FILE"`
+	PeerCacheTTL          uint32            `yaml:"peerCacheTTL"          envconfig:"PEER_CACHE_TTL"`
+	MaxPeersDisplayed     int               `yaml:"maxPeersDisplayed"     envconfig:"MAX_PEERS_DISPLAYED"`
+	PeerSort              string            `yaml:"peerSort"              envconfig:"PEER_SORT"`
+	MetricAliases         map[string]string `yaml:"metricAliases"         envconfig:"METRIC_ALIASES"`
+	StartPage             string            `yaml:"startPage"             envconfig:"START_PAGE"`
+	PublicIP              string            `yaml:"publicIP"              envconfig:"PUBLIC_IP"`
+	NoEmoji               bool              `yaml:"noEmoji"               envconfig:"NO_EMOJI"`
+	PoolRelaysFile        string            `yaml:"poolRelaysFile"        envconfig:"POOL_RELAYS_FILE"`
+	PoolRelaysFormat      string            `yaml:"poolRelaysFormat"      envconfig:"POOL_RELAYS_FORMAT"`
+	PeerReverseDNS        bool              `yaml:"peerReverseDNS"        envconfig:"PEER_REVERSE_DNS"`
+	PeerDistance          bool              `yaml:"peerDistance"          envconfig:"PEER_DISTANCE"`
+	HomeLatitude          float64           `yaml:"homeLatitude"          envconfig:"HOME_LATITUDE"`
+	HomeLongitude         float64           `yaml:"homeLongitude"         envconfig:"HOME_LONGITUDE"`
+	Granularity           int               `yaml:"granularity"           envconfig:"GRANULARITY"`
+	LeaderScheduleFile    string            `yaml:"leaderScheduleFile"    envconfig:"LEADER_SCHEDULE_FILE"`
+	ForgeHistoryFile      string            `yaml:"forgeHistoryFile"      envconfig:"FORGE_HISTORY_FILE"`
+	CPUMode               string            `yaml:"cpuMode"               envconfig:"CPU_MODE"`
+	TipDiffOK             uint64            `yaml:"tipDiffOK"             envconfig:"TIP_DIFF_OK"`
+	TipDiffSlow           uint64            `yaml:"tipDiffSlow"           envconfig:"TIP_DIFF_SLOW"`
+	HiddenPanels          []string          `yaml:"hiddenPanels"          envconfig:"HIDDEN_PANELS"`
+	EnableMouse           bool              `yaml:"enableMouse"           envconfig:"ENABLE_MOUSE"`
+	Timezone              string            `yaml:"timezone"              envconfig:"TIMEZONE"`
+	PoolStake             float64           `yaml:"poolStake"             envconfig:"POOL_STAKE"`
+	MemoryUnit            string            `yaml:"memoryUnit"            envconfig:"MEMORY_UNIT"`
 }
 
 type NodeConfig struct {
 	ByronGenesis      ByronGenesisConfig   `yaml:"byron"`
 	Binary            string               `yaml:"binary"           envconfig:"CARDANO_NODE_BINARY"`
+	Binaries          []string             `yaml:"binaries"         envconfig:"CARDANO_NODE_BINARIES"`
 	Network           string               `yaml:"network"          envconfig:"CARDANO_NETWORK"`
 	SocketPath        string               `yaml:"socketPath"       envconfig:"CARDANO_NODE_SOCKET_PATH"`
 	NetworkMagic      uint32               `yaml:"networkMagic"     envconfig:"CARDANO_NODE_NETWORK_MAGIC"`
@@ -46,6 +120,10 @@ type NodeConfig struct {
 	ShelleyGenesis    ShelleyGenesisConfig `yaml:"shelley"`
 	ShelleyTransEpoch int32                `yaml:"shellyTransEpoch" envconfig:"SHELLEY_TRANS_EPOCH"`
 	BlockProducer     bool                 `yaml:"blockProducer"    envconfig:"CARDANO_BLOCK_PRODUCER"`
+	Pid               int32                `yaml:"pid"              envconfig:"CARDANO_NODE_PID"`
+	PidFile           string               `yaml:"pidFile"          envconfig:"CARDANO_NODE_PID_FILE"`
+	N2CPort           uint32               `yaml:"n2cPort"          envconfig:"CARDANO_NODE_N2C_PORT"`
+	SyncedThreshold   uint64               `yaml:"syncedThreshold"  envconfig:"SYNCED_THRESHOLD"`
 }
 
 type PrometheusConfig struct {
@@ -63,25 +141,43 @@ type ByronGenesisConfig struct {
 }
 
 type ShelleyGenesisConfig struct {
-	EpochLength       uint64 `yaml:"epochLength"       envconfig:"SHELLEY_EPOCH_LENGTH"`
-	SlotLength        uint64 `yaml:"slotLength"        envconfig:"SHELLEY_SLOT_LENGTH"`
-	SlotsPerKESPeriod uint64 `yaml:"slotsPerKESPeriod" envconfig:"SHELLEY_SLOTS_PER_KES_PERIOD"`
+	EpochLength       uint64  `yaml:"epochLength"       envconfig:"SHELLEY_EPOCH_LENGTH"`
+	SlotLength        uint64  `yaml:"slotLength"        envconfig:"SHELLEY_SLOT_LENGTH"`
+	SlotsPerKESPeriod uint64  `yaml:"slotsPerKESPeriod" envconfig:"SHELLEY_SLOTS_PER_KES_PERIOD"`
+	ActiveSlotsCoeff  float64 `yaml:"activeSlotsCoeff"  envconfig:"SHELLEY_ACTIVE_SLOTS_COEFF"`
 }
 
 // Singleton config instance with default values
 var globalConfig = &Config{
 	App: AppConfig{
-		NodeName: "Cardano Node",
-		Network:  "",
-		Refresh:  1,
-		Retries:  3,
+		NodeName:              DefaultNodeName,
+		NodeNameMode:          "manual",
+		Network:               "",
+		Refresh:               1,
+		Retries:               3,
+		Granularity:           68,
+		CPUMode:               "raw",
+		TipDiffOK:             20,
+		TipDiffSlow:           600,
+		LogFormat:             "text",
+		LogLevel:              "info",
+		PublicIPResolver:      "resolver1.opendns.com:53",
+		PublicIPQuery:         "myip.opendns.com",
+		PoolRelaysFormat:      "csv",
+		MemoryUnit:            "GiB",
+		PeerRTTTimeout:        3000,
+		PeerEnrichConcurrency: 4,
+		EventsSize:            100,
+		PeerCacheTTL:          600,
+		PeerSort:              "asc",
+		StartPage:             "main",
 	},
 	Node: NodeConfig{
-		Binary:            "cardano-node",
-		Network:           "mainnet",
-		Port:              3001,
+		Binary:            "",
+		Network:           "",
 		ShelleyTransEpoch: -1,
 		SocketPath:        "/opt/cardano/ipc/socket",
+		SyncedThreshold:   20,
 	},
 	Prometheus: PrometheusConfig{
 		Host:    "127.0.0.1",
@@ -110,6 +206,23 @@ func LoadConfig(configFile string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error processing environment: %s", err)
 	}
+	if err := globalConfig.validate(); err != nil {
+		return nil, err
+	}
+	// Use the default node binary unless one is configured
+	globalConfig.nodeBinarySet = globalConfig.Node.Binary != ""
+	if !globalConfig.nodeBinarySet {
+		globalConfig.Node.Binary = DefaultNodeBinary
+	}
+	globalConfig.nodePortSet = globalConfig.Node.Port != 0
+	globalConfig.nodeMagicSet = globalConfig.Node.NetworkMagic != 0 ||
+		globalConfig.App.Network != ""
+	globalConfig.genesisSet = globalConfig.Node.ByronGenesis.StartTime != 0 ||
+		globalConfig.Node.ShelleyGenesis.EpochLength != 0 ||
+		globalConfig.Node.ShelleyTransEpoch != int32(-1)
+	if globalConfig.Node.Port == 0 {
+		globalConfig.Node.Port = DefaultNodePort
+	}
 	// Populate NetworkMagic from named networks
 	if err := globalConfig.populateNetworkMagic(); err != nil {
 		return nil, err
@@ -129,16 +242,209 @@ func LoadConfig(configFile string) (*Config, error) {
 	return globalConfig, nil
 }
 
+// Guards replacing the global config instance once it's in use
+var globalConfigMutex sync.RWMutex
+
 // GetConfig returns the global config instance
 func GetConfig() *Config {
+	globalConfigMutex.RLock()
+	defer globalConfigMutex.RUnlock()
 	return globalConfig
 }
 
+// Location returns the configured time zone for displaying timestamps, which
+// defaults to local time
+func (c *Config) Location() *time.Location {
+	if c.location == nil {
+		return time.Local
+	}
+	return c.location
+}
+
+// NodeBinarySet returns whether the node binary was explicitly configured
+func (c *Config) NodeBinarySet() bool {
+	return c.nodeBinarySet
+}
+
+// ApplyNodeConfig fills in the port and network magic discovered from the
+// running node, without overriding explicitly configured values. The values
+// are applied to a copy of the global config, which then replaces it, so
+// other goroutines never see a partially updated config.
+func ApplyNodeConfig(port uint32, networkMagic uint32) error {
+	globalConfigMutex.Lock()
+	defer globalConfigMutex.Unlock()
+	c := *globalConfig
+	if err := c.applyNodeConfig(port, networkMagic); err != nil {
+		return err
+	}
+	globalConfig = &c
+	return nil
+}
+
+// Fills in the port and network magic discovered from the running node
+func (c *Config) applyNodeConfig(port uint32, networkMagic uint32) error {
+	if port != 0 && !c.nodePortSet {
+		c.Node.Port = port
+	}
+	if networkMagic == 0 || c.nodeMagicSet ||
+		networkMagic == c.Node.NetworkMagic {
+		return nil
+	}
+	c.Node.NetworkMagic = networkMagic
+	network, ok := ouroboros.NetworkByNetworkMagic(networkMagic)
+	if !ok || c.genesisSet {
+		return nil
+	}
+	// Re-populate our genesis values for the discovered network
+	c.Node.Network = network.Name
+	c.Node.ByronGenesis = ByronGenesisConfig{}
+	c.Node.ShelleyGenesis = ShelleyGenesisConfig{}
+	c.Node.ShelleyTransEpoch = -1
+	if err := c.populateByronGenesis(); err != nil {
+		return err
+	}
+	if err := c.populateShelleyGenesis(); err != nil {
+		return err
+	}
+	return c.populateShelleyTransEpoch()
+}
+
+// Checks config values, returning an error listing every problem found
+func (c *Config) validate() error {
+	var problems []string
+	addProblem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if c.App.Refresh < 1 {
+		addProblem("refresh (REFRESH) must be at least 1 second")
+	}
+	if c.App.Retries < 1 {
+		addProblem("retries (RETRIES) must be at least 1")
+	}
+	if c.Prometheus.Refresh < 1 {
+		addProblem("prometheus refresh (PROM_REFRESH) must be at least 1 second")
+	}
+	if c.Prometheus.Timeout < 1 {
+		addProblem("prometheus timeout (PROM_TIMEOUT) must be at least 1 second")
+	}
+	// Scrapes run one at a time, so a timeout up to the refresh interval
+	// can't cause them to pile up
+	if c.Prometheus.Refresh > 0 && c.Prometheus.Timeout > c.Prometheus.Refresh {
+		addProblem(
+			"prometheus timeout (PROM_TIMEOUT) %d must not be more than "+
+				"the refresh interval (PROM_REFRESH) %d",
+			c.Prometheus.Timeout,
+			c.Prometheus.Refresh,
+		)
+	}
+	if c.Prometheus.Port < 1 || c.Prometheus.Port > 65535 {
+		addProblem(
+			"prometheus port (PROM_PORT) %d must be between 1 and 65535",
+			c.Prometheus.Port,
+		)
+	}
+	// Node ports of 0 are discovered from the running node or disabled
+	if c.Node.Port > 65535 {
+		addProblem(
+			"node port (CARDANO_PORT) %d must be between 1 and 65535",
+			c.Node.Port,
+		)
+	}
+	if c.Node.N2CPort > 65535 {
+		addProblem(
+			"node-to-client port (CARDANO_NODE_N2C_PORT) %d must be between 1 and 65535",
+			c.Node.N2CPort,
+		)
+	}
+	if c.App.PublicIP != "" && net.ParseIP(c.App.PublicIP) == nil {
+		addProblem("invalid public IP address (PUBLIC_IP): %s", c.App.PublicIP)
+	}
+	if c.App.Timezone != "" {
+		loc, err := time.LoadLocation(c.App.Timezone)
+		if err != nil {
+			addProblem("invalid time zone (TIMEZONE) %q: %s", c.App.Timezone, err)
+		} else {
+			c.location = loc
+		}
+	}
+	if c.App.PoolStake < 0 || c.App.PoolStake > 1 {
+		addProblem(
+			"pool stake (POOL_STAKE) %g must be a fraction between 0 and 1",
+			c.App.PoolStake,
+		)
+	}
+	if c.App.PeerRTTTimeout < 1 {
+		addProblem("peer RTT timeout (PEER_RTT_TIMEOUT) must be at least 1ms")
+	}
+	if c.App.PeerEnrichConcurrency < 1 {
+		addProblem(
+			"peer enrichment concurrency (PEER_ENRICH_CONCURRENCY) %d must be at least 1",
+			c.App.PeerEnrichConcurrency,
+		)
+	}
+	if c.App.EventsSize < 1 {
+		addProblem(
+			"events size (EVENTS_SIZE) %d must be at least 1",
+			c.App.EventsSize,
+		)
+	}
+	if c.App.PeerCacheTTL < 1 {
+		addProblem("peer cache TTL (PEER_CACHE_TTL) must be at least 1s")
+	}
+	if c.App.MaxPeersDisplayed < 0 {
+		addProblem(
+			"max peers displayed (MAX_PEERS_DISPLAYED) %d must not be negative",
+			c.App.MaxPeersDisplayed,
+		)
+	}
+	if c.App.PeerPingSampleSize < 0 {
+		addProblem(
+			"peer ping sample size (PEER_PING_SAMPLE_SIZE) %d must not be negative",
+			c.App.PeerPingSampleSize,
+		)
+	}
+	if c.App.TipDiffSlow < c.App.TipDiffOK {
+		addProblem(
+			"tip diff slow threshold (TIP_DIFF_SLOW) %d is less than OK threshold (TIP_DIFF_OK) %d",
+			c.App.TipDiffSlow,
+			c.App.TipDiffOK,
+		)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf(
+			"invalid config:\n - %s",
+			strings.Join(problems, "\n - "),
+		)
+	}
+	return nil
+}
+
+// Returns a known network by name, accepting "sancho" as a short name for
+// sanchonet
+func networkByName(name string) (ouroboros.Network, bool) {
+	if name == "sancho" {
+		name = "sanchonet"
+	}
+	return ouroboros.NetworkByName(name)
+}
+
 // Populates NetworkMagic from named networks
 func (c *Config) populateNetworkMagic() error {
+	// Derive the named network from a known network magic when only the
+	// network magic is configured
+	if c.Node.NetworkMagic != 0 && c.App.Network == "" && c.Node.Network == "" {
+		if network, ok := ouroboros.NetworkByNetworkMagic(
+			c.Node.NetworkMagic,
+		); ok {
+			c.Node.Network = network.Name
+		}
+	}
+	if c.Node.Network == "" {
+		c.Node.Network = DefaultNetwork
+	}
 	if c.Node.NetworkMagic == 0 {
 		if c.App.Network != "" {
-			network, ok := ouroboros.NetworkByName(c.App.Network)
+			network, ok := networkByName(c.App.Network)
 			if !ok {
 				return fmt.Errorf("unknown network: %s", c.App.Network)
 			}
@@ -149,7 +455,7 @@ func (c *Config) populateNetworkMagic() error {
 			c.Node.SocketPath = "/ipc/node.socket"
 			return nil
 		} else if c.Node.Network != "" {
-			network, ok := ouroboros.NetworkByName(c.Node.Network)
+			network, ok := networkByName(c.Node.Network)
 			if !ok {
 				return fmt.Errorf("unknown network: %s", c.Node.Network)
 			}
@@ -204,7 +510,7 @@ func (c *Config) populateByronGenesis() error {
 			c.Node.ByronGenesis.StartTime = 1666656000
 		case "preprod":
 			c.Node.ByronGenesis.StartTime = 1654041600
-		case "sancho":
+		case "sancho", "sanchonet":
 			c.Node.ByronGenesis.K = 432
 			c.Node.ByronGenesis.StartTime = 1686789000
 		case "mainnet":
@@ -217,7 +523,7 @@ func (c *Config) populateByronGenesis() error {
 			c.Node.ByronGenesis.StartTime = 1666656000
 		case "preprod":
 			c.Node.ByronGenesis.StartTime = 1654041600
-		case "sancho":
+		case "sancho", "sanchonet":
 			c.Node.ByronGenesis.K = 432
 			c.Node.ByronGenesis.StartTime = 1686789000
 		case "mainnet":
@@ -232,6 +538,10 @@ func (c *Config) populateByronGenesis() error {
 
 // Populates ShelleyGenesisConfig from named networks
 func (c *Config) populateShelleyGenesis() error {
+	// Our active slots coefficient is always 0.05 in supported networks
+	if c.Node.ShelleyGenesis.ActiveSlotsCoeff == 0 {
+		c.Node.ShelleyGenesis.ActiveSlotsCoeff = 0.05
+	}
 	if c.Node.ShelleyGenesis.EpochLength != 0 {
 		return nil
 	}
@@ -243,14 +553,14 @@ func (c *Config) populateShelleyGenesis() error {
 	c.Node.ShelleyGenesis.EpochLength = 432000
 	if c.App.Network != "" {
 		switch c.App.Network {
-		case "sancho":
+		case "sancho", "sanchonet":
 			c.Node.ShelleyGenesis.EpochLength = 86400
 		case "preview":
 			c.Node.ShelleyGenesis.EpochLength = 86400
 		}
 	} else if c.Node.Network != "" {
 		switch c.Node.Network {
-		case "sancho":
+		case "sancho", "sanchonet":
 			c.Node.ShelleyGenesis.EpochLength = 86400
 		case "preview":
 			c.Node.ShelleyGenesis.EpochLength = 86400
@@ -0,0 +1,748 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Default config values, copied before any test loads the config
+var testDefaults = *globalConfig
+
+// Returns a copy of the default config
+func newTestConfig() *Config {
+	c := testDefaults
+	return &c
+}
+
+func TestValidate(t *testing.T) {
+	testDefs := []struct {
+		name     string
+		modify   func(c *Config)
+		expected string
+	}{
+		{
+			name:     "refresh",
+			modify:   func(c *Config) { c.App.Refresh = 0 },
+			expected: "refresh (REFRESH) must be at least 1 second",
+		},
+		{
+			name:     "retries",
+			modify:   func(c *Config) { c.App.Retries = 0 },
+			expected: "retries (RETRIES) must be at least 1",
+		},
+		{
+			name:     "prometheus timeout",
+			modify:   func(c *Config) { c.Prometheus.Timeout = 0 },
+			expected: "prometheus timeout (PROM_TIMEOUT)",
+		},
+		{
+			name:     "prometheus refresh",
+			modify:   func(c *Config) { c.Prometheus.Refresh = 0 },
+			expected: "prometheus refresh (PROM_REFRESH) must be at least 1",
+		},
+		{
+			name:     "prometheus timeout longer than refresh",
+			modify:   func(c *Config) { c.Prometheus.Timeout = 4 },
+			expected: "prometheus timeout (PROM_TIMEOUT) 4 must not be more",
+		},
+		{
+			name:     "prometheus port",
+			modify:   func(c *Config) { c.Prometheus.Port = 70000 },
+			expected: "prometheus port (PROM_PORT) 70000",
+		},
+		{
+			name:     "node port",
+			modify:   func(c *Config) { c.Node.Port = 70000 },
+			expected: "node port (CARDANO_PORT) 70000",
+		},
+		{
+			name:     "n2c port",
+			modify:   func(c *Config) { c.Node.N2CPort = 70000 },
+			expected: "node-to-client port (CARDANO_NODE_N2C_PORT) 70000",
+		},
+		{
+			name:     "public IP",
+			modify:   func(c *Config) { c.App.PublicIP = "not-an-ip" },
+			expected: "invalid public IP address (PUBLIC_IP): not-an-ip",
+		},
+		{
+			name:     "time zone",
+			modify:   func(c *Config) { c.App.Timezone = "Nowhere/Special" },
+			expected: "invalid time zone (TIMEZONE) \"Nowhere/Special\"",
+		},
+		{
+			name:     "pool stake",
+			modify:   func(c *Config) { c.App.PoolStake = 1.5 },
+			expected: "pool stake (POOL_STAKE) 1.5",
+		},
+		{
+			name:     "peer RTT timeout",
+			modify:   func(c *Config) { c.App.PeerRTTTimeout = 0 },
+			expected: "peer RTT timeout (PEER_RTT_TIMEOUT)",
+		},
+		{
+			name:     "peer enrich concurrency",
+			modify:   func(c *Config) { c.App.PeerEnrichConcurrency = 0 },
+			expected: "peer enrichment concurrency (PEER_ENRICH_CONCURRENCY) 0",
+		},
+		{
+			name:     "events size",
+			modify:   func(c *Config) { c.App.EventsSize = 0 },
+			expected: "events size (EVENTS_SIZE) 0",
+		},
+		{
+			name:     "peer cache TTL",
+			modify:   func(c *Config) { c.App.PeerCacheTTL = 0 },
+			expected: "peer cache TTL (PEER_CACHE_TTL)",
+		},
+		{
+			name:     "max peers displayed",
+			modify:   func(c *Config) { c.App.MaxPeersDisplayed = -1 },
+			expected: "max peers displayed (MAX_PEERS_DISPLAYED) -1",
+		},
+		{
+			name:     "peer ping sample size",
+			modify:   func(c *Config) { c.App.PeerPingSampleSize = -1 },
+			expected: "peer ping sample size (PEER_PING_SAMPLE_SIZE) -1",
+		},
+		{
+			name: "tip diff thresholds",
+			modify: func(c *Config) {
+				c.App.TipDiffOK = 100
+				c.App.TipDiffSlow = 10
+			},
+			expected: "tip diff slow threshold (TIP_DIFF_SLOW) 10",
+		},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.name, func(t *testing.T) {
+			c := newTestConfig()
+			testDef.modify(c)
+			err := c.validate()
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), testDef.expected) {
+				t.Errorf(
+					"got error %q, expected it to contain %q",
+					err,
+					testDef.expected,
+				)
+			}
+		})
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	if err := newTestConfig().validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestValidatePublicIP(t *testing.T) {
+	testDefs := []struct {
+		publicIP string
+		valid    bool
+	}{
+		{publicIP: "", valid: true},
+		{publicIP: "198.51.100.7", valid: true},
+		{publicIP: "2001:db8::7", valid: true},
+		{publicIP: "198.51.100.256", valid: false},
+		{publicIP: "relay.example.com", valid: false},
+		{publicIP: "198.51.100.7:3001", valid: false},
+	}
+	for _, testDef := range testDefs {
+		c := newTestConfig()
+		c.App.PublicIP = testDef.publicIP
+		err := c.validate()
+		if testDef.valid && err != nil {
+			t.Errorf("%q: unexpected error: %s", testDef.publicIP, err)
+		}
+		if !testDef.valid && (err == nil ||
+			!strings.Contains(err.Error(), "invalid public IP address")) {
+			t.Errorf(
+				"%q: got error %v, expected an invalid public IP",
+				testDef.publicIP,
+				err,
+			)
+		}
+	}
+}
+
+func TestValidatePrometheusTimeout(t *testing.T) {
+	testDefs := []struct {
+		refresh uint32
+		timeout uint32
+		valid   bool
+	}{
+		{refresh: 3, timeout: 3, valid: true},
+		{refresh: 3, timeout: 2, valid: true},
+		{refresh: 3, timeout: 4, valid: false},
+		{refresh: 1, timeout: 1, valid: true},
+		{refresh: 1, timeout: 3, valid: false},
+		{refresh: 10, timeout: 5, valid: true},
+	}
+	for _, testDef := range testDefs {
+		c := newTestConfig()
+		c.Prometheus.Refresh = testDef.refresh
+		c.Prometheus.Timeout = testDef.timeout
+		err := c.validate()
+		if (err == nil) != testDef.valid {
+			t.Errorf(
+				"refresh %d, timeout %d: got error %v, expected valid %v",
+				testDef.refresh,
+				testDef.timeout,
+				err,
+				testDef.valid,
+			)
+		}
+		// Values are never adjusted
+		if c.Prometheus.Refresh != testDef.refresh ||
+			c.Prometheus.Timeout != testDef.timeout {
+			t.Errorf(
+				"got refresh %d, timeout %d, expected %d, %d",
+				c.Prometheus.Refresh,
+				c.Prometheus.Timeout,
+				testDef.refresh,
+				testDef.timeout,
+			)
+		}
+	}
+}
+
+// Every problem is listed in one error, rather than only the first
+func TestValidateMultiple(t *testing.T) {
+	c := newTestConfig()
+	c.App.Refresh = 0
+	c.Prometheus.Port = 0
+	c.App.EventsSize = 0
+	err := c.validate()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	expected := "invalid config:\n" +
+		" - refresh (REFRESH) must be at least 1 second\n" +
+		" - prometheus port (PROM_PORT) 0 must be between 1 and 65535\n" +
+		" - events size (EVENTS_SIZE) 0 must be at least 1"
+	if err.Error() != expected {
+		t.Errorf("got error %q, expected %q", err, expected)
+	}
+}
+
+// Populates network and genesis values the same way LoadConfig does
+func populateTestConfig(t *testing.T, c *Config) {
+	t.Helper()
+	if err := c.populateNetworkMagic(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := c.populateByronGenesis(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := c.populateShelleyGenesis(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := c.populateShelleyTransEpoch(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestPopulateNetworkMagic(t *testing.T) {
+	testDefs := []struct {
+		appNetwork  string
+		nodeNetwork string
+		magic       uint32
+		network     string
+		expMagic    uint32
+		startTime   uint64
+		epochLength uint64
+		transEpoch  int32
+	}{
+		{
+			network:     "mainnet",
+			expMagic:    764824073,
+			startTime:   1506203091,
+			epochLength: 432000,
+			transEpoch:  208,
+		},
+		{
+			appNetwork:  "preprod",
+			network:     "preprod",
+			expMagic:    1,
+			startTime:   1654041600,
+			epochLength: 432000,
+			transEpoch:  4,
+		},
+		{
+			nodeNetwork: "preview",
+			network:     "preview",
+			expMagic:    2,
+			startTime:   1666656000,
+			epochLength: 86400,
+		},
+		{
+			magic:       764824073,
+			network:     "mainnet",
+			expMagic:    764824073,
+			startTime:   1506203091,
+			epochLength: 432000,
+			transEpoch:  208,
+		},
+		{
+			magic:       1,
+			network:     "preprod",
+			expMagic:    1,
+			startTime:   1654041600,
+			epochLength: 432000,
+			transEpoch:  4,
+		},
+		{
+			magic:       2,
+			network:     "preview",
+			expMagic:    2,
+			startTime:   1666656000,
+			epochLength: 86400,
+		},
+		{
+			magic:       4,
+			network:     "sanchonet",
+			expMagic:    4,
+			startTime:   1686789000,
+			epochLength: 86400,
+		},
+	}
+	for _, testDef := range testDefs {
+		c := newTestConfig()
+		c.App.Network = testDef.appNetwork
+		c.Node.Network = testDef.nodeNetwork
+		c.Node.NetworkMagic = testDef.magic
+		populateTestConfig(t, c)
+		if c.Node.Network != testDef.network {
+			t.Errorf(
+				"got network %q, expected %q",
+				c.Node.Network,
+				testDef.network,
+			)
+		}
+		if c.Node.NetworkMagic != testDef.expMagic {
+			t.Errorf(
+				"got network magic %d, expected %d",
+				c.Node.NetworkMagic,
+				testDef.expMagic,
+			)
+		}
+		if c.Node.ByronGenesis.StartTime != testDef.startTime {
+			t.Errorf(
+				"%s: got start time %d, expected %d",
+				testDef.network,
+				c.Node.ByronGenesis.StartTime,
+				testDef.startTime,
+			)
+		}
+		if c.Node.ShelleyGenesis.EpochLength != testDef.epochLength {
+			t.Errorf(
+				"%s: got epoch length %d, expected %d",
+				testDef.network,
+				c.Node.ShelleyGenesis.EpochLength,
+				testDef.epochLength,
+			)
+		}
+		if c.Node.ShelleyTransEpoch != testDef.transEpoch {
+			t.Errorf(
+				"%s: got shelley transition epoch %d, expected %d",
+				testDef.network,
+				c.Node.ShelleyTransEpoch,
+				testDef.transEpoch,
+			)
+		}
+	}
+}
+
+func TestPopulateNetworkMagicUnknown(t *testing.T) {
+	c := newTestConfig()
+	c.App.Network = "nosuchnet"
+	if err := c.populateNetworkMagic(); err == nil {
+		t.Errorf("expected an error for an unknown network")
+	}
+}
+
+func TestApplyNodeConfig(t *testing.T) {
+	testDefs := []struct {
+		name        string
+		portSet     bool
+		magicSet    bool
+		genesisSet  bool
+		port        uint32
+		magic       uint32
+		expPort     uint32
+		expMagic    uint32
+		network     string
+		startTime   uint64
+		epochLength uint64
+		transEpoch  int32
+	}{
+		{
+			name:        "discovered",
+			port:        3002,
+			magic:       2,
+			expPort:     3002,
+			expMagic:    2,
+			network:     "preview",
+			startTime:   1666656000,
+			epochLength: 86400,
+		},
+		{
+			name:        "nothing discovered",
+			expPort:     3001,
+			expMagic:    764824073,
+			network:     "mainnet",
+			startTime:   1506203091,
+			epochLength: 432000,
+			transEpoch:  208,
+		},
+		{
+			name:        "explicit port and magic",
+			portSet:     true,
+			magicSet:    true,
+			port:        3002,
+			magic:       2,
+			expPort:     3001,
+			expMagic:    764824073,
+			network:     "mainnet",
+			startTime:   1506203091,
+			epochLength: 432000,
+			transEpoch:  208,
+		},
+		{
+			name:        "explicit genesis",
+			genesisSet:  true,
+			magic:       1,
+			expPort:     3001,
+			expMagic:    1,
+			network:     "mainnet",
+			startTime:   1506203091,
+			epochLength: 432000,
+			transEpoch:  208,
+		},
+		{
+			name:        "unknown magic",
+			magic:       12345,
+			expPort:     3001,
+			expMagic:    12345,
+			network:     "mainnet",
+			startTime:   1506203091,
+			epochLength: 432000,
+			transEpoch:  208,
+		},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.name, func(t *testing.T) {
+			c := newTestConfig()
+			c.Node.Port = DefaultNodePort
+			populateTestConfig(t, c)
+			c.nodePortSet = testDef.portSet
+			c.nodeMagicSet = testDef.magicSet
+			c.genesisSet = testDef.genesisSet
+			err := c.applyNodeConfig(testDef.port, testDef.magic)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if c.Node.Port != testDef.expPort {
+				t.Errorf(
+					"got port %d, expected %d",
+					c.Node.Port,
+					testDef.expPort,
+				)
+			}
+			if c.Node.NetworkMagic != testDef.expMagic {
+				t.Errorf(
+					"got network magic %d, expected %d",
+					c.Node.NetworkMagic,
+					testDef.expMagic,
+				)
+			}
+			if c.Node.Network != testDef.network {
+				t.Errorf(
+					"got network %q, expected %q",
+					c.Node.Network,
+					testDef.network,
+				)
+			}
+			if c.Node.ByronGenesis.StartTime != testDef.startTime {
+				t.Errorf(
+					"got start time %d, expected %d",
+					c.Node.ByronGenesis.StartTime,
+					testDef.startTime,
+				)
+			}
+			if c.Node.ShelleyGenesis.EpochLength != testDef.epochLength {
+				t.Errorf(
+					"got epoch length %d, expected %d",
+					c.Node.ShelleyGenesis.EpochLength,
+					testDef.epochLength,
+				)
+			}
+			if c.Node.ShelleyTransEpoch != testDef.transEpoch {
+				t.Errorf(
+					"got shelley transition epoch %d, expected %d",
+					c.Node.ShelleyTransEpoch,
+					testDef.transEpoch,
+				)
+			}
+		})
+	}
+}
+
+// The "sancho" alias loads end to end, from the environment through the
+// network magic and genesis values
+func TestLoadConfigSancho(t *testing.T) {
+	testDefs := []struct {
+		envVar  string
+		network string
+	}{
+		{envVar: "NETWORK", network: "sancho"},
+		{envVar: "NETWORK", network: "sanchonet"},
+		{envVar: "CARDANO_NETWORK", network: "sancho"},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.envVar+"="+testDef.network, func(t *testing.T) {
+			*globalConfig = testDefaults
+			t.Cleanup(func() { *globalConfig = testDefaults })
+			t.Setenv(testDef.envVar, testDef.network)
+			c, err := LoadConfig("")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if c.Node.NetworkMagic != 4 {
+				t.Errorf(
+					"got network magic %d, expected %d",
+					c.Node.NetworkMagic,
+					4,
+				)
+			}
+			if c.Node.ByronGenesis.StartTime != 1686789000 {
+				t.Errorf(
+					"got start time %d, expected %d",
+					c.Node.ByronGenesis.StartTime,
+					1686789000,
+				)
+			}
+			if c.Node.ByronGenesis.K != 432 {
+				t.Errorf("got K %d, expected %d", c.Node.ByronGenesis.K, 432)
+			}
+			if c.Node.ShelleyGenesis.EpochLength != 86400 {
+				t.Errorf(
+					"got epoch length %d, expected %d",
+					c.Node.ShelleyGenesis.EpochLength,
+					86400,
+				)
+			}
+			if c.Node.ShelleyTransEpoch != 0 {
+				t.Errorf(
+					"got shelley transition epoch %d, expected %d",
+					c.Node.ShelleyTransEpoch,
+					0,
+				)
+			}
+		})
+	}
+}
+
+// Readers never see a partially applied node config
+func TestApplyNodeConfigConcurrent(t *testing.T) {
+	c := newTestConfig()
+	c.Node.Port = DefaultNodePort
+	populateTestConfig(t, c)
+	globalConfigMutex.Lock()
+	oldConfig := globalConfig
+	globalConfig = c
+	globalConfigMutex.Unlock()
+	t.Cleanup(func() {
+		globalConfigMutex.Lock()
+		globalConfig = oldConfig
+		globalConfigMutex.Unlock()
+	})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				node := GetConfig().Node
+				mainnet := node.NetworkMagic == 764824073 &&
+					node.ByronGenesis.StartTime == 1506203091 &&
+					node.ShelleyGenesis.EpochLength == 432000
+				preview := node.NetworkMagic == 2 &&
+					node.ByronGenesis.StartTime == 1666656000 &&
+					node.ShelleyGenesis.EpochLength == 86400
+				if !mainnet && !preview {
+					t.Errorf("inconsistent node config: %+v", node)
+					return
+				}
+			}
+		}()
+	}
+	if err := ApplyNodeConfig(3002, 2); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	close(done)
+	wg.Wait()
+	if network := GetConfig().Node.Network; network != "preview" {
+		t.Errorf("got network %q, expected %q", network, "preview")
+	}
+	// The original instance is left as it was
+	if c.Node.Network != "mainnet" || c.Node.Port != DefaultNodePort {
+		t.Errorf(
+			"original config changed to %s port %d",
+			c.Node.Network,
+			c.Node.Port,
+		)
+	}
+}
+
+func TestLoadConfigEnableMouse(t *testing.T) {
+	testDefs := []struct {
+		value    string
+		expected bool
+	}{
+		// Off by default to preserve copy and paste
+		{value: "", expected: false},
+		{value: "true", expected: true},
+		{value: "false", expected: false},
+	}
+	for _, testDef := range testDefs {
+		t.Run("ENABLE_MOUSE="+testDef.value, func(t *testing.T) {
+			*globalConfig = testDefaults
+			t.Cleanup(func() { *globalConfig = testDefaults })
+			if testDef.value != "" {
+				t.Setenv("ENABLE_MOUSE", testDef.value)
+			}
+			c, err := LoadConfig("")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if c.App.EnableMouse != testDef.expected {
+				t.Errorf(
+					"got %v, expected %v",
+					c.App.EnableMouse,
+					testDef.expected,
+				)
+			}
+		})
+	}
+}
+
+func TestLocation(t *testing.T) {
+	testDefs := []struct {
+		timezone string
+		expected string
+	}{
+		// Local time is the default
+		{timezone: "", expected: time.Local.String()},
+		{timezone: "UTC", expected: "UTC"},
+		{timezone: "America/New_York", expected: "America/New_York"},
+	}
+	for _, testDef := range testDefs {
+		c := newTestConfig()
+		c.App.Timezone = testDef.timezone
+		if err := c.validate(); err != nil {
+			t.Fatalf("%q: unexpected error: %s", testDef.timezone, err)
+		}
+		if got := c.Location().String(); got != testDef.expected {
+			t.Errorf(
+				"%q: got %s, expected %s",
+				testDef.timezone,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestLoadConfigNodeBinary(t *testing.T) {
+	testDefs := []struct {
+		value    string
+		expected string
+		set      bool
+	}{
+		// The default binary is only a fallback for detection
+		{value: "", expected: DefaultNodeBinary, set: false},
+		{value: "cardano-node", expected: "cardano-node", set: true},
+		{value: "/usr/bin/dingo", expected: "/usr/bin/dingo", set: true},
+	}
+	for _, testDef := range testDefs {
+		t.Run("CARDANO_NODE_BINARY="+testDef.value, func(t *testing.T) {
+			*globalConfig = testDefaults
+			t.Cleanup(func() { *globalConfig = testDefaults })
+			if testDef.value != "" {
+				t.Setenv("CARDANO_NODE_BINARY", testDef.value)
+			}
+			c, err := LoadConfig("")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if c.Node.Binary != testDef.expected ||
+				c.NodeBinarySet() != testDef.set {
+				t.Errorf(
+					"got %s (set %v), expected %s (set %v)",
+					c.Node.Binary,
+					c.NodeBinarySet(),
+					testDef.expected,
+					testDef.set,
+				)
+			}
+		})
+	}
+}
+
+func TestLoadConfigStartPage(t *testing.T) {
+	testDefs := []struct {
+		value    string
+		expected string
+	}{
+		// The main page is the default
+		{value: "", expected: "main"},
+		{value: "peers", expected: "peers"},
+	}
+	for _, testDef := range testDefs {
+		t.Run("START_PAGE="+testDef.value, func(t *testing.T) {
+			*globalConfig = testDefaults
+			t.Cleanup(func() { *globalConfig = testDefaults })
+			if testDef.value != "" {
+				t.Setenv("START_PAGE", testDef.value)
+			}
+			c, err := LoadConfig("")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if c.App.StartPage != testDef.expected {
+				t.Errorf(
+					"got %s, expected %s",
+					c.App.StartPage,
+					testDef.expected,
+				)
+			}
+		})
+	}
+}
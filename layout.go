@@ -0,0 +1,180 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// Main text section, with the left and middle columns
+var layout = tview.NewFlex()
+var leftSide = tview.NewFlex().SetDirection(tview.FlexRow)
+var middleSide = tview.NewFlex().SetDirection(tview.FlexRow)
+
+// Panels which can be hidden, in the order of their toggle keys (1-7)
+var panelNames = []string{
+	"node",
+	"resources",
+	"connections",
+	"core",
+	"chain",
+	"block",
+	"peers",
+}
+
+// Track which panels are visible
+var panelVisibility = newPanelVisibility(nil)
+
+// A panel and its size within its column
+type layoutPanel struct {
+	name       string
+	view       tview.Primitive
+	fixedSize  int
+	proportion int
+	focus      bool
+	// Height needed to show the panel's content, for panels which fill their
+	// column
+	minSize int
+}
+
+// Creates a panel visibility map with all panels visible except the hidden
+// ones, ignoring unknown panel names
+func newPanelVisibility(hidden []string) map[string]bool {
+	ret := make(map[string]bool)
+	for _, name := range panelNames {
+		ret[name] = true
+	}
+	for _, name := range hidden {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := ret[name]; !ok {
+			slog.Warn("unknown panel name", "name", name)
+			continue
+		}
+		ret[name] = false
+	}
+	return ret
+}
+
+// Toggles the visibility of a panel by its position in panelNames
+func togglePanel(idx int) {
+	if idx < 0 || idx >= len(panelNames) {
+		return
+	}
+	name := panelNames[idx]
+	panelVisibility[name] = !panelVisibility[name]
+}
+
+// Whether only the peers panel is updated on refresh, with the other panels
+// paused on their last text to reduce CPU use
+var peersOnlyFocus bool
+
+// Returns whether a panel is updated on refresh
+func isPanelLive(name string) bool {
+	return !peersOnlyFocus || name == "peers"
+}
+
+// Toggles peers-only focus and returns a notice describing the new mode
+func togglePeersOnlyFocus() string {
+	peersOnlyFocus = !peersOnlyFocus
+	if peersOnlyFocus {
+		return "Peers-only focus: other panels are paused"
+	}
+	return "All panels are updating"
+}
+
+// Returns the visible panels for the left and middle columns
+func getLayoutPanels(
+	visibility map[string]bool,
+	nodeRole string,
+) ([]layoutPanel, []layoutPanel) {
+	left := []layoutPanel{
+		{name: "node", view: nodeTextView, fixedSize: 8},
+		{name: "resources", view: resourceTextView, fixedSize: 13},
+		{name: "connections", view: connectionTextView, fixedSize: 12},
+	}
+	// Core panel fills the rest of the column on block producers
+	if nodeRole == "Core" {
+		left = append(
+			left,
+			layoutPanel{
+				name:       "core",
+				view:       coreTextView,
+				proportion: 1,
+				minSize:    10,
+			},
+		)
+	}
+	middle := []layoutPanel{
+		{name: "chain", view: chainTextView, fixedSize: 9, proportion: 1},
+		{name: "block", view: blockTextView, fixedSize: 5},
+		// Peers needs room for the RTT summary and a few peers
+		{
+			name:       "peers",
+			view:       peerTextView,
+			proportion: 3,
+			focus:      true,
+			minSize:    14,
+		},
+	}
+	hidden := func(p layoutPanel) bool { return !visibility[p.name] }
+	return slices.DeleteFunc(left, hidden), slices.DeleteFunc(middle, hidden)
+}
+
+// Returns the terminal lines needed to show the content of the visible
+// panels, along with our header and footer
+func getLayoutMinLines(left, middle []layoutPanel) int {
+	columnLines := func(panels []layoutPanel) int {
+		ret := 0
+		for _, p := range panels {
+			ret += max(p.fixedSize, p.minSize)
+		}
+		return ret
+	}
+	return headerLines + max(columnLines(left), columnLines(middle)) +
+		footerLines
+}
+
+// Assembles the main text section from the visible panels. A column is
+// dropped when all of its panels are hidden, so the other column takes up
+// the space.
+func buildLayout() {
+	left, middle := getLayoutPanels(panelVisibility, role)
+	termMinLines.Store(int32(getLayoutMinLines(left, middle)))
+	layout.Clear()
+	leftSide.Clear()
+	middleSide.Clear()
+	if len(left) > 0 {
+		for _, p := range left {
+			leftSide.AddItem(p.view, p.fixedSize, p.proportion, p.focus)
+		}
+		// Fill any remaining space
+		if !slices.ContainsFunc(left, func(p layoutPanel) bool {
+			return p.proportion > 0
+		}) {
+			leftSide.AddItem(nil, 0, 1, false)
+		}
+		layout.AddItem(leftSide, 37, 1, false)
+	}
+	if len(middle) > 0 {
+		for _, p := range middle {
+			middleSide.AddItem(p.view, p.fixedSize, p.proportion, p.focus)
+		}
+		layout.AddItem(middleSide, 74, 2, true)
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewPanelVisibility(t *testing.T) {
+	logBuf := setLogCapture(t)
+	testDefs := []struct {
+		hidden   []string
+		expected []string
+	}{
+		{hidden: nil, expected: nil},
+		{hidden: []string{"core"}, expected: []string{"core"}},
+		// Names are trimmed and case-insensitive
+		{
+			hidden:   []string{" Peers", "CHAIN "},
+			expected: []string{"chain", "peers"},
+		},
+		// Unknown names are ignored
+		{
+			hidden:   []string{"governance", "block"},
+			expected: []string{"block"},
+		},
+	}
+	for _, testDef := range testDefs {
+		visibility := newPanelVisibility(testDef.hidden)
+		if len(visibility) != len(panelNames) {
+			t.Errorf(
+				"%v: got %d panels, expected %d",
+				testDef.hidden,
+				len(visibility),
+				len(panelNames),
+			)
+		}
+		var hidden []string
+		for _, name := range panelNames {
+			if !visibility[name] {
+				hidden = append(hidden, name)
+			}
+		}
+		if !reflect.DeepEqual(hidden, testDef.expected) {
+			t.Errorf(
+				"%v: got hidden %v, expected %v",
+				testDef.hidden,
+				hidden,
+				testDef.expected,
+			)
+		}
+	}
+	if !strings.Contains(logBuf.String(), "name=governance") {
+		t.Errorf("expected a warning for an unknown panel name")
+	}
+}
+
+func TestTogglePanel(t *testing.T) {
+	oldVisibility := panelVisibility
+	t.Cleanup(func() {
+		panelVisibility = oldVisibility
+	})
+	panelVisibility = newPanelVisibility(nil)
+	// Keys 1-7 toggle panels in order
+	togglePanel(3)
+	if panelVisibility["core"] {
+		t.Errorf("expected the core panel to be hidden")
+	}
+	togglePanel(3)
+	if !panelVisibility["core"] {
+		t.Errorf("expected the core panel to be visible")
+	}
+	// Out of range keys do nothing
+	togglePanel(-1)
+	togglePanel(len(panelNames))
+	if !reflect.DeepEqual(panelVisibility, newPanelVisibility(nil)) {
+		t.Errorf("got %v, expected all panels visible", panelVisibility)
+	}
+}
+
+func TestGetLayoutPanels(t *testing.T) {
+	names := func(panels []layoutPanel) []string {
+		var ret []string
+		for _, p := range panels {
+			ret = append(ret, p.name)
+		}
+		return ret
+	}
+	testDefs := []struct {
+		nodeRole string
+		hidden   []string
+		left     []string
+		middle   []string
+	}{
+		{
+			nodeRole: "Relay",
+			left:     []string{"node", "resources", "connections"},
+			middle:   []string{"chain", "block", "peers"},
+		},
+		{
+			nodeRole: "Core",
+			left:     []string{"node", "resources", "connections", "core"},
+			middle:   []string{"chain", "block", "peers"},
+		},
+		// Relays have no core panel to hide
+		{
+			nodeRole: "Relay",
+			hidden:   []string{"core", "block"},
+			left:     []string{"node", "resources", "connections"},
+			middle:   []string{"chain", "peers"},
+		},
+		// Peers full-screen
+		{
+			nodeRole: "Core",
+			hidden: []string{
+				"node", "resources", "connections", "core", "chain", "block",
+			},
+			middle: []string{"peers"},
+		},
+	}
+	for _, testDef := range testDefs {
+		left, middle := getLayoutPanels(
+			newPanelVisibility(testDef.hidden),
+			testDef.nodeRole,
+		)
+		if got := names(left); !reflect.DeepEqual(got, testDef.left) {
+			t.Errorf(
+				"%s, hidden %v: got left %v, expected %v",
+				testDef.nodeRole,
+				testDef.hidden,
+				got,
+				testDef.left,
+			)
+		}
+		if got := names(middle); !reflect.DeepEqual(got, testDef.middle) {
+			t.Errorf(
+				"%s, hidden %v: got middle %v, expected %v",
+				testDef.nodeRole,
+				testDef.hidden,
+				got,
+				testDef.middle,
+			)
+		}
+	}
+}
+
+func TestBuildLayout(t *testing.T) {
+	oldVisibility := panelVisibility
+	oldRole := role
+	oldMinLines := termMinLines.Load()
+	t.Cleanup(func() {
+		panelVisibility = oldVisibility
+		role = oldRole
+		buildLayout()
+		termMinLines.Store(oldMinLines)
+	})
+	testDefs := []struct {
+		nodeRole string
+		hidden   []string
+		columns  int
+		left     int
+		middle   int
+	}{
+		// The relay left column is padded to fill the space
+		{nodeRole: "Relay", columns: 2, left: 4, middle: 3},
+		{nodeRole: "Core", columns: 2, left: 4, middle: 3},
+		{
+			nodeRole: "Relay",
+			hidden:   []string{"node", "resources", "connections"},
+			columns:  1,
+			middle:   3,
+		},
+		{
+			nodeRole: "Core",
+			hidden:   []string{"chain", "block", "peers"},
+			columns:  1,
+			left:     4,
+		},
+		{nodeRole: "Relay", hidden: panelNames},
+	}
+	for _, testDef := range testDefs {
+		panelVisibility = newPanelVisibility(testDef.hidden)
+		role = testDef.nodeRole
+		buildLayout()
+		got := []int{
+			layout.GetItemCount(),
+			leftSide.GetItemCount(),
+			middleSide.GetItemCount(),
+		}
+		expected := []int{testDef.columns, testDef.left, testDef.middle}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf(
+				"%s, hidden %v: got items %v, expected %v",
+				testDef.nodeRole,
+				testDef.hidden,
+				got,
+				expected,
+			)
+		}
+		left, middle := getLayoutPanels(panelVisibility, role)
+		minLines := getLayoutMinLines(left, middle)
+		if got := int(termMinLines.Load()); got != minLines {
+			t.Errorf("got min lines %d, expected %d", got, minLines)
+		}
+	}
+}
+
+func TestGetLayoutMinLines(t *testing.T) {
+	testDefs := []struct {
+		nodeRole string
+		hidden   []string
+		expected int
+	}{
+		// The left column is the tallest
+		{nodeRole: "Relay", expected: 36},
+		// The core panel needs room for its content
+		{nodeRole: "Core", expected: 46},
+		{nodeRole: "Core", hidden: []string{"core"}, expected: 36},
+		// The middle column takes over when the left one is hidden
+		{
+			nodeRole: "Core",
+			hidden:   []string{"node", "resources", "connections", "core"},
+			expected: 31,
+		},
+		{
+			nodeRole: "Relay",
+			hidden:   []string{"resources", "connections"},
+			expected: 31,
+		},
+		// Only our header and footer remain
+		{nodeRole: "Relay", hidden: panelNames, expected: 3},
+	}
+	for _, testDef := range testDefs {
+		left, middle := getLayoutPanels(
+			newPanelVisibility(testDef.hidden),
+			testDef.nodeRole,
+		)
+		if got := getLayoutMinLines(left, middle); got != testDef.expected {
+			t.Errorf(
+				"%s, hidden %v: got %d, expected %d",
+				testDef.nodeRole,
+				testDef.hidden,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestIsTerminalAdequate(t *testing.T) {
+	oldMinLines := termMinLines.Load()
+	t.Cleanup(func() {
+		termMinLines.Store(oldMinLines)
+	})
+	termMinLines.Store(46)
+	testDefs := []struct {
+		cols     int
+		lines    int
+		expected bool
+	}{
+		{cols: 80, lines: 46, expected: true},
+		{cols: 80, lines: 45, expected: false},
+		{cols: 71, lines: 50, expected: false},
+		{cols: 72, lines: 50, expected: true},
+	}
+	for _, testDef := range testDefs {
+		got := isTerminalAdequate(testDef.cols, testDef.lines)
+		if got != testDef.expected {
+			t.Errorf(
+				"%dx%d: got %v, expected %v",
+				testDef.cols,
+				testDef.lines,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestTogglePeersOnlyFocus(t *testing.T) {
+	oldFocus := peersOnlyFocus
+	t.Cleanup(func() {
+		peersOnlyFocus = oldFocus
+	})
+	peersOnlyFocus = false
+	panels := []string{
+		"node",
+		"resources",
+		"connections",
+		"core",
+		"chain",
+		"block",
+		"peers",
+	}
+	testDefs := []struct {
+		notice string
+		live   []string
+	}{
+		{
+			notice: "Peers-only focus: other panels are paused",
+			live:   []string{"peers"},
+		},
+		{notice: "All panels are updating", live: panels},
+	}
+	for _, testDef := range testDefs {
+		if got := togglePeersOnlyFocus(); got != testDef.notice {
+			t.Errorf("got %q, expected %q", got, testDef.notice)
+		}
+		for _, name := range panels {
+			expected := slices.Contains(testDef.live, name)
+			if got := isPanelLive(name); got != expected {
+				t.Errorf(
+					"%s, %s: got %v, expected %v",
+					testDef.notice,
+					name,
+					got,
+					expected,
+				)
+			}
+		}
+	}
+}
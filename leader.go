@@ -0,0 +1,116 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// Cached leadership schedule, reloaded when the file changes
+var leaderSchedule []uint64
+var leaderScheduleModTime time.Time
+
+// Parses a leadership schedule into a sorted list of slots
+//
+// This accepts the JSON output of "cardano-cli query leadership-schedule",
+// which is a list of objects with a slotNumber, or text with a slot number
+// at the start of each line, which includes the default table output.
+func parseLeaderSchedule(data []byte) ([]uint64, error) {
+	var slots []uint64
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var entries []struct {
+			SlotNumber uint64 `json:"slotNumber"`
+		}
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			slots = append(slots, entry.SlotNumber)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			// Skip headers, dividers, and anything else without a slot
+			slot, err := strconv.ParseUint(fields[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			slots = append(slots, slot)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+	return slots, nil
+}
+
+// Returns the configured leadership schedule, reading the file again when it
+// has been modified
+func getLeaderSchedule() []uint64 {
+	cfg := config.GetConfig()
+	if cfg.App.LeaderScheduleFile == "" {
+		return nil
+	}
+	info, err := os.Stat(cfg.App.LeaderScheduleFile)
+	if err != nil {
+		return leaderSchedule
+	}
+	if !info.ModTime().Equal(leaderScheduleModTime) {
+		data, err := os.ReadFile(cfg.App.LeaderScheduleFile)
+		if err != nil {
+			return leaderSchedule
+		}
+		slots, err := parseLeaderSchedule(data)
+		if err != nil {
+			return leaderSchedule
+		}
+		leaderSchedule = slots
+		leaderScheduleModTime = info.ModTime()
+	}
+	return leaderSchedule
+}
+
+// Returns the first scheduled slot after the given slot, with false when
+// there are none
+func getNextLeaderSlot(schedule []uint64, slot uint64) (uint64, bool) {
+	idx := sort.Search(len(schedule), func(i int) bool {
+		return schedule[i] > slot
+	})
+	if idx == len(schedule) {
+		return 0, false
+	}
+	return schedule[idx], true
+}
+
+// Returns the time remaining until a slot, rounded to the second
+func getSlotCountdown(slot uint64, now time.Time) time.Duration {
+	remaining := getSlotTime(slot).Sub(now).Round(time.Second)
+	return max(remaining, 0)
+}
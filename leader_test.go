@@ -0,0 +1,243 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+func TestParseLeaderSchedule(t *testing.T) {
+	testDefs := []struct {
+		name     string
+		data     string
+		expected []uint64
+	}{
+		{
+			name: "json",
+			data: `[
+    {"slotNumber": 134218200, "slotTime": "2024-10-25T22:54:51Z"},
+    {"slotNumber": 134215900, "slotTime": "2024-10-25T22:16:31Z"}
+]`,
+			expected: []uint64{134215900, 134218200},
+		},
+		{
+			name: "table",
+			data: `     SlotNo                          UTC Time
+-------------------------------------------------------------
+     134218200                   2024-10-25 22:54:51 UTC
+     134215900                   2024-10-25 22:16:31 UTC
+`,
+			expected: []uint64{134215900, 134218200},
+		},
+		{
+			name:     "plain slots",
+			data:     "300\n100\n\n200\n",
+			expected: []uint64{100, 200, 300},
+		},
+		{name: "empty", data: ""},
+		{name: "empty json", data: "[]"},
+	}
+	for _, testDef := range testDefs {
+		got, err := parseLeaderSchedule([]byte(testDef.data))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", testDef.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, testDef.expected) {
+			t.Errorf(
+				"%s: got %v, expected %v",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestParseLeaderScheduleInvalid(t *testing.T) {
+	_, err := parseLeaderSchedule([]byte(`[{"slotNumber": "x"}`))
+	if err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestGetNextLeaderSlot(t *testing.T) {
+	schedule := []uint64{100, 200, 300}
+	testDefs := []struct {
+		slot     uint64
+		expected uint64
+		ok       bool
+	}{
+		{slot: 0, expected: 100, ok: true},
+		// A slot that has just been led is no longer next
+		{slot: 100, expected: 200, ok: true},
+		{slot: 250, expected: 300, ok: true},
+		{slot: 300},
+		{slot: 1000},
+	}
+	for _, testDef := range testDefs {
+		got, ok := getNextLeaderSlot(schedule, testDef.slot)
+		if got != testDef.expected || ok != testDef.ok {
+			t.Errorf(
+				"%d: got (%d, %v), expected (%d, %v)",
+				testDef.slot,
+				got,
+				ok,
+				testDef.expected,
+				testDef.ok,
+			)
+		}
+	}
+	if _, ok := getNextLeaderSlot(nil, 0); ok {
+		t.Errorf("expected no next slot in an empty schedule")
+	}
+}
+
+func TestGetSlotCountdown(t *testing.T) {
+	setTestGenesis(t, "mainnet")
+	// One day after the first Shelley slot
+	const slot = 4579200
+	slotTime := mustParseTime(t, "2020-07-30T21:44:51Z")
+	testDefs := []struct {
+		now      time.Time
+		expected time.Duration
+	}{
+		{
+			now:      slotTime.Add(-252 * time.Second),
+			expected: 4*time.Minute + 12*time.Second,
+		},
+		{now: slotTime.Add(-3 * time.Hour), expected: 3 * time.Hour},
+		// Partial seconds are rounded
+		{now: slotTime.Add(-1600 * time.Millisecond), expected: 2 * time.Second},
+		{now: slotTime.Add(-1400 * time.Millisecond), expected: time.Second},
+		// Slots in the past have no time remaining
+		{now: slotTime, expected: 0},
+		{now: slotTime.Add(time.Minute), expected: 0},
+	}
+	for _, testDef := range testDefs {
+		got := getSlotCountdown(slot, testDef.now)
+		if got != testDef.expected {
+			t.Errorf(
+				"%s: got %s, expected %s",
+				testDef.now.Format(time.RFC3339Nano),
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+// Sets the leadership schedule file for the duration of a test
+func setLeaderScheduleFile(t *testing.T, path string) {
+	t.Helper()
+	cfg := config.GetConfig()
+	oldFile := cfg.App.LeaderScheduleFile
+	t.Cleanup(func() {
+		cfg.App.LeaderScheduleFile = oldFile
+		leaderSchedule = nil
+		leaderScheduleModTime = time.Time{}
+	})
+	cfg.App.LeaderScheduleFile = path
+	leaderSchedule = nil
+	leaderScheduleModTime = time.Time{}
+}
+
+func TestGetLeaderSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.txt")
+	setLeaderScheduleFile(t, "")
+	// No schedule is configured
+	if got := getLeaderSchedule(); got != nil {
+		t.Errorf("got %v, expected no schedule", got)
+	}
+	setLeaderScheduleFile(t, path)
+	if err := os.WriteFile(path, []byte("200\n100\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []uint64{100, 200}
+	if got := getLeaderSchedule(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+	// A modified file is read again
+	if err := os.WriteFile(path, []byte("300\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected = []uint64{300}
+	if got := getLeaderSchedule(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+	// The last schedule is kept when the file goes away or is invalid
+	if err := os.WriteFile(path, []byte("[{"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	modTime = modTime.Add(time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := getLeaderSchedule(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := getLeaderSchedule(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestGetCoreTextNextLeaderSlot(t *testing.T) {
+	setPanelFixtures(t)
+	path := filepath.Join(t.TempDir(), "schedule.txt")
+	setLeaderScheduleFile(t, path)
+	// The fixture tip is one second before now
+	tip := promMetrics.SlotNum + 12
+	testDefs := []struct {
+		schedule string
+		expected string
+	}{
+		{
+			schedule: "100\n" + strconv.FormatUint(tip+253, 10) + "\n",
+			expected: "[white]in ~4m12s\n",
+		},
+		{schedule: "100\n", expected: "[yellow]none scheduled\n"},
+	}
+	for i, testDef := range testDefs {
+		err := os.WriteFile(path, []byte(testDef.schedule), 0o600)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		modTime := time.Now().Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		text := getCoreText(context.Background())
+		expected := " [green]Next slot  : " + testDef.expected
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in:\n%s", expected, text)
+		}
+	}
+}
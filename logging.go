@@ -0,0 +1,88 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// Configures the default slog logger from our config
+//
+// The TUI owns stdout, so logs are discarded unless a log file is configured
+func setupLogging(cfg *config.Config) error {
+	var w io.Writer = io.Discard
+	if cfg.App.LogFile != "" {
+		f, err := os.OpenFile(
+			cfg.App.LogFile,
+			os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+			0o640,
+		)
+		if err != nil {
+			return fmt.Errorf("error opening log file: %s", err)
+		}
+		w = f
+	}
+	level, err := parseLogLevel(cfg.App.LogLevel)
+	if err != nil {
+		return err
+	}
+	handler, err := newLogHandler(w, cfg.App.LogFormat, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Returns a text or JSON slog handler writing to w at the given level
+func newLogHandler(
+	w io.Writer,
+	format string,
+	level slog.Level,
+) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{
+		Level: level,
+	}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.NewTextHandler(w, opts), nil
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", format)
+	}
+}
+
+// Maps a log level name to a slog.Level
+func parseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level: %s", level)
+	}
+}
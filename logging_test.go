@@ -0,0 +1,238 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+func TestNewLogHandlerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := newLogHandler(&buf, "JSON", slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	logger := slog.New(handler)
+	logger.Info("peer analysis complete", "peers", 12, "source", "ping")
+	logger.Warn("failed to write metrics CSV", "error", "disk full")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, expected 2: %q", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse %q: %s", lines[0], err)
+	}
+	expected := map[string]any{
+		"level":  "INFO",
+		"msg":    "peer analysis complete",
+		"peers":  float64(12),
+		"source": "ping",
+	}
+	for key, value := range expected {
+		if entry[key] != value {
+			t.Errorf("%s: got %v, expected %v", key, entry[key], value)
+		}
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("failed to parse %q: %s", lines[1], err)
+	}
+	if entry["error"] != "disk full" {
+		t.Errorf("got error %v, expected disk full", entry["error"])
+	}
+}
+
+func TestNewLogHandlerFormat(t *testing.T) {
+	testDefs := []struct {
+		format   string
+		expected string
+		err      bool
+	}{
+		{format: "", expected: "level=INFO msg=hello"},
+		{format: "text", expected: "level=INFO msg=hello"},
+		{format: "json", expected: `"msg":"hello"`},
+		{format: "yaml", err: true},
+	}
+	for _, testDef := range testDefs {
+		var buf bytes.Buffer
+		handler, err := newLogHandler(&buf, testDef.format, slog.LevelInfo)
+		if testDef.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", testDef.format)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", testDef.format, err)
+		}
+		slog.New(handler).Info("hello")
+		if !strings.Contains(buf.String(), testDef.expected) {
+			t.Errorf(
+				"%q: got %q, expected it to contain %q",
+				testDef.format,
+				buf.String(),
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestSetupLoggingFile(t *testing.T) {
+	oldLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(oldLogger)
+	})
+	logFile := filepath.Join(t.TempDir(), "nview.log")
+	cfg := &config.Config{}
+	cfg.App.LogFile = logFile
+	cfg.App.LogFormat = "json"
+	cfg.App.LogLevel = "info"
+	if err := setupLogging(cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	slog.Info("connected to node", "port", 12798)
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(data), &entry); err != nil {
+		t.Fatalf("failed to parse %q: %s", data, err)
+	}
+	if entry["msg"] != "connected to node" || entry["port"] != float64(12798) {
+		t.Errorf("got %v, expected the logged message and port", entry)
+	}
+	// Logs are appended to an existing file
+	slog.Info("connected to node", "port", 12799)
+	data, err = os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("got %d lines, expected 2", lines)
+	}
+}
+
+func TestSetupLoggingFileError(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.App.LogFile = filepath.Join(t.TempDir(), "missing", "nview.log")
+	if err := setupLogging(cfg); err == nil {
+		t.Errorf("expected an error for an unwritable log file")
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	testDefs := []struct {
+		level    string
+		expected slog.Level
+		err      bool
+	}{
+		{level: "debug", expected: slog.LevelDebug},
+		{level: "DEBUG", expected: slog.LevelDebug},
+		{level: "", expected: slog.LevelInfo},
+		{level: "info", expected: slog.LevelInfo},
+		{level: "warn", expected: slog.LevelWarn},
+		{level: "warning", expected: slog.LevelWarn},
+		{level: "error", expected: slog.LevelError},
+		{level: "trace", expected: slog.LevelInfo, err: true},
+	}
+	for _, testDef := range testDefs {
+		got, err := parseLogLevel(testDef.level)
+		if testDef.err != (err != nil) {
+			t.Errorf(
+				"%q: got error %v, expected error %v",
+				testDef.level,
+				err,
+				testDef.err,
+			)
+		}
+		if got != testDef.expected {
+			t.Errorf(
+				"%q: got %v, expected %v",
+				testDef.level,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestSetupLoggingLevel(t *testing.T) {
+	oldLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(oldLogger)
+	})
+	testDefs := []struct {
+		level   string
+		enabled map[slog.Level]bool
+	}{
+		{
+			level: "debug",
+			enabled: map[slog.Level]bool{
+				slog.LevelDebug: true,
+				slog.LevelInfo:  true,
+			},
+		},
+		{
+			level: "info",
+			enabled: map[slog.Level]bool{
+				slog.LevelDebug: false,
+				slog.LevelInfo:  true,
+			},
+		},
+		{
+			level: "error",
+			enabled: map[slog.Level]bool{
+				slog.LevelWarn:  false,
+				slog.LevelError: true,
+			},
+		},
+	}
+	for _, testDef := range testDefs {
+		cfg := &config.Config{}
+		cfg.App.LogLevel = testDef.level
+		if err := setupLogging(cfg); err != nil {
+			t.Fatalf("%q: unexpected error: %s", testDef.level, err)
+		}
+		handler := slog.Default().Handler()
+		for level, expected := range testDef.enabled {
+			got := handler.Enabled(context.Background(), level)
+			if got != expected {
+				t.Errorf(
+					"%q: got enabled %v for %v, expected %v",
+					testDef.level,
+					got,
+					level,
+					expected,
+				)
+			}
+		}
+	}
+	// Unknown levels are rejected rather than ignored
+	cfg := &config.Config{}
+	cfg.App.LogLevel = "verbose"
+	if err := setupLogging(cfg); err == nil {
+		t.Errorf("expected an error for an unknown log level")
+	}
+}
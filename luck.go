@@ -0,0 +1,68 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+)
+
+// Adopted blocks at the first slot we saw in the current epoch, since the
+// node only reports adopted blocks since it started
+type luckBaseline struct {
+	epoch       uint64
+	slotInEpoch uint64
+	adopted     uint64
+}
+
+var luckBase *luckBaseline
+
+// Returns the baseline for the current epoch, resetting it on a new epoch or
+// node restart
+func getLuckBaseline(metrics *PromMetrics) luckBaseline {
+	if luckBase == nil ||
+		luckBase.epoch != metrics.EpochNum ||
+		luckBase.slotInEpoch > metrics.SlotInEpoch ||
+		luckBase.adopted > metrics.Adopted {
+		luckBase = &luckBaseline{
+			epoch:       metrics.EpochNum,
+			slotInEpoch: metrics.SlotInEpoch,
+			adopted:     metrics.Adopted,
+		}
+	}
+	return *luckBase
+}
+
+// Returns the expected number of blocks for a pool over a number of slots,
+// using the Praos leader probability 1 - (1 - f)^stake
+func getExpectedBlocks(
+	slots uint64,
+	activeSlotsCoeff float64,
+	stake float64,
+) float64 {
+	if activeSlotsCoeff <= 0 || activeSlotsCoeff >= 1 || stake <= 0 {
+		return 0
+	}
+	p := 1 - math.Pow(1-activeSlotsCoeff, stake)
+	return float64(slots) * p
+}
+
+// Returns actual blocks as a percentage of expected blocks, and false when no
+// blocks are expected yet
+func getLuck(actual uint64, expected float64) (float64, bool) {
+	if expected <= 0 {
+		return 0, false
+	}
+	return float64(actual) / expected * 100, true
+}
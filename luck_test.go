@@ -0,0 +1,194 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+func TestGetExpectedBlocks(t *testing.T) {
+	// Leader probability per slot for 0.1% of stake
+	p := 1 - math.Pow(0.95, 0.001)
+	testDefs := []struct {
+		name     string
+		slots    uint64
+		coeff    float64
+		stake    float64
+		expected float64
+	}{
+		{
+			name:     "full epoch",
+			slots:    432000,
+			coeff:    0.05,
+			stake:    0.001,
+			expected: 432000 * p,
+		},
+		// Expected blocks are prorated over the slots so far
+		{
+			name:     "half epoch",
+			slots:    216000,
+			coeff:    0.05,
+			stake:    0.001,
+			expected: 216000 * p,
+		},
+		// The whole stake leads each active slot
+		{
+			name:     "all stake",
+			slots:    1000,
+			coeff:    0.05,
+			stake:    1,
+			expected: 50,
+		},
+		{name: "no slots", slots: 0, coeff: 0.05, stake: 0.001},
+		{name: "no stake", slots: 432000, coeff: 0.05, stake: 0},
+		{name: "no coefficient", slots: 432000, coeff: 0, stake: 0.001},
+		{name: "invalid coefficient", slots: 432000, coeff: 1, stake: 0.001},
+	}
+	for _, testDef := range testDefs {
+		got := getExpectedBlocks(testDef.slots, testDef.coeff, testDef.stake)
+		if math.Abs(got-testDef.expected) > 1e-9 {
+			t.Errorf(
+				"%s: got %v, expected %v",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+	// About 22 blocks an epoch for 0.1% of stake
+	if got := getExpectedBlocks(432000, 0.05, 0.001); math.Round(got) != 22 {
+		t.Errorf("got %v, expected about 22 blocks", got)
+	}
+}
+
+func TestGetLuck(t *testing.T) {
+	testDefs := []struct {
+		actual   uint64
+		expected float64
+		luck     float64
+		ok       bool
+	}{
+		{actual: 28, expected: 25, luck: 112, ok: true},
+		{actual: 0, expected: 25, luck: 0, ok: true},
+		{actual: 1, expected: 0.5, luck: 200, ok: true},
+		// No blocks are expected yet
+		{actual: 0, expected: 0},
+		{actual: 3, expected: 0},
+	}
+	for _, testDef := range testDefs {
+		luck, ok := getLuck(testDef.actual, testDef.expected)
+		if math.Abs(luck-testDef.luck) > 1e-9 || ok != testDef.ok {
+			t.Errorf(
+				"%d of %v: got (%v, %v), expected (%v, %v)",
+				testDef.actual,
+				testDef.expected,
+				luck,
+				ok,
+				testDef.luck,
+				testDef.ok,
+			)
+		}
+	}
+}
+
+func TestGetLuckBaseline(t *testing.T) {
+	t.Cleanup(func() {
+		luckBase = nil
+	})
+	luckBase = nil
+	testDefs := []struct {
+		name     string
+		metrics  PromMetrics
+		expected luckBaseline
+	}{
+		{
+			name:     "first",
+			metrics:  PromMetrics{EpochNum: 500, SlotInEpoch: 1000, Adopted: 4},
+			expected: luckBaseline{epoch: 500, slotInEpoch: 1000, adopted: 4},
+		},
+		{
+			name:     "same epoch",
+			metrics:  PromMetrics{EpochNum: 500, SlotInEpoch: 9000, Adopted: 6},
+			expected: luckBaseline{epoch: 500, slotInEpoch: 1000, adopted: 4},
+		},
+		{
+			name:     "node restart",
+			metrics:  PromMetrics{EpochNum: 500, SlotInEpoch: 9500, Adopted: 0},
+			expected: luckBaseline{epoch: 500, slotInEpoch: 9500},
+		},
+		{
+			name:     "new epoch",
+			metrics:  PromMetrics{EpochNum: 501, SlotInEpoch: 10, Adopted: 2},
+			expected: luckBaseline{epoch: 501, slotInEpoch: 10, adopted: 2},
+		},
+		// A rollback to an earlier slot
+		{
+			name:     "earlier slot",
+			metrics:  PromMetrics{EpochNum: 501, SlotInEpoch: 5, Adopted: 2},
+			expected: luckBaseline{epoch: 501, slotInEpoch: 5, adopted: 2},
+		},
+	}
+	for _, testDef := range testDefs {
+		got := getLuckBaseline(&testDef.metrics)
+		if got != testDef.expected {
+			t.Errorf(
+				"%s: got %+v, expected %+v",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetCoreTextLuck(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldStake := cfg.App.PoolStake
+	oldCoeff := cfg.Node.ShelleyGenesis.ActiveSlotsCoeff
+	t.Cleanup(func() {
+		cfg.App.PoolStake = oldStake
+		cfg.Node.ShelleyGenesis.ActiveSlotsCoeff = oldCoeff
+		luckBase = nil
+	})
+	cfg.Node.ShelleyGenesis.ActiveSlotsCoeff = 0.05
+	luckBase = nil
+	// Without a stake there's no luck line
+	cfg.App.PoolStake = 0
+	text := getCoreText(context.Background())
+	if strings.Contains(text, "Luck") {
+		t.Errorf("got:\n%s\nexpected no luck line", text)
+	}
+	cfg.App.PoolStake = 1
+	// The first refresh sets the baseline, with no blocks expected yet
+	expected := " [green]Luck       : [white]-\n"
+	text = getCoreText(context.Background())
+	if !strings.Contains(text, expected) {
+		t.Errorf("expected %q in:\n%s", expected, text)
+	}
+	// Two adopted blocks over 20 slots, with 1 expected
+	promMetrics.SlotInEpoch += 20
+	promMetrics.Adopted += 2
+	expected = " [green]Luck       : [white]200% [blue]([white]2/1.0[blue])\n"
+	text = getCoreText(context.Background())
+	if !strings.Contains(text, expected) {
+		t.Errorf("expected %q in:\n%s", expected, text)
+	}
+}
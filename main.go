@@ -16,19 +16,23 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"log/slog"
 	"net"
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/mikioh/tcp"
 	"github.com/mikioh/tcpinfo"
 	"github.com/rivo/tview"
+	"github.com/shirou/gopsutil/v3/mem"
 	netutil "github.com/shirou/gopsutil/v3/net"
 	"github.com/shirou/gopsutil/v3/process"
 	terminal "golang.org/x/term"
@@ -40,6 +44,12 @@ import (
 // Global command line flags
 var cmdlineFlags struct {
 	configFile string
+	peersOnce  bool
+	check      bool
+	json       bool
+	replay     string
+	slotToTime string
+	timeToSlot string
 }
 
 // Global tview application and pages
@@ -75,6 +85,7 @@ var footerTextView = tview.NewTextView().
 	SetDynamicColors(true).
 	SetTextColor(tcell.ColorGreen)
 var headerTextView = tview.NewTextView().
+	SetDynamicColors(true).
 	SetTextColor(tcell.ColorGreen)
 var nodeTextView = tview.NewTextView().
 	SetDynamicColors(true).
@@ -94,15 +105,68 @@ var resourceTextView = tview.NewTextView().
 		app.Draw()
 	})
 
+// Default footer text
+var defaultFooterText = " [yellow](esc/q)[white] Quit | [yellow](p)[white] Peer Analysis | [yellow](f)[white] Peers | [yellow](h)[white] Help"
+
 // Text strings
 var blockText, chainText, coreText, connectionText, nodeText, peerText, resourceText string
 
 // Metrics variables
-var processMetrics *process.Process
+var processMetrics nodeProcess
+
+// Node process thread count, updated with the process metrics
+var processThreads int32
 
 // Track our failures
 var failCount uint32 = 0
 
+// Maximum time between Prometheus scrapes while the node is down
+const promBackoffMax = time.Second * 60
+
+// Track whether mouse support is enabled, which is off by default to
+// preserve copy and paste in the terminal
+var mouseEnabled bool
+
+// Enables or disables mouse support in the app, which can be replaced in
+// tests
+var enableAppMouse = func(enabled bool) {
+	app.EnableMouse(enabled)
+}
+
+// Sets whether mouse support is enabled
+func setMouseEnabled(enabled bool) {
+	mouseEnabled = enabled
+	enableAppMouse(enabled)
+}
+
+// Toggles mouse support and returns a notice describing the new mode
+func toggleMouse() string {
+	setMouseEnabled(!mouseEnabled)
+	if mouseEnabled {
+		return "Mouse enabled: scroll with the wheel"
+	}
+	return "Mouse disabled: select text to copy"
+}
+
+// Track our start time
+var appStartTime = time.Now()
+
+// Tracks our background goroutines so we can wait for them on shutdown
+var workers sync.WaitGroup
+
+// Runs a background goroutine which stops when the context is cancelled,
+// unless the context is already cancelled
+func runWorker(ctx context.Context, f func()) {
+	if ctx.Err() != nil {
+		return
+	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		f()
+	}()
+}
+
 func main() {
 	// Check if any command line flags are given
 	flag.StringVar(
@@ -111,111 +175,206 @@ func main() {
 		"",
 		"path to config file to load",
 	)
+	flag.BoolVar(
+		&cmdlineFlags.peersOnce,
+		"peers-once",
+		false,
+		"run a single peer analysis, print the results, and exit",
+	)
+	flag.BoolVar(
+		&cmdlineFlags.check,
+		"check",
+		false,
+		"check the node once, print its status, and exit non-zero unless synced",
+	)
+	flag.BoolVar(
+		&cmdlineFlags.json,
+		"json",
+		false,
+		"use JSON output for non-interactive modes",
+	)
+	flag.StringVar(
+		&cmdlineFlags.replay,
+		"replay",
+		"",
+		"replay captured metrics from a file or directory of snapshots",
+	)
+	flag.StringVar(
+		&cmdlineFlags.slotToTime,
+		"slot-to-time",
+		"",
+		"print the UTC time of a slot on the configured network and exit",
+	)
+	flag.StringVar(
+		&cmdlineFlags.timeToSlot,
+		"time-to-slot",
+		"",
+		"print the slot at an RFC 3339 time on the configured network and exit",
+	)
 	flag.Parse()
 
 	// Load config
 	cfg, err := config.LoadConfig(cmdlineFlags.configFile)
 	if err != nil {
-		fmt.Printf("Failed to load config: %s", err)
+		fmt.Printf("Failed to load config: %s\n", err)
 		os.Exit(1)
 	}
 
-	// Create a background context
-	ctx := context.Background()
+	// Setup logging
+	if err := setupLogging(cfg); err != nil {
+		fmt.Printf("Failed to setup logging: %s\n", err)
+		os.Exit(1)
+	}
+
+	// Convert between slots and times and exit
+	if cmdlineFlags.slotToTime != "" {
+		os.Exit(runSlotToTime(os.Stdout, cmdlineFlags.slotToTime))
+	}
+	if cmdlineFlags.timeToSlot != "" {
+		os.Exit(runTimeToSlot(os.Stdout, cmdlineFlags.timeToSlot))
+	}
 
-	// Exit if NODE_NAME is > 19 characters
-	if len([]rune(cfg.App.NodeName)) > 19 {
-		fmt.Println(
-			"Please keep node name at or below 19 characters in length!",
+	// Create a context which is cancelled on shutdown to stop our
+	// background goroutines
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Warn if NODE_NAME will be truncated
+	if len([]rune(cfg.App.NodeName)) > maxNodeNameLength {
+		slog.Warn(
+			"node name will be truncated for display",
+			"name", cfg.App.NodeName,
+			"maxLength", maxNodeNameLength,
 		)
+	}
+
+	// Select where we read metrics from
+	metricsSource, err = getMetricsSource()
+	if err != nil {
+		fmt.Printf("Failed to load metrics: %s\n", err)
 		os.Exit(1)
 	}
 
+	// Run a headless health check and exit
+	if cmdlineFlags.check {
+		os.Exit(runCheck(ctx, os.Stdout, cmdlineFlags.json))
+	}
+
+	peerStats.RTTresultsMap = make(map[string]*Peer)
+	peerStats.RTTresultsSlice = []*Peer{}
+	loadPeerCache()
+
+	// Open our metrics CSV file
+	if cfg.App.MetricsCsvPath != "" {
+		metricsCsv, err = newMetricsCsvWriter(
+			cfg.App.MetricsCsvPath,
+			cfg.App.MetricsCsvMaxSize,
+		)
+		if err != nil {
+			fmt.Printf("Failed to open metrics CSV: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load known stake pool relays
+	if cfg.App.PoolRelaysFile != "" {
+		err := loadPoolRelays(cfg.App.PoolRelaysFile, cfg.App.PoolRelaysFormat)
+		if err != nil {
+			fmt.Printf("Failed to load pool relays: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Run a headless peer analysis and exit
+	if cmdlineFlags.peersOnce {
+		if err := runPeersOnce(ctx, os.Stdout, cmdlineFlags.json); err != nil {
+			fmt.Printf("Failed to run peer analysis: %s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Determine if we're P2P
 	p2p = getP2P(ctx, processMetrics)
 	// Set role
 	setRole()
+	local := isLocalNode()
+	nodeEvents = newEventLog(cfg.App.EventsSize)
+	peerSortDescending.Store(strings.ToLower(cfg.App.PeerSort) == "desc")
+	loadForgeHistory()
 	// Get public IP
-	ip, err := getPublicIP(ctx)
-	if err == nil {
-		publicIP = &ip
+	if local {
+		runWorker(ctx, func() { updatePublicIP(ctx) })
+		checkPeers = true
 	}
-	checkPeers = true
 
 	// Fetch data from Prometheus
-	go func() {
-		for {
-			prom, err := getPromMetrics(ctx)
-			if err != nil && prom != nil {
-				failCount++
-				time.Sleep(time.Second * time.Duration(cfg.Prometheus.Refresh))
-				continue
-			}
-			promMetrics = prom
-			time.Sleep(time.Second * time.Duration(cfg.Prometheus.Refresh))
-		}
-	}()
+	runWorker(ctx, func() { updatePromMetrics(ctx) })
 
 	// Set Epoch
-	go func() {
-		for {
-			setCurrentEpoch()
-			if currentEpoch != 0 {
-				time.Sleep(time.Second * 20)
+	runWorker(ctx, func() {
+		// Retry with backoff until the node metrics are available, then
+		// refresh periodically
+		backoff := time.Second
+		for ctx.Err() == nil {
+			wait := time.Second * 20
+			if err := setCurrentEpoch(); err != nil {
+				slog.Debug("failed to set current epoch", "error", err)
+				wait = backoff
+				backoff = min(backoff*2, time.Second*20)
+			} else {
+				backoff = time.Second
+			}
+			if epochTracker.update(currentEpoch) {
+				onEpochStarted(currentEpoch)
+			}
+			if !sleepOrRefresh(ctx, wait, refreshRequests) {
+				return
 			}
 		}
-	}()
+	})
 
 	// Update Process metrics
-	go func() {
-		for {
-			proc, err := getProcessMetrics(ctx)
-			if err != nil {
-				failCount++
-				time.Sleep(time.Second * 1)
-				continue
-			}
-			processMetrics = proc
-			time.Sleep(time.Second * 1)
-		}
-	}()
+	runWorker(ctx, func() { pollNodeProcess(ctx) })
 
 	// Set uptimes
-	go func() {
+	runWorker(ctx, func() {
 		for {
 			uptime := getUptimes(ctx, processMetrics)
 			if uptime != 0 {
 				uptimes = uptime
 			}
-			time.Sleep(time.Second * 1)
+			if !sleepWithContext(ctx, time.Second*1) {
+				return
+			}
 		}
-	}()
+	})
 
 	// Filter peers
-	go func() {
-		for {
+	runWorker(ctx, func() {
+		for local {
 			err := filterPeers(ctx)
 			if err != nil {
 				failCount++
-				time.Sleep(time.Second * 1)
-				continue
 			}
-			time.Sleep(time.Second * 1)
+			if !sleepWithContext(ctx, time.Second*1) {
+				return
+			}
 		}
-	}()
+	})
 
 	// Ping peers
-	go func() {
-		for {
+	runWorker(ctx, func() {
+		for local {
 			err := pingPeers(ctx)
 			if err != nil {
 				failCount++
-				time.Sleep(time.Second * 10)
-				continue
 			}
-			time.Sleep(time.Second * 10)
+			if !sleepWithContext(ctx, time.Second*10) {
+				return
+			}
 		}
-	}()
+	})
 
 	// Populate initial text from metrics
 	nodeText = getNodeText(ctx)
@@ -244,138 +403,69 @@ func main() {
 	peerTextView.SetText(peerText).SetTitle("Peers").SetBorder(true)
 
 	// Set our footer
-	defaultFooterText := " [yellow](esc/q)[white] Quit | [yellow](p)[white] Peer Analysis"
-	footerTextView.SetText(defaultFooterText)
+	footerTextView.SetText(getFooterText())
 
 	// Add content to our flex box
-	layout := tview.NewFlex()
-	leftSide := tview.NewFlex()
-	middleSide := tview.NewFlex()
+	panelVisibility = newPanelVisibility(cfg.App.HiddenPanels)
+	buildLayout()
 	flex.SetDirection(tview.FlexRow).
 		// Row 1 is our application header
-		AddItem(headerTextView.SetText(fmt.Sprintln(" > nview -", version.GetVersionString())),
-			1,
+		AddItem(headerTextView.SetText(getHeaderText()),
+			headerLines,
 			1,
 			false).
-
 		// Row 2 is our main text section, and its own flex
-		AddItem(layout.
-			AddItem(leftSide.SetDirection(tview.FlexRow).
-				// Node
-				AddItem(nodeTextView,
-					8,
-					0,
-					false).
-				// Resources
-				AddItem(resourceTextView,
-					8,
-					0,
-					false).
-				// Connections
-				AddItem(connectionTextView,
-					11,
-					0,
-					false),
-				37,
-				1,
-				false).
-			AddItem(middleSide.SetDirection(tview.FlexRow).
-				// Chain
-				AddItem(chainTextView,
-					8,
-					1,
-					false).
-				// Block
-				AddItem(blockTextView,
-					4,
-					0,
-					false).
-				// Peers
-				AddItem(peerTextView,
-					0,
-					3,
-					true),
-				74,
-				2,
-				true),
-			0,
-			6,
-			true).
+		AddItem(layout, 0, 6, true).
 		// Row 3 is our footer
-		AddItem(footerTextView, 2, 0, false)
-
-	// Core
-	if role == "Core" {
-		leftSide.AddItem(coreTextView, 0, 1, false)
-	} else {
-		leftSide.AddItem(nil, 0, 1, false)
-	}
-	// TODO: another section + data
-	// layout.AddItem(tview.NewBox().SetBorder(true).SetTitle("Coming Soon"), 22, 1, false)
-	peerStats.RTTresultsMap = make(map[string]*Peer)
-	peerStats.RTTresultsSlice = []*Peer{}
+		AddItem(footerTextView, footerLines, 0, false)
 
 	// capture inputs
 	flex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Rune() == 104 || event.Rune() == 114 { // h or r
-			setRole()
-			resetPeers()
-			checkPeers = true
-			footerTextView.Clear()
-			footerTextView.SetText(defaultFooterText)
-			var tmpText string
-			tmpText = getNodeText(ctx)
-			if tmpText != "" && tmpText != nodeText {
-				nodeText = tmpText
-				nodeTextView.Clear()
-				nodeTextView.SetText(nodeText)
-			}
-			tmpText = getResourceText(ctx)
-			if tmpText != "" && tmpText != resourceText {
-				resourceText = tmpText
-				resourceTextView.Clear()
-				resourceTextView.SetText(resourceText)
-			}
-			tmpText = getConnectionText(ctx)
-			if tmpText != "" && tmpText != connectionText {
-				connectionText = tmpText
-				connectionTextView.Clear()
-				connectionTextView.SetText(connectionText)
-			}
-			tmpText = getCoreText(ctx)
-			if tmpText != "" && tmpText != coreText {
-				coreText = tmpText
-				coreTextView.Clear()
-				coreTextView.SetText(coreText)
-			}
-			tmpText = fmt.Sprintf(
-				"%s\n%s",
-				getEpochText(ctx),
-				getChainText(ctx),
-			)
-			if tmpText != "" && tmpText != chainText {
-				chainText = tmpText
-				chainTextView.Clear()
-				chainTextView.SetText(chainText)
-			}
-			tmpText = getBlockText(ctx)
-			if tmpText != "" && tmpText != blockText {
-				blockText = tmpText
-				blockTextView.Clear()
-				blockTextView.SetText(blockText)
-			}
-			// Peers are last since they take time to process
-			tmpText = getPeerText(ctx)
-			if tmpText != "" && tmpText != peerText {
-				peerText = tmpText
-				peerTextView.Clear()
-				peerTextView.SetText(peerText)
-				// Scroll to the top only once
-				if scrollPeers {
-					scrollPeers = false
-					peerTextView.ScrollToBeginning()
-				}
-			}
+		if event.Rune() == 114 { // r
+			// Wake the background workers rather than refreshing here
+			refreshRequests.trigger()
+			return nil
+		}
+		if event.Rune() == 104 || event.Rune() == 63 { // h or ?
+			showHelpPage()
+			return nil
+		}
+		if event.Rune() >= '1' && event.Rune() <= '7' { // panel toggles
+			togglePanel(int(event.Rune() - '1'))
+			buildLayout()
+			return nil
+		}
+		if event.Rune() == 99 { // c
+			setFooterNotice(copySnapshot(ctx))
+			footerTextView.SetText(getFooterText())
+			return nil
+		}
+		if event.Rune() == 109 { // m
+			setFooterNotice(toggleMouse())
+			footerTextView.SetText(getFooterText())
+			return nil
+		}
+		if event.Rune() == 115 { // s
+			setFooterNotice(togglePeerSort())
+			footerTextView.SetText(getFooterText())
+			return nil
+		}
+		if event.Rune() == 122 { // z
+			setFooterNotice(togglePeersOnlyFocus())
+			footerTextView.SetText(getFooterText())
+			return nil
+		}
+		if event.Rune() == 102 { // f
+			showPeersPage()
+			return nil
+		}
+		if event.Rune() == 101 { // e
+			showEventsPage()
+			return nil
+		}
+		if event.Rune() == 120 { // x
+			showRawMetricsPage()
+			return nil
 		}
 		if event.Rune() == 112 { // p
 			resetPeers()
@@ -383,6 +473,7 @@ func main() {
 			scrollPeers = false
 		}
 		if event.Rune() == 113 || event.Key() == tcell.KeyEscape { // q
+			cancel()
 			app.Stop()
 		}
 		return event
@@ -390,9 +481,16 @@ func main() {
 
 	// Pages
 	pages.AddPage("Main", flex, true, true)
+	setupPeersPage()
+	setupEventsPage()
+	setupRawMetricsPage()
+	setupHelpPage()
+	if getStartPage(cfg.App.StartPage) == "Peers" {
+		showPeersPage()
+	}
 
 	// Start our background refresh timer
-	go func() {
+	runWorker(ctx, func() {
 		for {
 			if failCount >= cfg.App.Retries {
 				panic(
@@ -403,87 +501,140 @@ func main() {
 				)
 			}
 
-			// Refresh all the things
 			setRole()
-			var tmpText string
-			tmpText = getNodeText(ctx)
-			if tmpText != "" && tmpText != nodeText {
-				nodeText = tmpText
-				nodeTextView.Clear()
-				nodeTextView.SetText(nodeText)
-			}
-			tmpText = getResourceText(ctx)
-			if tmpText != "" && tmpText != resourceText {
-				resourceText = tmpText
-				resourceTextView.Clear()
-				resourceTextView.SetText(resourceText)
-			}
-			tmpText = getConnectionText(ctx)
-			if tmpText != "" && tmpText != connectionText {
-				connectionText = tmpText
-				connectionTextView.Clear()
-				connectionTextView.SetText(connectionText)
-			}
-			tmpText = getCoreText(ctx)
-			if tmpText != "" && tmpText != coreText {
-				coreText = tmpText
-				coreTextView.Clear()
-				coreTextView.SetText(coreText)
-			}
-			tmpText = fmt.Sprintf(
-				"%s\n%s",
-				getEpochText(ctx),
-				getChainText(ctx),
-			)
-			if tmpText != "" && tmpText != chainText {
-				chainText = tmpText
-				chainTextView.Clear()
-				chainTextView.SetText(chainText)
-			}
-			tmpText = getBlockText(ctx)
-			if tmpText != "" && tmpText != blockText {
-				blockText = tmpText
-				blockTextView.Clear()
-				blockTextView.SetText(blockText)
-			}
-			tmpText = getPeerText(ctx)
-			if tmpText != "" && tmpText != peerText {
-				peerText = tmpText
-				peerTextView.Clear()
-				peerTextView.SetText(peerText)
-				// Scroll to the top only once
-				if scrollPeers {
-					scrollPeers = false
-					peerTextView.ScrollToBeginning()
+			checkEvents()
+			checkCriticalAlerts()
+			recordForges()
+			nodeForgeHealth.update(promMetrics, timeNow())
+			refreshPanels(ctx)
+			if metricsCsv != nil {
+				err := metricsCsv.Write(getMetricsCsvRow(ctx, time.Now()))
+				if err != nil {
+					slog.Warn("failed to write metrics CSV", "error", err)
 				}
 			}
-			time.Sleep(time.Second * time.Duration(cfg.App.Refresh))
+			if !sleepOrRefresh(
+				ctx,
+				time.Second*time.Duration(cfg.App.Refresh),
+				refreshRequests,
+			) {
+				return
+			}
 		}
-	}()
+	})
+
+	// Track our terminal size on each draw, which includes resizes
+	app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		termCols, termLines = screen.Size()
+		if alertBellPending.Swap(false) {
+			_ = screen.Beep()
+		}
+		return false
+	})
 
-	if err := app.SetRoot(pages, true).EnableMouse(false).Run(); err != nil {
+	setMouseEnabled(cfg.App.EnableMouse)
+	if err := app.SetRoot(pages, true).Run(); err != nil {
 		panic(err)
 	}
+
+	// Stop our background goroutines and close resources
+	cancel()
+	workers.Wait()
+	savePeerCache()
+	closeGeoIP()
+	if metricsCsv != nil {
+		if err := metricsCsv.Close(); err != nil {
+			slog.Warn("failed to close metrics CSV", "error", err)
+		}
+	}
+}
+
+func getHeaderText() string {
+	cfg := config.GetConfig()
+	// Replace the header with a banner while the node is unreachable
+	failures := promFailures.Load()
+	if isNodeDown(failures, cfg.App.Retries) {
+		return fmt.Sprintf(
+			"[white:red] NODE UNREACHABLE - retrying (%d/%d) [-:-]\n",
+			failures,
+			cfg.App.Retries,
+		)
+	}
+	width, _, err := getTerminalSize()
+	if err != nil {
+		width = 0
+	}
+	return formatHeaderText(
+		cfg.App.Title,
+		!cfg.App.HideTitleVersion,
+		timeFromSeconds(uint64(time.Since(appStartTime).Seconds())),
+		width,
+	)
+}
+
+// Returns the header for a title, which defaults to nview, truncating the
+// title to fit the terminal width when it's known
+func formatHeaderText(
+	title string,
+	showVersion bool,
+	uptime string,
+	width int,
+) string {
+	if title == "" {
+		title = "nview"
+	}
+	if showVersion {
+		title += " - " + version.GetVersionString()
+	}
+	suffix := " | Uptime: " + uptime
+	if width > 0 {
+		title = truncateString(title, max(width-len(" > ")-len(suffix), 1))
+	}
+	return fmt.Sprintf(" > %s%s\n", tview.Escape(title), suffix)
 }
 
 var uptimes uint64
 
-func getUptimes(ctx context.Context, processMetrics *process.Process) uint64 {
+func getUptimes(ctx context.Context, processMetrics nodeProcess) uint64 {
 	if processMetrics == nil {
 		return uptimes
 	}
 	// Calculate uptime
-	createTime, err := processMetrics.CreateTimeWithContext(ctx)
+	createTime, err := processMetrics.CreateTime(ctx)
 	if err != nil {
 		return uptimes
 	}
 	// createTime is milliseconds since UNIX epoch, convert to seconds
-	uptimes = uint64(time.Now().Unix() - (createTime / 1000))
+	uptimes = uint64(timeNow().Unix() - (createTime / 1000))
 	return uptimes
 }
 
-// Track size of epoch items
-var epochItemsLast = 0
+// Inputs to the cached epoch bar, which is rebuilt when any of them change
+type epochBarKey struct {
+	epoch       uint32
+	items       int
+	granularity int
+	charMarked  string
+}
+
+// Track the cached epoch bar and its inputs
+var epochBarLast epochBarKey
+var epochBar string
+
+// Returns the number of progress bar items for the configured granularity
+func getGranularity() int {
+	cfg := config.GetConfig()
+	if cfg.App.Granularity < 1 {
+		return 68
+	}
+	return cfg.App.Granularity
+}
+
+// Returns the number of marked items in a progress bar for a percentage
+func progressItems(percent float32, granularity int) int {
+	items := int(percent) * granularity / 100
+	return max(0, min(items, granularity))
+}
 
 func getEpochProgress() float32 {
 	cfg := config.GetConfig()
@@ -520,8 +671,7 @@ func getEpochText(ctx context.Context) string {
 	)
 
 	// Epoch progress bar
-	var epochBar string
-	var granularity int = 68
+	granularity := getGranularity()
 	var charMarked string
 	var charUnmarked string
 	// TODO: legacy mode vs new
@@ -533,10 +683,16 @@ func getEpochText(ctx context.Context) string {
 		charUnmarked = string('▖')
 	}
 
-	epochItems := int(epochProgress) * granularity / 100
-	if epochBar == "" || epochItems != epochItemsLast {
+	epochItems := progressItems(epochProgress, granularity)
+	barKey := epochBarKey{
+		epoch:       currentEpoch,
+		items:       epochItems,
+		granularity: granularity,
+		charMarked:  charMarked,
+	}
+	if epochBar == "" || barKey != epochBarLast {
 		epochBar = ""
-		epochItemsLast = epochItems
+		epochBarLast = barKey
 		for i := 0; i <= granularity-1; i++ {
 			if i < epochItems {
 				epochBar += fmt.Sprintf("[blue]%s", charMarked)
@@ -549,21 +705,35 @@ func getEpochText(ctx context.Context) string {
 	return fmt.Sprint(sb.String())
 }
 
+// Returns whether the node has populated its chain metrics. These are all
+// missing, and so zero, while the node starts up, but only all zero at
+// genesis otherwise.
+func isMetricsPopulated(metrics *PromMetrics) bool {
+	return metrics != nil &&
+		(metrics.SlotNum != 0 || metrics.EpochNum != 0 || metrics.BlockNum != 0)
+}
+
+// Placeholder for panels while the node is initializing
+const initializingText = " [yellow]initializing...\n"
+
 func getChainText(ctx context.Context) string {
 	if promMetrics == nil {
 		return chainText
 	}
+	if !isMetricsPopulated(promMetrics) {
+		return initializingText
+	}
 	var sb strings.Builder
 
 	// Blocks / Slots / Tx
 
-	mempoolTxKBytes := promMetrics.MempoolBytes / 1024
+	mempoolSize, mempoolUnit := getByteSize(promMetrics.MempoolBytes, false)
 	kWidth := strconv.Itoa(10 -
 		len(strconv.FormatUint(promMetrics.MempoolTx, 10)) -
-		len(strconv.FormatUint(mempoolTxKBytes, 10)))
+		len(mempoolSize))
 
 	tipRef := getSlotTipRef()
-	tipDiff := (tipRef - promMetrics.SlotNum)
+	tipDiff := getTipDiff(promMetrics, tipRef)
 
 	// Row 1
 	sb.WriteString(fmt.Sprintf(
@@ -574,10 +744,23 @@ func getChainText(ctx context.Context) string {
 		" Tip (ref)  : [white]%-"+strconv.Itoa(10)+"s[green]",
 		strconv.FormatUint(tipRef, 10),
 	))
-	sb.WriteString(fmt.Sprintf(
-		" Forks      : [white]%-"+strconv.Itoa(10)+"s[green]\n",
-		strconv.FormatUint(promMetrics.Forks, 10),
-	))
+	forksDelta := forksTrend.update(promMetrics.Forks)
+	if forksDelta > 0 {
+		forks := fmt.Sprintf(
+			"%d (+%d)",
+			promMetrics.Forks,
+			forksDelta,
+		)
+		sb.WriteString(fmt.Sprintf(
+			" Forks      : [yellow]%-"+strconv.Itoa(10)+"s[green]\n",
+			forks,
+		))
+	} else {
+		sb.WriteString(fmt.Sprintf(
+			" Forks      : [white]%-"+strconv.Itoa(10)+"s[green]\n",
+			strconv.FormatUint(promMetrics.Forks, 10),
+		))
+	}
 	// Row 2
 	sb.WriteString(fmt.Sprintf(
 		" Slot       : [white]%-"+strconv.Itoa(10)+"s[green]",
@@ -590,15 +773,12 @@ func getChainText(ctx context.Context) string {
 			)+"s[green]",
 			"starting",
 		))
-	} else if tipDiff <= 20 {
-		sb.WriteString(fmt.Sprintf(
-			" Tip (diff) : [white]%-"+strconv.Itoa(9)+"s[green]",
-			fmt.Sprintf("%s 😀", strconv.FormatUint(tipDiff, 10)),
-		))
-	} else if tipDiff <= 600 {
+	} else if bucket := getTipDiffBucket(tipDiff); bucket != tipDiffSyncing {
+		status, width := getTipDiffStatus(bucket.Emoji(), bucket.String())
 		sb.WriteString(fmt.Sprintf(
-			" Tip (diff) : [yellow]%-"+strconv.Itoa(9)+"s[green]",
-			fmt.Sprintf("%s 😐", strconv.FormatUint(tipDiff, 10)),
+			" Tip (diff) : [%s]%-"+strconv.Itoa(width)+"s[green]",
+			bucket.Color(),
+			fmt.Sprintf("%s %s", strconv.FormatUint(tipDiff, 10), status),
 		))
 	} else {
 		syncProgress := float32((float32(promMetrics.SlotNum) / float32(tipRef)) * 100)
@@ -616,30 +796,272 @@ func getChainText(ctx context.Context) string {
 		" Slot epoch : [white]%-"+strconv.Itoa(10)+"s[green]",
 		strconv.FormatUint(promMetrics.SlotInEpoch, 10),
 	))
+	sb.WriteString(getDensityText(promMetrics.Density))
 	sb.WriteString(fmt.Sprintf(
-		" Density    : [white]%-"+strconv.Itoa(10)+"s[green]",
-		fmt.Sprintf("%3.5f", promMetrics.Density*100/1),
-	))
-	sb.WriteString(fmt.Sprintf(
-		" Pending Tx : [white]%d[blue]/[white]%d[blue]%-"+kWidth+"s\n",
+		" Pending Tx : [white]%d[blue]/[white]%s[blue]%-"+kWidth+"s\n",
 		promMetrics.MempoolTx,
-		mempoolTxKBytes,
-		"K",
+		mempoolSize,
+		mempoolUnit,
 	))
+	// Row 4
+	if promMetrics.SlotNum != 0 {
+		tipTime := getSlotTime(promMetrics.SlotNum)
+		tipSkew := int64(tipTime.Sub(timeNow()).Round(time.Second).Seconds())
+		sb.WriteString(fmt.Sprintf(
+			" Tip time   : [white]%s [blue]([white]%+ds[blue])[green]\n",
+			formatTime(tipTime, time.TimeOnly),
+			tipSkew,
+		))
+	}
 	return fmt.Sprint(sb.String())
 }
 
+// Tracks increases in a counter metric from a baseline
+type counterTrend struct {
+	baseline uint64
+	last     uint64
+	seen     bool
+}
+
+// Updates the trend with the latest counter value and returns the increase
+// since the baseline. The baseline is reset when the counter goes backwards,
+// which happens when the node restarts.
+func (t *counterTrend) update(value uint64) uint64 {
+	if !t.seen || value < t.last {
+		t.baseline = value
+		t.seen = true
+	}
+	t.last = value
+	return value - t.baseline
+}
+
+// Track forks since we started watching the node
+var forksTrend counterTrend
+
+// Returns chain density as a percentage of the ideal density, which is the
+// active slots coefficient, or 0 when the coefficient is unknown
+func getDensityRatio(density float64, activeSlotsCoeff float64) float64 {
+	if activeSlotsCoeff <= 0 {
+		return 0
+	}
+	return density / activeSlotsCoeff * 100
+}
+
+// Returns the color for a chain density percentage of ideal
+func getDensityColor(ratio float64) string {
+	if ratio < 80 {
+		return "red"
+	}
+	if ratio < 90 {
+		return "yellow"
+	}
+	return "white"
+}
+
+// Returns the density column for the Chain panel, showing density and its
+// percentage of ideal
+func getDensityText(density float64) string {
+	cfg := config.GetConfig()
+	densityPct := fmt.Sprintf("%3.3f", density*100)
+	ratio := getDensityRatio(
+		density,
+		cfg.Node.ShelleyGenesis.ActiveSlotsCoeff,
+	)
+	if ratio == 0 {
+		return fmt.Sprintf(
+			" Density    : [white]%-"+strconv.Itoa(10)+"s[green]",
+			densityPct,
+		)
+	}
+	ratioPct := fmt.Sprintf("%.0f%%", ratio)
+	// Pad the plain text, since the color tags don't take up columns
+	pad := max(10-len(densityPct)-len(ratioPct)-1, 0)
+	return fmt.Sprintf(
+		" Density    : [white]%s [%s]%s[green]%s",
+		densityPct,
+		getDensityColor(ratio),
+		ratioPct,
+		strings.Repeat(" ", pad),
+	)
+}
+
+// How far behind the reference tip the node is
+type tipDiffBucket int
+
+const (
+	tipDiffOK tipDiffBucket = iota
+	tipDiffSlow
+	tipDiffSyncing
+)
+
+func (b tipDiffBucket) String() string {
+	switch b {
+	case tipDiffOK:
+		return "OK"
+	case tipDiffSlow:
+		return "SLOW"
+	default:
+		return "SYNCING"
+	}
+}
+
+func (b tipDiffBucket) Color() string {
+	if b == tipDiffOK {
+		return "white"
+	}
+	return "yellow"
+}
+
+func (b tipDiffBucket) Emoji() string {
+	if b == tipDiffOK {
+		return "😀"
+	}
+	return "😐"
+}
+
+// Returns the bucket for a tip diff using the configured thresholds, which
+// are inclusive
+func getTipDiffBucket(tipDiff uint64) tipDiffBucket {
+	cfg := config.GetConfig()
+	if tipDiff <= cfg.App.TipDiffOK {
+		return tipDiffOK
+	}
+	if tipDiff <= cfg.App.TipDiffSlow {
+		return tipDiffSlow
+	}
+	return tipDiffSyncing
+}
+
+// Returns the tip diff between a tip reference and the node's slot
+func getTipDiff(metrics *PromMetrics, tipRef uint64) uint64 {
+	if metrics == nil || tipRef < metrics.SlotNum {
+		return 0
+	}
+	return tipRef - metrics.SlotNum
+}
+
+// Returns whether the node is synced, which is when it has started and its
+// tip diff is within SYNCED_THRESHOLD
+func isSynced(metrics *PromMetrics, tipRef uint64) bool {
+	if metrics == nil || metrics.SlotNum == 0 {
+		return false
+	}
+	cfg := config.GetConfig()
+	return getTipDiff(metrics, tipRef) <= cfg.Node.SyncedThreshold
+}
+
+// Returns the tip diff status marker and the column width to pad to, which
+// is one less for emoji since they display two columns wide
+func getTipDiffStatus(emoji string, text string) (string, int) {
+	cfg := config.GetConfig()
+	if cfg.App.NoEmoji {
+		return text, 10
+	}
+	return emoji, 9
+}
+
+// Refreshes the header, footer, live panels, and any full-screen page from
+// the latest metrics
+func refreshPanels(ctx context.Context) {
+	headerTextView.SetText(getHeaderText())
+	if !termTooSmall {
+		footerTextView.SetText(getFooterText())
+	}
+	var tmpText string
+	if isPanelLive("node") {
+		tmpText = getNodeText(ctx)
+		if tmpText != "" && tmpText != nodeText {
+			nodeText = tmpText
+			nodeTextView.Clear()
+			nodeTextView.SetText(nodeText)
+		}
+	}
+	if isPanelLive("resources") {
+		tmpText = getResourceText(ctx)
+		if tmpText != "" && tmpText != resourceText {
+			resourceText = tmpText
+			resourceTextView.Clear()
+			resourceTextView.SetText(resourceText)
+		}
+	}
+	if isPanelLive("connections") {
+		tmpText = getConnectionText(ctx)
+		if tmpText != "" && tmpText != connectionText {
+			connectionText = tmpText
+			connectionTextView.Clear()
+			connectionTextView.SetText(connectionText)
+		}
+	}
+	if isPanelLive("core") {
+		tmpText = getCoreText(ctx)
+		if tmpText != "" && tmpText != coreText {
+			coreText = tmpText
+			coreTextView.Clear()
+			coreTextView.SetText(coreText)
+		}
+	}
+	if isPanelLive("chain") {
+		tmpText = fmt.Sprintf(
+			"%s\n%s",
+			getEpochText(ctx),
+			getChainText(ctx),
+		)
+		if tmpText != "" && tmpText != chainText {
+			chainText = tmpText
+			chainTextView.Clear()
+			chainTextView.SetText(chainText)
+		}
+	}
+	if isPanelLive("block") {
+		tmpText = getBlockText(ctx)
+		if tmpText != "" && tmpText != blockText {
+			blockText = tmpText
+			blockTextView.Clear()
+			blockTextView.SetText(blockText)
+		}
+	}
+	tmpText = getPeerText(ctx)
+	if tmpText != "" && tmpText != peerText {
+		peerText = tmpText
+		peerTextView.Clear()
+		peerTextView.SetText(peerText)
+		// Scroll to the top only once
+		if scrollPeers {
+			scrollPeers = false
+			peerTextView.ScrollToBeginning()
+		}
+	}
+	updatePeersPage()
+	updateEventsPage()
+	updateRawMetricsPage()
+}
+
 func getConnectionText(ctx context.Context) string {
 	cfg := config.GetConfig()
 	var sb strings.Builder
 
 	if p2p {
-		if promMetrics == nil {
-			return connectionText
-		}
 		sb.WriteString(fmt.Sprintf(" [green]P2P        : %s\n",
 			"enabled",
 		))
+		// Show placeholders until metrics are loaded
+		if promMetrics == nil {
+			for _, label := range []string{
+				"Incoming   ",
+				"Outgoing   ",
+				"Cold Peers ",
+				"Warm Peers ",
+				"Hot Peers  ",
+				"Uni-Dir    ",
+				"Bi-Dir     ",
+				"Duplex     ",
+			} {
+				sb.WriteString(
+					fmt.Sprintf(" [green]%s: [yellow]%s\n", label, "--"),
+				)
+			}
+			return sb.String()
+		}
 		sb.WriteString(fmt.Sprintf(" [green]Incoming   : [white]%s\n",
 			strconv.FormatUint(promMetrics.ConnIncoming, 10),
 		))
@@ -664,12 +1086,30 @@ func getConnectionText(ctx context.Context) string {
 		sb.WriteString(fmt.Sprintf(" [green]Duplex     : [white]%s\n",
 			strconv.FormatUint(promMetrics.ConnDuplex, 10),
 		))
+		sb.WriteString(getLocalClientText(ctx))
 	} else {
+		// Connections come from the node process, which we don't look for
+		// in remote mode
+		if cfg.App.RemoteMode {
+			sb.WriteString(fmt.Sprintf(" [green]P2P        : [yellow]%s\n",
+				"disabled",
+			))
+			sb.WriteString(fmt.Sprintf(" [green]Incoming   : [white]%s\n", "N/A"))
+			sb.WriteString(fmt.Sprintf(" [green]Outgoing   : [white]%s\n", "N/A"))
+			return sb.String()
+		}
+		// Show placeholders until the node process is found
 		if processMetrics == nil {
-			return connectionText
+			sb.WriteString(fmt.Sprintf(" [green]P2P        : [yellow]%s\n",
+				"disabled",
+			))
+			sb.WriteString(fmt.Sprintf(" [green]Incoming   : [yellow]%s\n", "--"))
+			sb.WriteString(fmt.Sprintf(" [green]Outgoing   : [yellow]%s\n", "--"))
+			sb.WriteString(" [yellow]finding node process...\n")
+			return sb.String()
 		}
 		// Get process in/out connections
-		connections, err := netutil.ConnectionsPidWithContext(ctx, "tcp", processMetrics.Pid)
+		connections, err := processMetrics.Connections(ctx, "tcp")
 		if err != nil {
 			sb.WriteString(fmt.Sprintf("Failed to get processes: %v", err))
 		}
@@ -684,8 +1124,8 @@ func getConnectionText(ctx context.Context) string {
 				if c.Laddr.Port == cfg.Node.Port {
 					peersIn = append(peersIn, fmt.Sprintf("%s:%d", c.Raddr.IP, c.Raddr.Port))
 				}
-				// If local port != node port, ekg port, or prometheus port, it's outgoing
-				if c.Laddr.Port != cfg.Node.Port && c.Laddr.Port != uint32(12788) && c.Laddr.Port != cfg.Prometheus.Port {
+				// If local port isn't one of the node's listeners, it's outgoing
+				if !isNodeListenerPort(c.Laddr.Port) {
 					peersOut = append(peersOut, fmt.Sprintf("%s:%d", c.Raddr.IP, c.Raddr.Port))
 				}
 			}
@@ -700,10 +1140,54 @@ func getConnectionText(ctx context.Context) string {
 		sb.WriteString(fmt.Sprintf(" [green]Outgoing   : [white]%s\n",
 			strconv.Itoa(len(peersOut)),
 		))
+		// Connections stuck in these states point to connection leaks or
+		// churn
+		states := getConnectionStateCounts(connections)
+		for _, state := range []struct {
+			label  string
+			status string
+		}{
+			{"Syn Sent   ", "SYN_SENT"},
+			{"Time Wait  ", "TIME_WAIT"},
+			{"Close Wait ", "CLOSE_WAIT"},
+		} {
+			color := "white"
+			if states[state.status] > 0 {
+				color = "yellow"
+			}
+			sb.WriteString(fmt.Sprintf(" [green]%s: [%s]%d\n",
+				state.label,
+				color,
+				states[state.status],
+			))
+		}
+		sb.WriteString(getLocalClientText(ctx))
 	}
 	return fmt.Sprint(sb.String())
 }
 
+// Returns the count of local node-to-client connections
+func getLocalClientText(ctx context.Context) string {
+	cfg := config.GetConfig()
+	if processMetrics == nil || processMetrics.Pid() == 0 {
+		return fmt.Sprintf(" [green]NtC Clients: [yellow]%s\n", "--")
+	}
+	unixConns, err := processMetrics.Connections(ctx, "unix")
+	if err != nil {
+		return fmt.Sprintf(" [green]NtC Clients: [yellow]%s\n", "--")
+	}
+	var tcpConns []netutil.ConnectionStat
+	if cfg.Node.N2CPort != 0 {
+		tcpConns, err = processMetrics.Connections(ctx, "tcp")
+		if err != nil {
+			return fmt.Sprintf(" [green]NtC Clients: [yellow]%s\n", "--")
+		}
+	}
+	return fmt.Sprintf(" [green]NtC Clients: [white]%d\n",
+		countLocalClients(unixConns, tcpConns, cfg.Node.SocketPath, cfg.Node.N2CPort),
+	)
+}
+
 func getCoreText(ctx context.Context) string {
 	if promMetrics == nil {
 		return coreText
@@ -712,7 +1196,9 @@ func getCoreText(ctx context.Context) string {
 	var sb strings.Builder
 
 	// Core section
-	if role == "Core" {
+	if role == "Core" && !isMetricsPopulated(promMetrics) {
+		sb.WriteString(initializingText)
+	} else if role == "Core" {
 		// TODO: block log functionality
 		var adoptedFmt string = "white"
 		var invalidFmt string = "white"
@@ -745,6 +1231,38 @@ func getCoreText(ctx context.Context) string {
 			strconv.FormatUint(promMetrics.MissedSlots, 10),
 			fmt.Sprintf("%.2f", missedSlotsPct),
 		))
+		sb.WriteString(getForgeText(promMetrics))
+		if cfg := config.GetConfig(); cfg.App.PoolStake > 0 {
+			base := getLuckBaseline(promMetrics)
+			adopted := promMetrics.Adopted - base.adopted
+			expected := getExpectedBlocks(
+				promMetrics.SlotInEpoch-base.slotInEpoch,
+				cfg.Node.ShelleyGenesis.ActiveSlotsCoeff,
+				cfg.App.PoolStake,
+			)
+			sb.WriteString(" [green]Luck       : ")
+			if luck, ok := getLuck(adopted, expected); ok {
+				sb.WriteString(fmt.Sprintf(
+					"[white]%.0f%% [blue]([white]%d/%.1f[blue])\n",
+					luck,
+					adopted,
+					expected,
+				))
+			} else {
+				sb.WriteString("[white]-\n")
+			}
+		}
+		if schedule := getLeaderSchedule(); schedule != nil {
+			sb.WriteString(" [green]Next slot  : ")
+			nextSlot, ok := getNextLeaderSlot(schedule, promMetrics.SlotNum)
+			if ok {
+				sb.WriteString(fmt.Sprintf("[white]in ~%s\n",
+					getSlotCountdown(nextSlot, timeNow()),
+				))
+			} else {
+				sb.WriteString("[yellow]none scheduled\n")
+			}
+		}
 
 		sb.WriteString("\n")
 
@@ -769,33 +1287,37 @@ func getBlockText(ctx context.Context) string {
 		return blockText
 	}
 
-	// Style / UI
-	var width = 71
-
 	// Get our terminal size
-	tcols, tlines, err := terminal.GetSize(int(os.Stdout.Fd()))
+	tcols, tlines, err := getTerminalSize()
 	if err != nil {
 		failCount++
 		return fmt.Sprintf("ERROR: %v", err)
 	}
 	// Validate size
-	if width >= tcols {
+	if !isTerminalAdequate(tcols, tlines) {
+		termTooSmall = true
 		footerTextView.Clear()
 		footerTextView.SetText(" [yellow](esc/q) Quit\n")
+		if termMinWidth >= tcols {
+			return fmt.Sprintf(
+				"\n [red]Terminal width too small![white]\n Please increase by [yellow]%d[white] columns\n",
+				termMinWidth-tcols+1,
+			)
+		}
 		return fmt.Sprintf(
-			"\n [red]Terminal width too small![white]\n Please increase by [yellow]%d[white] columns\n",
-			width-tcols+1,
+			"\n [red]Terminal height too small![white]\n Please increase by [yellow]%d[white] lines\n",
+			int(termMinLines.Load())-tlines,
 		)
 	}
-	// TODO: populate lines
-	line := 10
-	if line >= (tlines - 1) {
+	// Restore our footer once the terminal is large enough again
+	if termTooSmall {
+		termTooSmall = false
 		footerTextView.Clear()
-		footerTextView.SetText(" [yellow](esc/q) Quit\n")
-		return fmt.Sprintf(
-			"\n [red]Terminal height too small![white]\n Please increase by [yellow]%d[white] lines\n",
-			line-tlines+2,
-		)
+		footerTextView.SetText(getFooterText())
+	}
+
+	if !isMetricsPopulated(promMetrics) {
+		return initializingText
 	}
 
 	var sb strings.Builder
@@ -849,11 +1371,67 @@ func getBlockText(ctx context.Context) string {
 			"%",
 		),
 	)
+	// Row 3, when the node exposes a block delay histogram
+	if promMetrics.BlockDelayP50 != 0 || promMetrics.BlockDelayP95 != 0 {
+		p50 := fmt.Sprintf("%.2f", promMetrics.BlockDelayP50)
+		p95 := fmt.Sprintf("%.2f", promMetrics.BlockDelayP95)
+		sb.WriteString(
+			fmt.Sprintf(
+				" [green]Delay p50  : [white]%s[blue]%-"+strconv.Itoa(
+					10-len(p50),
+				)+"s",
+				p50,
+				"s",
+			),
+		)
+		sb.WriteString(
+			fmt.Sprintf(
+				" [green]Delay p95  : [white]%s[blue]%-"+strconv.Itoa(
+					10-len(p95),
+				)+"s\n",
+				p95,
+				"s",
+			),
+		)
+	}
 
 	failCount = 0
 	return fmt.Sprint(sb.String())
 }
 
+// Minimum terminal width for our layout
+const termMinWidth = 71
+
+// Lines used by our application header and footer
+const (
+	headerLines = 1
+	footerLines = 2
+)
+
+// Minimum terminal lines for the current layout, set when it's built
+var termMinLines atomic.Int32
+
+// Track our terminal size and whether it's too small
+var termCols, termLines int
+var termTooSmall bool = false
+
+// Returns the terminal size from the last draw, or from stdout before the
+// first draw
+func getTerminalSize() (int, int, error) {
+	if termCols > 0 && termLines > 0 {
+		return termCols, termLines, nil
+	}
+	return terminal.GetSize(int(os.Stdout.Fd()))
+}
+
+// Checks the terminal is large enough for our layout
+func isTerminalAdequate(cols, lines int) bool {
+	return termMinWidth < cols && int(termMinLines.Load()) <= lines
+}
+
+// Maximum node name length which fits the Node panel
+const maxNodeNameLength = 19
+
 func getNodeText(ctx context.Context) string {
 	cfg := config.GetConfig()
 	var network string
@@ -865,7 +1443,10 @@ func getNodeText(ctx context.Context) string {
 	nodeVersion, nodeRevision, _ := getNodeVersion()
 	var sb strings.Builder
 	sb.WriteString(
-		fmt.Sprintf(" [green]Name       : [white]%s\n", cfg.App.NodeName),
+		fmt.Sprintf(
+			" [green]Name       : [white]%s\n",
+			truncateString(getEffectiveNodeName(), maxNodeNameLength),
+		),
 	)
 	sb.WriteString(fmt.Sprintf(" [green]Role       : [white]%s\n", role))
 	sb.WriteString(fmt.Sprintf(" [green]Network    : [white]%s\n", network))
@@ -884,16 +1465,28 @@ func getNodeText(ctx context.Context) string {
 	} else {
 		sb.WriteString(fmt.Sprintln())
 	}
-	sb.WriteString(fmt.Sprintf(" [green]Uptime     : [white]%s\n",
-		timeFromSeconds(uptimes),
-	))
+	if cfg.App.RemoteMode {
+		sb.WriteString(fmt.Sprintf(" [green]Uptime     : [white]%s\n", "N/A"))
+	} else {
+		sb.WriteString(fmt.Sprintf(" [green]Uptime     : [white]%s\n",
+			timeFromSeconds(uptimes),
+		))
+	}
 	return fmt.Sprint(sb.String())
 }
 
 func getPeerText(ctx context.Context) string {
+	if config.GetConfig().App.RemoteMode {
+		return " [yellow]Peer analysis is not available in remote mode\n"
+	}
 	if processMetrics == nil {
 		return peerText
 	}
+	// The analysis progress changes constantly, but the results only change
+	// when the peer data does
+	if !checkPeers && !peersDirty.Swap(false) && peerText != "" {
+		return peerText
+	}
 	var sb strings.Builder
 
 	// Style / UI
@@ -909,190 +1502,357 @@ func getPeerText(ctx context.Context) string {
 		charMarked = string('▌')
 		charUnmarked = string('▖')
 	}
-	var granularity int = 68
-	granularitySmall := granularity / 2
+	granularitySmall := getGranularity() / 2
 	if checkPeers {
-		peerCount := len(peersFiltered)
-		sb.WriteString(
-			fmt.Sprintf(" [yellow]%s [blue]%d[white]/[green]%d[white]\n",
-				"Peer analysis started... please wait!",
-				len(peerStats.RTTresultsSlice),
-				peerCount,
-			),
-		)
+		sb.WriteString(getPeerAnalysisText())
 		scrollPeers = false
 		return sb.String()
 	}
 
 	peerCount := len(peersFiltered)
-	sb.WriteString("       [green]RTT : Peers / Percent\n")
-	sb.WriteString(fmt.Sprintf(
-		"    [green]0-50ms : [white]%5s   %.f%%",
-		strconv.Itoa(peerStats.CNT1),
-		peerStats.PCT1,
-	))
-	sb.WriteString(fmt.Sprintf(
-		"%"+strconv.Itoa(10-len(fmt.Sprintf("%.f", peerStats.PCT1)))+"s",
-		" ",
-	))
-	for i := 0; i < granularitySmall; i++ {
-		if i < int(peerStats.PCT1) {
-			sb.WriteString(fmt.Sprintf("[green]%s", charMarked))
-		} else {
-			sb.WriteString(fmt.Sprintf("[white]%s", charUnmarked))
-		}
-	}
-	sb.WriteString("[white]\n") // closeRow
-	sb.WriteString(fmt.Sprintf(
-		"  [green]50-100ms : [white]%5s   %.f%%",
-		strconv.Itoa(peerStats.CNT2),
-		peerStats.PCT2,
-	))
-	sb.WriteString(fmt.Sprintf(
-		"%"+strconv.Itoa(10-len(fmt.Sprintf("%.f", peerStats.PCT2)))+"s",
-		"",
-	))
-	for i := 0; i < granularitySmall; i++ {
-		if i < int(peerStats.PCT2) {
-			sb.WriteString(fmt.Sprintf("[yellow]%s", charMarked))
-		} else {
-			sb.WriteString(fmt.Sprintf("[white]%s", charUnmarked))
+	// Preallocate for the header rows and a row per peer
+	sb.Grow(1024 + len(peerStats.RTTresultsSlice)*96)
+	divider := strings.Repeat("-", width-1) + "\n"
+	airGapped := config.GetConfig().App.AirGapped
+	if airGapped {
+		// RTT isn't measured in air-gapped mode, so only show the
+		// connection counts
+		var peersIn, peersOut int
+		for _, peer := range peerStats.RTTresultsSlice {
+			if strings.Contains(peer.Direction, "i") {
+				peersIn++
+			}
+			if strings.Contains(peer.Direction, "o") {
+				peersOut++
+			}
 		}
-	}
-	sb.WriteString("[white]\n") // closeRow
-	sb.WriteString(fmt.Sprintf(
-		" [green]100-200ms : [white]%5s   %.f%%",
-		strconv.Itoa(peerStats.CNT3),
-		peerStats.PCT3,
-	))
-	sb.WriteString(fmt.Sprintf(
-		"%"+strconv.Itoa(10-len(fmt.Sprintf("%.f", peerStats.PCT3)))+"s",
-		"",
-	))
-	for i := 0; i < granularitySmall; i++ {
-		if i < int(peerStats.PCT3) {
-			sb.WriteString(fmt.Sprintf("[red]%s", charMarked))
-		} else {
-			sb.WriteString(fmt.Sprintf("[white]%s", charUnmarked))
+		fmt.Fprintf(
+			&sb,
+			" [green]Total / In / Out : [white]%d / [blue]%d[white] / [blue]%d[white]\n",
+			peerCount,
+			peersIn,
+			peersOut,
+		)
+	} else {
+		sb.WriteString("       [green]RTT : Peers / Percent\n")
+		rttRows := []struct {
+			label string
+			count int
+			pct   float32
+			color string
+		}{
+			{"    [green]0-50ms : ", peerStats.CNT1, peerStats.PCT1, "[green]"},
+			{"  [green]50-100ms : ", peerStats.CNT2, peerStats.PCT2, "[yellow]"},
+			{" [green]100-200ms : ", peerStats.CNT3, peerStats.PCT3, "[red]"},
+			{"   [green]200ms < : ", peerStats.CNT4, peerStats.PCT4, "[fuchsia]"},
 		}
-	}
-	sb.WriteString("[white]\n") // closeRow
-	sb.WriteString(fmt.Sprintf(
-		"   [green]200ms < : [white]%5s   %.f%%",
-		strconv.Itoa(peerStats.CNT4),
-		peerStats.PCT4,
-	))
-	sb.WriteString(fmt.Sprintf(
-		"%"+strconv.Itoa(10-len(fmt.Sprintf("%.f", peerStats.PCT4)))+"s",
-		"",
-	))
-	for i := 0; i < granularitySmall; i++ {
-		if i < int(peerStats.PCT4) {
-			sb.WriteString(fmt.Sprintf("[fuchsia]%s", charMarked))
-		} else {
-			sb.WriteString(fmt.Sprintf("[white]%s", charUnmarked))
+		for _, row := range rttRows {
+			pct := fmt.Sprintf("%.f", row.pct)
+			sb.WriteString(row.label)
+			fmt.Fprintf(&sb, "[white]%5d   %s%%", row.count, pct)
+			sb.WriteString(strings.Repeat(" ", max(10-len(pct), 0)))
+			writeProgressBar(
+				&sb,
+				progressItems(row.pct, granularitySmall),
+				granularitySmall,
+				row.color+charMarked,
+				"[white]"+charUnmarked,
+			)
+			sb.WriteString("[white]\n") // closeRow
 		}
-	}
-	sb.WriteString("[white]\n") // closeRow
 
-	// Divider
-	sb.WriteString(fmt.Sprintf("%s\n", strings.Repeat("-", width-1)))
+		// Divider
+		sb.WriteString(divider)
 
-	sb.WriteString(
-		fmt.Sprintf(
+		fmt.Fprintf(
+			&sb,
 			" [green]Total / Undetermined : [white]%d[white] / ",
 			peerCount,
-		),
-	)
-	if peerStats.CNT0 == 0 {
-		sb.WriteString("[blue]0[white]")
-	} else {
-		sb.WriteString(fmt.Sprintf("[fuchsia]%d[white]", peerStats.CNT0))
-	}
-	// TODO: figure out spacing here
-	if peerStats.RTTAVG >= 200 {
-		sb.WriteString(
-			fmt.Sprintf(
-				" Average RTT : [fuchsia]%d[white] ms\n",
-				peerStats.RTTAVG,
-			),
 		)
-	} else if peerStats.RTTAVG >= 100 {
-		sb.WriteString(fmt.Sprintf(" Average RTT : [red]%d[white] ms\n", peerStats.RTTAVG))
-	} else if peerStats.RTTAVG >= 50 {
-		sb.WriteString(fmt.Sprintf(" Average RTT : [yellow]%d[white] ms\n", peerStats.RTTAVG))
-	} else if peerStats.RTTAVG >= 0 {
-		sb.WriteString(fmt.Sprintf(" Average RTT : [green]%d[white] ms\n", peerStats.RTTAVG))
-	} else {
-		sb.WriteString(fmt.Sprintf(" Average RTT : [red]%s[white] ms\n", "---"))
+		if peerStats.CNT0 == 0 {
+			sb.WriteString("[blue]0[white]")
+		} else {
+			fmt.Fprintf(&sb, "[fuchsia]%d[white]", peerStats.CNT0)
+		}
+		// TODO: figure out spacing here
+		if peerStats.RTTAVG >= 200 {
+			fmt.Fprintf(&sb, " Average RTT : [fuchsia]%d[white] ms\n", peerStats.RTTAVG)
+		} else if peerStats.RTTAVG >= 100 {
+			fmt.Fprintf(&sb, " Average RTT : [red]%d[white] ms\n", peerStats.RTTAVG)
+		} else if peerStats.RTTAVG >= 50 {
+			fmt.Fprintf(&sb, " Average RTT : [yellow]%d[white] ms\n", peerStats.RTTAVG)
+		} else if peerStats.RTTAVG >= 0 {
+			fmt.Fprintf(&sb, " Average RTT : [green]%d[white] ms\n", peerStats.RTTAVG)
+		} else {
+			sb.WriteString(" Average RTT : [red]---[white] ms\n")
+		}
 	}
 
 	// Divider
-	sb.WriteString(fmt.Sprintf("%s\n", strings.Repeat("-", width-1)))
+	sb.WriteString(divider)
 
-	sb.WriteString(
-		fmt.Sprintf("   [green]# %24s  I/O RTT   Geolocation\n", "REMOTE PEER"),
+	if airGapped {
+		fmt.Fprintf(&sb, "   [green]# %24s  I/O Geolocation\n", "REMOTE PEER")
+	} else {
+		fmt.Fprintf(&sb, "   [green]# %24s  I/O RTT   Geolocation\n", "REMOTE PEER")
+	}
+	// Stats above cover all peers, but only the configured number are listed
+	peers, hiddenPeers := limitPeers(
+		getDisplayPeers(
+			peerStats.RTTresultsSlice,
+			peerSortDescending.Load(),
+		),
+		config.GetConfig().App.MaxPeersDisplayed,
 	)
-	// peerLocationWidth := width - 41
-	for peerNbr, peer := range peerStats.RTTresultsSlice {
+	for peerNbr, peer := range peers {
 		peerNbr++
-		peerRTT := peer.RTT
-		peerPORT := peer.Port
-		peerDIR := peer.Direction
 		peerIP := peer.IP
-		if strings.Contains(peer.IP, ":") {
-			if len(strings.Split(peer.IP, ":")) > 3 {
-				splitIP := strings.Split(peer.IP, ":")
-				if splitIP == nil {
-					continue
-				}
-				peerIP = fmt.Sprintf("%s...%s:%s",
-					splitIP[0],
-					splitIP[:len(splitIP)-2],
-					splitIP[:len(splitIP)-1],
-				)
-			}
+		// Shorten long IPv6 addresses to fit the column
+		if strings.Contains(peer.IP, ":") && len(peer.IP) > 19 {
+			splitIP := strings.Split(peer.IP, ":")
+			peerIP = splitIP[0] + "..." +
+				splitIP[len(splitIP)-2] + ":" +
+				splitIP[len(splitIP)-1]
 		}
-		peerLocationFmt := peer.Location
-
-		// Set color
-		color := "fuchsia"
-		if peerRTT < 50 {
-			color = "green"
-		} else if peerRTT < 100 {
-			color = "yellow"
-		} else if peerRTT < 200 {
-			color = "red"
+		peerLocationFmt := tview.Escape(getPeerLocationText(peer))
+		if peer.Pool != "" {
+			peerLocationFmt = "[blue]" + tview.Escape(peer.Pool) +
+				"[white] " + peerLocationFmt
 		}
-		if peerRTT < 99999 {
-			sb.WriteString(fmt.Sprintf(
-				" %3d %19s:%-5d %-3s ["+color+"]%-5d[white] %s\n",
+
+		if airGapped {
+			fmt.Fprintf(
+				&sb,
+				" %3d %19s:%-5d %-3s %s\n",
 				peerNbr,
 				peerIP,
-				peerPORT,
-				peerDIR,
-				peerRTT,
+				peer.Port,
+				peer.Direction,
 				peerLocationFmt,
-			))
+			)
+		} else if peer.RTT < 99999 {
+			fmt.Fprintf(
+				&sb,
+				" %3d %19s:%-5d %-3s [%s]%-5d[white] %s\n",
+				peerNbr,
+				peerIP,
+				peer.Port,
+				peer.Direction,
+				getPeerRTTColor(peer.RTT),
+				peer.RTT,
+				peerLocationFmt,
+			)
 		} else {
-			sb.WriteString(fmt.Sprintf(
+			fmt.Fprintf(
+				&sb,
 				" %3d %19s:%-5d %-3s [fuchsia]%-5s[white] %s\n",
 				peerNbr,
 				peerIP,
-				peerPORT,
-				peerDIR,
+				peer.Port,
+				peer.Direction,
 				"---",
 				peerLocationFmt,
-			))
+			)
 		}
 	}
+	if hiddenPeers > 0 {
+		fmt.Fprintf(&sb, " [yellow]… and %d more[white]\n", hiddenPeers)
+	}
 	sb.WriteString("[white]\n")
 
 	failCount = 0
-	return fmt.Sprint(sb.String())
+	return sb.String()
+}
+
+// Returns at most limit peers, or all peers when limit is 0, along with the
+// number of peers left out
+func limitPeers(peers []*Peer, limit int) ([]*Peer, int) {
+	if limit <= 0 || len(peers) <= limit {
+		return peers, 0
+	}
+	return peers[:limit], len(peers) - limit
+}
+
+// Writes a progress bar of marked items followed by unmarked items
+func writeProgressBar(
+	sb *strings.Builder,
+	items int,
+	granularity int,
+	marked string,
+	unmarked string,
+) {
+	for i := 0; i < granularity; i++ {
+		if i < items {
+			sb.WriteString(marked)
+		} else {
+			sb.WriteString(unmarked)
+		}
+	}
+}
+
+// Returns the color for a peer RTT
+func getPeerRTTColor(rtt int) string {
+	switch {
+	case rtt < 50:
+		return "green"
+	case rtt < 100:
+		return "yellow"
+	case rtt < 200:
+		return "red"
+	default:
+		return "fuchsia"
+	}
+}
+
+// System stats, which can be replaced in tests
+var virtualMemory = mem.VirtualMemoryWithContext
+var swapMemory = mem.SwapMemoryWithContext
+var systemLoadAvg = getLoadAvg
+var numCPU = runtime.NumCPU
+
+// Returns the system memory and swap lines for the Resources panel, which
+// continue the RSS line with its percentage of total system memory
+func getSystemMemText(
+	vmStat *mem.VirtualMemoryStat,
+	swapStat *mem.SwapMemoryStat,
+	rss uint64,
+) string {
+	var sb strings.Builder
+	if vmStat == nil || vmStat.Total == 0 {
+		sb.WriteString("\n")
+		sb.WriteString(" [green]Mem (Sys)  : [yellow]--\n")
+	} else {
+		sb.WriteString(fmt.Sprintf(" [blue]([white]%.0f%%[blue])\n",
+			float64(rss)/float64(vmStat.Total)*100,
+		))
+		sb.WriteString(" [green]Mem (Sys)  : " +
+			getMemoryUsageText(vmStat.Used, vmStat.Total) + "\n")
+	}
+	if swapStat == nil {
+		sb.WriteString(" [green]Swap       : [yellow]--\n")
+	} else {
+		sb.WriteString(" [green]Swap       : " +
+			getMemoryUsageText(swapStat.Used, swapStat.Total) + "\n")
+	}
+	return sb.String()
+}
+
+// Returns used and total memory sizes, like 1.50GiB/8.00GiB
+func getMemoryUsageText(used uint64, total uint64) string {
+	usedValue, usedUnit := getMemorySize(used)
+	totalValue, totalUnit := getMemorySize(total)
+	return fmt.Sprintf(
+		"[white]%s[blue]%s/[white]%s[blue]%s",
+		usedValue,
+		usedUnit,
+		totalValue,
+		totalUnit,
+	)
+}
+
+// Returns the thread count of the node process, or 0 when unknown
+func getProcessThreads(ctx context.Context, proc nodeProcess) int32 {
+	if proc == nil || proc.Pid() == 0 {
+		return 0
+	}
+	threads, err := proc.NumThreads(ctx)
+	if err != nil {
+		return 0
+	}
+	return threads
+}
+
+// Returns the thread count line for the Resources panel
+func getThreadsText(threads int32) string {
+	if threads == 0 {
+		return fmt.Sprintf(" [green]Threads    : [yellow]%s\n", "--")
+	}
+	return fmt.Sprintf(" [green]Threads    : [white]%d\n", threads)
+}
+
+// Returns the color for open file descriptors relative to the limit
+func getFDColor(numFDs int32, limit uint64) string {
+	if limit == 0 || numFDs < 0 {
+		return "white"
+	}
+	used := float64(numFDs) / float64(limit)
+	if used >= 0.9 {
+		return "red"
+	}
+	if used >= 0.75 {
+		return "yellow"
+	}
+	return "white"
+}
+
+// Returns the open file descriptors line for the Resources panel
+func getFDText(numFDs int32, limit uint64) string {
+	if limit == 0 {
+		return fmt.Sprintf(" [green]Open FDs   : [white]%d\n", numFDs)
+	}
+	return fmt.Sprintf(
+		" [green]Open FDs   : [%s]%d[blue]/[white]%d\n",
+		getFDColor(numFDs, limit),
+		numFDs,
+		limit,
+	)
+}
+
+// Returns the color for a load average relative to the number of cores
+func getLoadColor(load float64, cores int) string {
+	if cores < 1 {
+		return "white"
+	}
+	perCore := load / float64(cores)
+	if perCore >= 1 {
+		return "red"
+	}
+	if perCore >= 0.7 {
+		return "yellow"
+	}
+	return "white"
+}
+
+// Returns the CPU usage line for the Resources panel, either summed across
+// cores, which can exceed 100%, or normalized by the number of cores
+func getCPUText(cpuPercent float64, cores int) string {
+	cfg := config.GetConfig()
+	if strings.ToLower(cfg.App.CPUMode) == "normalized" && cores > 0 {
+		return fmt.Sprintf(
+			" [green]CPU (norm) : [white]%.2f%%\n",
+			cpuPercent/float64(cores),
+		)
+	}
+	return fmt.Sprintf(
+		" [green]CPU (sys)  : [white]%.2f%% [blue](%d cores)\n",
+		cpuPercent,
+		cores,
+	)
+}
+
+// Returns the Resources panel for a remote node, which only has memory and
+// GC stats from its metrics
+func getRemoteResourceText() string {
+	var sb strings.Builder
+	memLive, memLiveUnit := getMemorySize(promMetrics.MemLive)
+	memHeap, memHeapUnit := getMemorySize(promMetrics.MemHeap)
+	sb.WriteString(fmt.Sprintf(" [green]CPU (sys)  : [white]%s\n", "N/A"))
+	sb.WriteString(
+		fmt.Sprintf(" [green]Mem (Live) : [white]%s[blue]%s\n", memLive, memLiveUnit),
+	)
+	sb.WriteString(fmt.Sprintf(" [green]Mem (RSS)  : [white]%s\n", "N/A"))
+	sb.WriteString(
+		fmt.Sprintf(" [green]Mem (Heap) : [white]%s[blue]%s\n", memHeap, memHeapUnit),
+	)
+	sb.WriteString(fmt.Sprintf(" [green]GC Minor   : [white]%d\n", promMetrics.GcMinor))
+	sb.WriteString(fmt.Sprintf(" [green]GC Major   : [white]%d\n", promMetrics.GcMajor))
+	return sb.String()
 }
 
 func getResourceText(ctx context.Context) string {
+	if promMetrics != nil && config.GetConfig().App.RemoteMode {
+		return getRemoteResourceText()
+	}
 	if processMetrics == nil || promMetrics == nil {
 		return resourceText
 	}
@@ -1103,44 +1863,72 @@ func getResourceText(ctx context.Context) string {
 	var rss uint64 = 0
 	var err error
 	var processMemory *process.MemoryInfoStat
-	if processMetrics != nil && processMetrics.Pid != 0 {
-		cpuPercent, err = processMetrics.CPUPercentWithContext(ctx)
+	if processMetrics != nil && processMetrics.Pid() != 0 {
+		cpuPercent, err = processMetrics.CPUPercent(ctx)
 		if err != nil {
 			failCount++
 			return fmt.Sprintf("cannot parse CPU usage: %s", err)
 		}
-		processMemory, err = processMetrics.MemoryInfoWithContext(ctx)
+		processMemory, err = processMetrics.MemoryInfo(ctx)
 		if err != nil {
 			failCount++
 			return fmt.Sprintf("cannot parse memory usage: %s", err)
 		}
 		rss = processMemory.RSS
+		// Include helper processes, for nodes run by a wrapper
+		if config.GetConfig().App.AggregateChildren {
+			childCPU, childRSS := sumProcessUsage(
+				ctx,
+				getProcessChildren(ctx, processMetrics, time.Now()),
+			)
+			cpuPercent += childCPU
+			rss += childRSS
+		}
 	}
 
-	memRss := fmt.Sprintf("%.1f", float64(rss)/float64(1073741824))
-	memLive := fmt.Sprintf(
-		"%.1f",
-		float64(promMetrics.MemLive)/float64(1073741824),
-	)
-	memHeap := fmt.Sprintf(
-		"%.1f",
-		float64(promMetrics.MemHeap)/float64(1073741824),
-	)
+	memRss, memRssUnit := getMemorySize(rss)
+	memLive, memLiveUnit := getMemorySize(promMetrics.MemLive)
+	memHeap, memHeapUnit := getMemorySize(promMetrics.MemHeap)
 
+	sb.WriteString(getCPUText(cpuPercent, numCPU()))
+	if loadAvg, err := systemLoadAvg(ctx); err == nil && loadAvg != nil {
+		sb.WriteString(fmt.Sprintf(
+			" [green]Load avg   : [%s]%.2f [white]%.2f %.2f\n",
+			getLoadColor(loadAvg.Load1, numCPU()),
+			loadAvg.Load1,
+			loadAvg.Load5,
+			loadAvg.Load15,
+		))
+	}
 	sb.WriteString(
-		fmt.Sprintf(
-			" [green]CPU (sys)  : [white]%s%%\n",
-			fmt.Sprintf("%.2f", cpuPercent),
-		),
-	)
-	sb.WriteString(
-		fmt.Sprintf(" [green]Mem (Live) : [white]%s[blue]G\n", memLive),
+		fmt.Sprintf(" [green]Mem (Live) : [white]%s[blue]%s\n", memLive, memLiveUnit),
 	)
 	sb.WriteString(
-		fmt.Sprintf(" [green]Mem (RSS)  : [white]%s[blue]G\n", memRss),
+		fmt.Sprintf(" [green]Mem (RSS)  : [white]%s[blue]%s", memRss, memRssUnit),
 	)
+	vmStat, err := virtualMemory(ctx)
+	if err != nil {
+		vmStat = nil
+	}
+	swapStat, err := swapMemory(ctx)
+	if err != nil {
+		swapStat = nil
+	}
+	sb.WriteString(getSystemMemText(vmStat, swapStat, rss))
+	sb.WriteString(getThreadsText(processThreads))
+	if processMetrics.Pid() != 0 {
+		numFDs, err := processMetrics.NumFDs(ctx)
+		if err == nil {
+			// The limit is optional, since it's not available everywhere
+			fdLimit, err := processMetrics.FDLimit(ctx)
+			if err != nil {
+				fdLimit = 0
+			}
+			sb.WriteString(getFDText(numFDs, fdLimit))
+		}
+	}
 	sb.WriteString(
-		fmt.Sprintf(" [green]Mem (Heap) : [white]%s[blue]G\n", memHeap),
+		fmt.Sprintf(" [green]Mem (Heap) : [white]%s[blue]%s\n", memHeap, memHeapUnit),
 	)
 	sb.WriteString(
 		fmt.Sprintf(
@@ -1157,9 +1945,69 @@ func getResourceText(ctx context.Context) string {
 	return fmt.Sprint(sb.String())
 }
 
-func getProcessMetrics(ctx context.Context) (*process.Process, error) {
+// Finds the node process, trying an explicit PID, then a PID file, then the
+// binary name and port
+func getProcessMetrics(ctx context.Context) (nodeProcess, error) {
+	cfg := config.GetConfig()
+	var p *process.Process
+	var err error
+	if cfg.Node.Pid > 0 {
+		p, err = getProcessMetricsByPid(ctx, cfg.Node.Pid)
+	} else if cfg.Node.PidFile != "" {
+		p, err = getProcessMetricsByPidFile(ctx, cfg.Node.PidFile)
+	} else {
+		p, err = getProcessMetricsByNameAndPort(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newNodeProcess(p), nil
+}
+
+func getProcessMetricsByPid(
+	ctx context.Context,
+	pid int32,
+) (*process.Process, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid: %d", pid)
+	}
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find process %d: %s", pid, err)
+	}
+	running, err := p.IsRunningWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check process %d: %s", pid, err)
+	}
+	if !running {
+		return nil, fmt.Errorf("process %d is not running", pid)
+	}
+	return p, nil
+}
+
+func getProcessMetricsByPidFile(
+	ctx context.Context,
+	pidFile string,
+) (*process.Process, error) {
+	buf, err := os.ReadFile(pidFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pid file: %s", err)
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(buf)), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pid file: %s", err)
+	}
+	return getProcessMetricsByPid(ctx, int32(pid))
+}
+
+func getProcessMetricsByNameAndPort(
+	ctx context.Context,
+) (*process.Process, error) {
 	cfg := config.GetConfig()
 	r, _ := process.NewProcessWithContext(ctx, 0)
+	var candidates []*process.Process
+	var candidateBinaries []string
+	binaries := getNodeBinaries()
 	processes, err := process.ProcessesWithContext(ctx)
 	if err != nil {
 		return r, fmt.Errorf("failed to get processes: %s", err)
@@ -1173,11 +2021,22 @@ func getProcessMetrics(ctx context.Context) (*process.Process, error) {
 		if err != nil {
 			return r, fmt.Errorf("failed to get process cmdline: %s", err)
 		}
-		if strings.Contains(n, cfg.Node.Binary) &&
-			strings.Contains(c, strconv.FormatUint(uint64(cfg.Node.Port), 10)) {
+		binary := matchNodeBinary(n, binaries)
+		if binary == "" {
+			continue
+		}
+		candidates = append(candidates, p)
+		candidateBinaries = append(candidateBinaries, binary)
+		if strings.Contains(c, strconv.FormatUint(uint64(cfg.Node.Port), 10)) {
 			r = p
+			matchedNodeBinary = binary
 		}
 	}
+	// Fall back to a single running binary when the port doesn't match
+	if r.Pid == 0 && len(candidates) == 1 {
+		r = candidates[0]
+		matchedNodeBinary = candidateBinaries[0]
+	}
 	return r, nil
 }
 
@@ -1202,33 +2061,38 @@ func createRemoteClientConnection(address string) net.Conn {
 	return conn
 }
 
-func tcpinfoRtt(address string) int {
+// Returns the RTT in milliseconds for a TCP connection to a peer, or 99999
+// along with the error when the peer can't be reached
+func tcpinfoRtt(
+	ctx context.Context,
+	address string,
+	timeout time.Duration,
+) (int, error) {
 	var result int = 99999
 	// Get a connection and setup our error channels
-	conn, err := net.DialTimeout("tcp", address, 3*time.Second)
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
 	if err != nil {
-		return result
+		return result, err
 	}
 	if conn == nil {
-		return result
+		return result, fmt.Errorf("no connection to %s", address)
 	}
+	defer conn.Close()
 	tc, err := tcp.NewConn(conn)
 	if err != nil {
-		return result
+		return result, err
 	}
 	var o tcpinfo.Info
 	var b [256]byte
 	i, err := tc.Option(o.Level(), o.Name(), b[:])
 	if err != nil {
-		return result
-	}
-	txt, err := json.Marshal(i)
-	if err != nil {
-		return result
+		return result, err
 	}
-	q := &tcpinfo.Info{}
-	if err := json.Unmarshal(txt, &q); err != nil {
-		result = int(q.RTT.Seconds() * 1000)
+	info, ok := i.(*tcpinfo.Info)
+	if !ok || info == nil {
+		return result, fmt.Errorf("no TCP info for %s", address)
 	}
-	return result
+	result = int(info.RTT.Seconds() * 1000)
+	return result, nil
 }
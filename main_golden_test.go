@@ -0,0 +1,319 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	netutil "github.com/shirou/gopsutil/v3/net"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	time.Local = time.UTC
+	if _, err := config.LoadConfig(""); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// Compares output to a golden file in testdata, or updates it with -update
+func checkGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run with -update: %s", err)
+	}
+	if got != string(want) {
+		t.Errorf("output doesn't match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// Fixed time for the panel fixtures
+var fixtureNow = time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// Sets fixed metrics, process, and system stats for rendering the panels
+func setPanelFixtures(t testing.TB) {
+	t.Helper()
+	cfg := config.GetConfig()
+	oldNow := timeNow
+	oldVirtualMemory := virtualMemory
+	oldSwapMemory := swapMemory
+	oldLoadAvg := systemLoadAvg
+	oldNumCPU := numCPU
+	oldNodeName := cfg.App.NodeName
+	t.Cleanup(func() {
+		timeNow = oldNow
+		virtualMemory = oldVirtualMemory
+		swapMemory = oldSwapMemory
+		systemLoadAvg = oldLoadAvg
+		numCPU = oldNumCPU
+		cfg.App.NodeName = oldNodeName
+		promMetrics = nil
+		processMetrics = nil
+		publicIP = nil
+		termCols, termLines = 0, 0
+	})
+	timeNow = func() time.Time { return fixtureNow }
+	virtualMemory = func(ctx context.Context) (*mem.VirtualMemoryStat, error) {
+		return &mem.VirtualMemoryStat{Total: 32 << 30, Used: 20 << 30}, nil
+	}
+	swapMemory = func(ctx context.Context) (*mem.SwapMemoryStat, error) {
+		return &mem.SwapMemoryStat{Total: 8 << 30, Used: 1 << 30}, nil
+	}
+	systemLoadAvg = func(ctx context.Context) (*load.AvgStat, error) {
+		return &load.AvgStat{Load1: 3.5, Load5: 2.25, Load15: 1.75}, nil
+	}
+	numCPU = func() int { return 8 }
+	cfg.App.NodeName = "Fixture"
+	termCols, termLines = 120, 40
+
+	tip := getSlotAtTime(time.Unix(fixtureNow.Unix()-1, 0))
+	promMetrics = &PromMetrics{
+		BlockNum:            11612345,
+		EpochNum:            556,
+		SlotInEpoch:         215985,
+		SlotNum:             tip - 12,
+		Density:             0.04891,
+		TxProcessed:         98765,
+		MempoolTx:           12,
+		MempoolBytes:        34567,
+		KesPeriod:           1123,
+		RemainingKesPeriods: 42,
+		IsLeader:            5,
+		Adopted:             4,
+		DidntAdopt:          1,
+		AboutToLead:         300,
+		MissedSlots:         3,
+		MemLive:             5 << 30,
+		MemHeap:             9 << 30,
+		GcMinor:             4321,
+		GcMajor:             21,
+		Forks:               7,
+		BlockDelay:          0.43,
+		BlocksServed:        1234,
+		BlocksLate:          2,
+		BlocksW1s:           0.9512,
+		BlocksW3s:           0.9877,
+		BlocksW5s:           0.9931,
+		PeersCold:           40,
+		PeersWarm:           20,
+		PeersHot:            10,
+		ConnIncoming:        25,
+		ConnOutgoing:        30,
+		ConnUniDir:          5,
+		ConnBiDir:           15,
+		ConnDuplex:          3,
+	}
+	processMetrics = &fakeProcess{
+		pid:     1234,
+		name:    "cardano-node",
+		cmdline: []string{"cardano-node", "run"},
+		cpu:     123.456,
+		rss:     12 << 30,
+		fds:     850,
+		fdLimit: 1024,
+		threads: 42,
+		conns: map[string][]netutil.ConnectionStat{
+			"tcp": {
+				{
+					Status: "ESTABLISHED",
+					Laddr:  netutil.Addr{IP: "10.0.0.1", Port: 3001},
+					Raddr:  netutil.Addr{IP: "203.0.113.1", Port: 40000},
+				},
+				{
+					Status: "ESTABLISHED",
+					Laddr:  netutil.Addr{IP: "10.0.0.1", Port: 45000},
+					Raddr:  netutil.Addr{IP: "203.0.113.2", Port: 3001},
+				},
+				{
+					Status: "TIME_WAIT",
+					Laddr:  netutil.Addr{IP: "10.0.0.1", Port: 45001},
+					Raddr:  netutil.Addr{IP: "203.0.113.3", Port: 3001},
+				},
+			},
+		},
+	}
+	currentEpoch = 556
+	epochBar = ""
+	uptimes = 93784
+	processThreads = 42
+	ip := net.ParseIP("198.51.100.7")
+	publicIP = &ip
+	nodeVersionCache.pid = 1234
+	nodeVersionCache.version = "10.1.4"
+	nodeVersionCache.revision = "1f63dbf2"
+	forksTrend = counterTrend{}
+	forges = forgeHistory{}
+	role = "Core"
+	p2p = true
+}
+
+// Sets peer analysis results for n peers, covering IPv4 and long and short
+// IPv6 addresses in both directions, pools, and unreachable peers
+func setPeerFixture(n int) {
+	peerStats = PeerStats{RTTresultsMap: make(peerRTTresultsMap)}
+	peersFiltered = nil
+	var reachable int
+	for i := 0; i < n; i++ {
+		peer := &Peer{Port: 3001 + i%3, Direction: "o"}
+		switch i % 5 {
+		case 0:
+			peer.IP = fmt.Sprintf("203.0.%d.%d", i/250, i%250)
+			peer.RTT = 12 + i%30
+			peer.Location = "Frankfurt, DE"
+			peer.Pool = "BLINK"
+		case 1:
+			peer.IP = fmt.Sprintf("2001:db8:85a3:0:0:8a2e:370:%x", i)
+			peer.RTT = 75
+			peer.Location = "Ashburn, US"
+			peer.Hostname = "relay1.example.com"
+			peer.Distance = 6543.2
+			peer.Direction = "i"
+		case 2:
+			peer.IP = fmt.Sprintf("2001:db8::%x", i)
+			peer.RTT = 150
+			peer.Location = "Tokyo, JP [test]"
+		case 3:
+			peer.IP = fmt.Sprintf("198.51.%d.%d", i/250, i%250)
+			peer.RTT = 250 + i
+			peer.Location = "Sydney, AU"
+			peer.Direction = "i"
+		case 4:
+			peer.IP = fmt.Sprintf("192.0.%d.%d", i/250, i%250)
+			peer.RTT = 99999
+			peer.Location = "---"
+		}
+		switch {
+		case peer.RTT < 50:
+			peerStats.CNT1++
+		case peer.RTT < 100:
+			peerStats.CNT2++
+		case peer.RTT < 200:
+			peerStats.CNT3++
+		case peer.RTT < 99999:
+			peerStats.CNT4++
+		default:
+			peerStats.CNT0++
+		}
+		if peer.RTT < 99999 {
+			peerStats.RTTSUM += peer.RTT
+			reachable++
+		}
+		peersFiltered = append(
+			peersFiltered,
+			fmt.Sprintf("%s;%d;%s", peer.IP, peer.Port, peer.Direction),
+		)
+		peerStats.RTTresultsMap[peer.IP] = peer
+		peerStats.RTTresultsSlice = append(peerStats.RTTresultsSlice, peer)
+	}
+	sort.Sort(peerStats.RTTresultsSlice)
+	if reachable > 0 {
+		peerStats.RTTAVG = peerStats.RTTSUM / reachable
+		peerStats.PCT1 = float32(peerStats.CNT1) / float32(reachable) * 100
+		peerStats.PCT2 = float32(peerStats.CNT2) / float32(reachable) * 100
+		peerStats.PCT3 = float32(peerStats.CNT3) / float32(reachable) * 100
+		peerStats.PCT4 = float32(peerStats.CNT4) / float32(reachable) * 100
+	}
+	checkPeers = false
+	peerText = ""
+	peersDirty.Store(true)
+}
+
+func TestPanelGolden(t *testing.T) {
+	testDefs := []struct {
+		name   string
+		setup  func()
+		render func(context.Context) string
+	}{
+		{name: "node", render: getNodeText},
+		{name: "chain", render: getChainText},
+		{name: "connections_p2p", render: getConnectionText},
+		{
+			name:   "connections",
+			setup:  func() { p2p = false },
+			render: getConnectionText,
+		},
+		{name: "core", render: getCoreText},
+		{
+			name:   "core_relay",
+			setup:  func() { role = "Relay" },
+			render: getCoreText,
+		},
+		{name: "block", render: getBlockText},
+		{name: "resources", render: getResourceText},
+		{name: "epoch", render: getEpochText},
+		{
+			name:   "peers",
+			setup:  func() { setPeerFixture(10) },
+			render: getPeerText,
+		},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.name, func(t *testing.T) {
+			setPanelFixtures(t)
+			if testDef.setup != nil {
+				testDef.setup()
+			}
+			got := testDef.render(context.Background())
+			checkGolden(t, testDef.name, got)
+		})
+	}
+}
+
+// The Peers panel for many peers matches its output from before it was
+// optimized
+func TestPeerTextGoldenManyPeers(t *testing.T) {
+	setPanelFixtures(t)
+	setPeerFixture(500)
+	checkGolden(t, "peers_500", getPeerText(context.Background()))
+}
+
+func BenchmarkGetPeerText(b *testing.B) {
+	setPanelFixtures(b)
+	setPeerFixture(500)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		peersDirty.Store(true)
+		_ = getPeerText(ctx)
+	}
+}
@@ -0,0 +1,1464 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	netutil "github.com/shirou/gopsutil/v3/net"
+
+	"github.com/blinklabs-io/nview/internal/config"
+	"github.com/blinklabs-io/nview/internal/version"
+)
+
+// Sets the synced threshold for a test
+func setSyncedThreshold(t *testing.T, threshold uint64) {
+	t.Helper()
+	cfg := config.GetConfig()
+	old := cfg.Node.SyncedThreshold
+	cfg.Node.SyncedThreshold = threshold
+	t.Cleanup(func() { cfg.Node.SyncedThreshold = old })
+}
+
+func TestIsSynced(t *testing.T) {
+	started := &PromMetrics{SlotNum: 1000}
+	testDefs := []struct {
+		threshold uint64
+		metrics   *PromMetrics
+		tipRef    uint64
+		expected  bool
+	}{
+		{threshold: 20, metrics: nil, tipRef: 1000, expected: false},
+		{threshold: 20, metrics: &PromMetrics{}, tipRef: 1000, expected: false},
+		{threshold: 20, metrics: started, tipRef: 1000, expected: true},
+		{threshold: 20, metrics: started, tipRef: 1020, expected: true},
+		{threshold: 20, metrics: started, tipRef: 1021, expected: false},
+		// A tip reference behind the node isn't a tip diff
+		{threshold: 20, metrics: started, tipRef: 990, expected: true},
+		{threshold: 0, metrics: started, tipRef: 1000, expected: true},
+		{threshold: 0, metrics: started, tipRef: 1001, expected: false},
+		{threshold: 600, metrics: started, tipRef: 1600, expected: true},
+		{threshold: 600, metrics: started, tipRef: 1601, expected: false},
+	}
+	for _, testDef := range testDefs {
+		setSyncedThreshold(t, testDef.threshold)
+		got := isSynced(testDef.metrics, testDef.tipRef)
+		if got != testDef.expected {
+			t.Errorf(
+				"threshold %d, tip ref %d: got %v, expected %v",
+				testDef.threshold,
+				testDef.tipRef,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+// The synced threshold is used by -check, independent of the tip diff
+// display thresholds
+func TestGetCheckResultSyncedThreshold(t *testing.T) {
+	metrics := &PromMetrics{BlockNum: 10, SlotNum: 1000}
+	testDefs := []struct {
+		threshold uint64
+		tipRef    uint64
+		status    string
+		synced    bool
+	}{
+		{threshold: 20, tipRef: 1020, status: "OK", synced: true},
+		{threshold: 20, tipRef: 1021, status: "SLOW", synced: false},
+		{threshold: 100, tipRef: 1100, status: "SLOW", synced: true},
+		{threshold: 100, tipRef: 1601, status: "SYNCING", synced: false},
+	}
+	for _, testDef := range testDefs {
+		setSyncedThreshold(t, testDef.threshold)
+		got := getCheckResult(metrics, testDef.tipRef)
+		if got.Status != testDef.status || got.Synced != testDef.synced {
+			t.Errorf(
+				"threshold %d, tip ref %d: got %s/%v, expected %s/%v",
+				testDef.threshold,
+				testDef.tipRef,
+				got.Status,
+				got.Synced,
+				testDef.status,
+				testDef.synced,
+			)
+		}
+	}
+}
+
+func TestGetBlockTextTerminalResize(t *testing.T) {
+	setPanelFixtures(t)
+	oldMinLines := termMinLines.Load()
+	t.Cleanup(func() {
+		termMinLines.Store(oldMinLines)
+		termTooSmall = false
+		footerTextView.SetText("")
+	})
+	termMinLines.Store(36)
+	testDefs := []struct {
+		cols, lines int
+		expected    string
+		footer      string
+	}{
+		{
+			cols:     60,
+			lines:    40,
+			expected: "Please increase by [yellow]12[white] columns",
+			footer:   " [yellow](esc/q) Quit\n",
+		},
+		{
+			cols:     120,
+			lines:    30,
+			expected: "Please increase by [yellow]6[white] lines",
+			footer:   " [yellow](esc/q) Quit\n",
+		},
+		// The footer is restored once the terminal is large enough again
+		{
+			cols:     120,
+			lines:    36,
+			expected: "[green]Last Delay : ",
+			footer:   getFooterText(),
+		},
+	}
+	for _, testDef := range testDefs {
+		termCols, termLines = testDef.cols, testDef.lines
+		got := getBlockText(context.Background())
+		if !strings.Contains(got, testDef.expected) {
+			t.Errorf(
+				"%dx%d: got %q, expected it to contain %q",
+				testDef.cols,
+				testDef.lines,
+				got,
+				testDef.expected,
+			)
+		}
+		footer := footerTextView.GetText(false)
+		if footer != testDef.footer {
+			t.Errorf(
+				"%dx%d: got footer %q, expected %q",
+				testDef.cols,
+				testDef.lines,
+				footer,
+				testDef.footer,
+			)
+		}
+	}
+}
+
+func TestGetConnectionTextLoading(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldP2P := p2p
+	oldRemoteMode := cfg.App.RemoteMode
+	t.Cleanup(func() {
+		p2p = oldP2P
+		cfg.App.RemoteMode = oldRemoteMode
+	})
+	promMetrics = nil
+	processMetrics = nil
+	testDefs := []struct {
+		p2p        bool
+		remoteMode bool
+		expected   string
+	}{
+		{
+			p2p: true,
+			expected: " [green]P2P        : enabled\n" +
+				" [green]Incoming   : [yellow]--\n" +
+				" [green]Outgoing   : [yellow]--\n" +
+				" [green]Cold Peers : [yellow]--\n" +
+				" [green]Warm Peers : [yellow]--\n" +
+				" [green]Hot Peers  : [yellow]--\n" +
+				" [green]Uni-Dir    : [yellow]--\n" +
+				" [green]Bi-Dir     : [yellow]--\n" +
+				" [green]Duplex     : [yellow]--\n",
+		},
+		{
+			p2p: false,
+			expected: " [green]P2P        : [yellow]disabled\n" +
+				" [green]Incoming   : [yellow]--\n" +
+				" [green]Outgoing   : [yellow]--\n" +
+				" [yellow]finding node process...\n",
+		},
+		{
+			p2p:        false,
+			remoteMode: true,
+			expected: " [green]P2P        : [yellow]disabled\n" +
+				" [green]Incoming   : [white]N/A\n" +
+				" [green]Outgoing   : [white]N/A\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		p2p = testDef.p2p
+		cfg.App.RemoteMode = testDef.remoteMode
+		got := getConnectionText(context.Background())
+		if got != testDef.expected {
+			t.Errorf(
+				"p2p %v, remote %v: got %q, expected %q",
+				testDef.p2p,
+				testDef.remoteMode,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+	// Loaded metrics replace the placeholders, including zeros
+	p2p = true
+	promMetrics = &PromMetrics{}
+	got := getConnectionText(context.Background())
+	if !strings.Contains(got, " [green]Incoming   : [white]0\n") ||
+		!strings.Contains(got, " [green]Duplex     : [white]0\n") {
+		t.Errorf("got %q, expected zero counts", got)
+	}
+}
+
+func TestRemoteModeText(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldRemoteMode := cfg.App.RemoteMode
+	t.Cleanup(func() {
+		cfg.App.RemoteMode = oldRemoteMode
+	})
+	cfg.App.RemoteMode = true
+	// Only fields from the metrics are shown, even with a process found
+	testDefs := []struct {
+		name     string
+		got      string
+		expected []string
+	}{
+		{
+			name: "node",
+			got:  getNodeText(context.Background()),
+			expected: []string{
+				" [green]Uptime     : [white]N/A\n",
+			},
+		},
+		{
+			name: "resources",
+			got:  getResourceText(context.Background()),
+			expected: []string{
+				" [green]CPU (sys)  : [white]N/A\n",
+				" [green]Mem (Live) : [white]5.00[blue]GiB\n",
+				" [green]Mem (RSS)  : [white]N/A\n",
+				" [green]Mem (Heap) : [white]9.00[blue]GiB\n",
+			},
+		},
+		{
+			name: "peers",
+			got:  getPeerText(context.Background()),
+			expected: []string{
+				" [yellow]Peer analysis is not available in remote mode\n",
+			},
+		},
+	}
+	for _, testDef := range testDefs {
+		for _, expected := range testDef.expected {
+			if !strings.Contains(testDef.got, expected) {
+				t.Errorf(
+					"%s: got %q, expected %q",
+					testDef.name,
+					testDef.got,
+					expected,
+				)
+			}
+		}
+	}
+}
+
+func TestTcpinfoRttTimeout(t *testing.T) {
+	// Dialing a non-routable address only fails once the timeout passes
+	timeout := 250 * time.Millisecond
+	start := time.Now()
+	rtt, err := tcpinfoRtt(context.Background(), "10.255.255.1:3001", timeout)
+	elapsed := time.Since(start)
+	// Some sandboxes answer for every address or have no route at all
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Skipf("dialing a non-routable address didn't time out: %v", err)
+	}
+	if rtt != 99999 {
+		t.Errorf("got %d, expected 99999", rtt)
+	}
+	if elapsed < timeout || elapsed > timeout+time.Second {
+		t.Errorf("got %s, expected about %s", elapsed, timeout)
+	}
+}
+
+func TestIsMetricsPopulated(t *testing.T) {
+	testDefs := []struct {
+		metrics  *PromMetrics
+		expected bool
+	}{
+		{metrics: nil, expected: false},
+		// A starting node only has its runtime metrics
+		{metrics: &PromMetrics{}, expected: false},
+		{metrics: &PromMetrics{MemLive: 5 << 30}, expected: false},
+		// Any chain metric is enough, even with the rest at zero
+		{metrics: &PromMetrics{SlotNum: 86400}, expected: true},
+		{metrics: &PromMetrics{EpochNum: 1}, expected: true},
+		{metrics: &PromMetrics{BlockNum: 1}, expected: true},
+	}
+	for i, testDef := range testDefs {
+		if got := isMetricsPopulated(testDef.metrics); got != testDef.expected {
+			t.Errorf("%d: got %v, expected %v", i, got, testDef.expected)
+		}
+	}
+}
+
+func TestInitializingText(t *testing.T) {
+	setPanelFixtures(t)
+	testDefs := []struct {
+		name    string
+		getText func(context.Context) string
+		zeros   string
+	}{
+		{
+			name:    "chain",
+			getText: getChainText,
+			zeros:   " Block      : [white]0 ",
+		},
+		{
+			name:    "block",
+			getText: getBlockText,
+			zeros:   " [green]Served     : [white]0 ",
+		},
+		{
+			name:    "core",
+			getText: getCoreText,
+			zeros:   " [green]Adopted    : [white]0\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		// Missing chain metrics show a placeholder instead of zeros
+		promMetrics = &PromMetrics{MemLive: 5 << 30}
+		got := testDef.getText(context.Background())
+		if !strings.HasPrefix(got, initializingText) ||
+			strings.Contains(got, testDef.zeros) {
+			t.Errorf(
+				"%s: got %q, expected %q",
+				testDef.name,
+				got,
+				initializingText,
+			)
+		}
+		// Genuine zeros are shown once the node reports its slot
+		promMetrics = &PromMetrics{SlotNum: 86400}
+		got = testDef.getText(context.Background())
+		if strings.Contains(got, initializingText) ||
+			!strings.Contains(got, testDef.zeros) {
+			t.Errorf(
+				"%s: got %q, expected it to contain %q",
+				testDef.name,
+				got,
+				testDef.zeros,
+			)
+		}
+	}
+}
+
+func TestRefreshPanelsPeersOnlyFocus(t *testing.T) {
+	setPanelFixtures(t)
+	oldFocus := peersOnlyFocus
+	oldNodeText, oldResourceText := nodeText, resourceText
+	oldCoreText, oldChainText, oldBlockText := coreText, chainText, blockText
+	t.Cleanup(func() {
+		peersOnlyFocus = oldFocus
+		nodeText, resourceText = oldNodeText, oldResourceText
+		coreText, chainText, blockText = oldCoreText, oldChainText, oldBlockText
+	})
+	testDefs := []struct {
+		focus  bool
+		paused bool
+	}{
+		// Paused panels keep their last text
+		{focus: true, paused: true},
+		{focus: false, paused: false},
+	}
+	for _, testDef := range testDefs {
+		peersOnlyFocus = testDef.focus
+		nodeText, resourceText, coreText = "last", "last", "last"
+		chainText, blockText = "last", "last"
+		refreshPanels(context.Background())
+		texts := map[string]string{
+			"node":      nodeText,
+			"resources": resourceText,
+			"core":      coreText,
+			"chain":     chainText,
+			"block":     blockText,
+		}
+		for name, text := range texts {
+			if (text == "last") != testDef.paused {
+				t.Errorf(
+					"focus %v, %s: got %q, expected paused %v",
+					testDef.focus,
+					name,
+					text,
+					testDef.paused,
+				)
+			}
+		}
+	}
+}
+
+func TestRefreshPanels(t *testing.T) {
+	setPanelFixtures(t)
+	setEventLogFixture(t, 10)
+	oldNodeText, oldPeerText := nodeText, peerText
+	oldScrollPeers, oldTooSmall := scrollPeers, termTooSmall
+	oldEventsPageText := eventsPageText
+	oldDirty := peersDirty.Load()
+	t.Cleanup(func() {
+		nodeText, peerText = oldNodeText, oldPeerText
+		peersDirty.Store(oldDirty)
+		scrollPeers, termTooSmall = oldScrollPeers, oldTooSmall
+		eventsPageText = oldEventsPageText
+		pages.RemovePage("Events")
+		footerTextView.SetText("")
+	})
+	ctx := context.Background()
+	nodeText, peerText = "stale", "stale"
+	scrollPeers, termTooSmall = true, false
+	peersDirty.Store(true)
+	refreshPanels(ctx)
+	if nodeText == "stale" || nodeText != getNodeText(ctx) {
+		t.Errorf("got node text %q, expected it to be refreshed", nodeText)
+	}
+	if got := nodeTextView.GetText(false); got != nodeText {
+		t.Errorf("got node view %q, expected %q", got, nodeText)
+	}
+	if peerText == "stale" {
+		t.Errorf("got peer text %q, expected it to be refreshed", peerText)
+	}
+	if scrollPeers {
+		t.Errorf("got scrollPeers true, expected it to be cleared")
+	}
+	if got := headerTextView.GetText(false); got != getHeaderText() {
+		t.Errorf("got header %q, expected %q", got, getHeaderText())
+	}
+
+	// The footer keeps its size warning while the terminal is too small
+	termCols, termLines = 40, 10
+	refreshPanels(ctx)
+	refreshPanels(ctx)
+	quitFooter := " [yellow](esc/q) Quit\n"
+	if got := footerTextView.GetText(false); got != quitFooter {
+		t.Errorf("got footer %q, expected %q", got, quitFooter)
+	}
+	termCols, termLines = 120, 40
+
+	// Full-screen pages only refresh while they are shown
+	nodeEvents.add(fixtureNow, "fixture event")
+	eventsPageText = ""
+	refreshPanels(ctx)
+	if eventsPageText != "" {
+		t.Errorf(
+			"got events page %q, expected no refresh while hidden",
+			eventsPageText,
+		)
+	}
+	pages.AddPage("Events", eventsPageTextView, true, true)
+	refreshPanels(ctx)
+	if !strings.Contains(eventsPageText, "fixture event") {
+		t.Errorf(
+			"got events page %q, expected it to contain %q",
+			eventsPageText,
+			"fixture event",
+		)
+	}
+}
+
+func TestGetPeerTextDirty(t *testing.T) {
+	setPanelFixtures(t)
+	oldPeerText := peerText
+	oldCheckPeers := checkPeers
+	oldDescending := peerSortDescending.Load()
+	t.Cleanup(func() {
+		peerText = oldPeerText
+		checkPeers = oldCheckPeers
+		peerSortDescending.Store(oldDescending)
+		peersDirty.Store(false)
+		peersFiltered = nil
+		peerStats = PeerStats{}
+	})
+	setPeerFixture(3)
+	testDefs := []struct {
+		name       string
+		setup      func()
+		rebuilt    bool
+		checkPeers bool
+	}{
+		// Nothing changed since the last rebuild
+		{name: "clean", setup: func() {}, rebuilt: false},
+		{
+			name:    "dirty",
+			setup:   func() { peersDirty.Store(true) },
+			rebuilt: true,
+		},
+		{
+			name:    "sorted",
+			setup:   func() { togglePeerSort() },
+			rebuilt: true,
+		},
+		{name: "reset", setup: resetPeers, rebuilt: true},
+		// The analysis progress is always redrawn while it runs
+		{
+			name:       "analysis",
+			setup:      func() {},
+			rebuilt:    true,
+			checkPeers: true,
+		},
+	}
+	for _, testDef := range testDefs {
+		peerText = "last"
+		peersDirty.Store(false)
+		testDef.setup()
+		checkPeers = testDef.checkPeers
+		got := getPeerText(context.Background())
+		if (got != "last") != testDef.rebuilt {
+			t.Errorf(
+				"%s: got %q, expected rebuilt %v",
+				testDef.name,
+				got,
+				testDef.rebuilt,
+			)
+		}
+		// A rebuild clears the flag until the data changes again
+		if peersDirty.Load() {
+			t.Errorf("%s: expected the dirty flag to be cleared", testDef.name)
+		}
+	}
+	// Nothing is cached to reuse before the first rebuild
+	peerText = ""
+	checkPeers = false
+	if got := getPeerText(context.Background()); got == "" {
+		t.Errorf("expected the peers panel to be built")
+	}
+}
+
+func TestGetPeerTextAirGapped(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldAirGapped := cfg.App.AirGapped
+	t.Cleanup(func() {
+		cfg.App.AirGapped = oldAirGapped
+		peersDirty.Store(false)
+		peersFiltered = nil
+		peerStats = PeerStats{}
+	})
+	cfg.App.AirGapped = true
+	setPeerFixture(4)
+	got := getPeerText(context.Background())
+	// Only connection counts are shown, since RTT isn't measured
+	if !strings.Contains(got, " [green]Total / In / Out : [white]4 / ") ||
+		!strings.Contains(got, "  I/O Geolocation\n") {
+		t.Errorf("got %q, expected connection counts", got)
+	}
+	if strings.Contains(got, "Average RTT") || strings.Contains(got, "0-50ms") {
+		t.Errorf("got %q, expected no RTT stats", got)
+	}
+}
+
+func TestGetConnectionTextStates(t *testing.T) {
+	setPanelFixtures(t)
+	oldP2P := p2p
+	t.Cleanup(func() {
+		p2p = oldP2P
+	})
+	p2p = false
+	conn := func(status string, port uint32) netutil.ConnectionStat {
+		return netutil.ConnectionStat{
+			Status: status,
+			Laddr:  netutil.Addr{IP: "10.0.0.1", Port: 45000},
+			Raddr:  netutil.Addr{IP: "203.0.113.1", Port: port},
+		}
+	}
+	testDefs := []struct {
+		connections []netutil.ConnectionStat
+		expected    string
+	}{
+		// Problem states are highlighted only when present
+		{
+			connections: []netutil.ConnectionStat{conn("ESTABLISHED", 3001)},
+			expected: " [green]Syn Sent   : [white]0\n" +
+				" [green]Time Wait  : [white]0\n" +
+				" [green]Close Wait : [white]0\n",
+		},
+		{
+			connections: []netutil.ConnectionStat{
+				conn("ESTABLISHED", 3001),
+				conn("SYN_SENT", 3002),
+				conn("TIME_WAIT", 3003),
+				conn("TIME_WAIT", 3004),
+			},
+			expected: " [green]Syn Sent   : [yellow]1\n" +
+				" [green]Time Wait  : [yellow]2\n" +
+				" [green]Close Wait : [white]0\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		processMetrics = &fakeProcess{
+			pid: 1234,
+			conns: map[string][]netutil.ConnectionStat{
+				"tcp": testDef.connections,
+			},
+		}
+		got := getConnectionText(context.Background())
+		if !strings.Contains(got, testDef.expected) {
+			t.Errorf("got %q, expected it to contain %q", got, testDef.expected)
+		}
+	}
+}
+
+func TestFormatHeaderText(t *testing.T) {
+	oldVersion, oldCommitHash := version.Version, version.CommitHash
+	t.Cleanup(func() {
+		version.Version, version.CommitHash = oldVersion, oldCommitHash
+	})
+	version.Version, version.CommitHash = "1.2.3", "abc1234"
+	testDefs := []struct {
+		title       string
+		showVersion bool
+		width       int
+		expected    string
+	}{
+		{
+			showVersion: true,
+			expected:    " > nview - 1.2.3 (commit abc1234) | Uptime: 01:02:03\n",
+		},
+		{
+			title:       "Preview Relay",
+			showVersion: true,
+			expected: " > Preview Relay - 1.2.3 (commit abc1234) " +
+				"| Uptime: 01:02:03\n",
+		},
+		{
+			title:    "Preview Relay",
+			expected: " > Preview Relay | Uptime: 01:02:03\n",
+		},
+		// Tags in the title are shown as is
+		{
+			title:    "[preview] relay",
+			expected: " > [preview[] relay | Uptime: 01:02:03\n",
+		},
+		// The title is truncated to fit, keeping the uptime
+		{
+			title:       "Preview Relay",
+			showVersion: true,
+			width:       40,
+			expected:    " > Preview Relay - 1… | Uptime: 01:02:03\n",
+		},
+		{
+			title:    "Preview Relay",
+			width:    10,
+			expected: " > … | Uptime: 01:02:03\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		got := formatHeaderText(
+			testDef.title,
+			testDef.showVersion,
+			"01:02:03",
+			testDef.width,
+		)
+		if got != testDef.expected {
+			t.Errorf(
+				"%q, width %d: got %q, expected %q",
+				testDef.title,
+				testDef.width,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestLimitPeers(t *testing.T) {
+	peers := []*Peer{{IP: "a"}, {IP: "b"}, {IP: "c"}}
+	testDefs := []struct {
+		limit    int
+		expected int
+		hidden   int
+	}{
+		// No limit by default
+		{limit: 0, expected: 3, hidden: 0},
+		{limit: 2, expected: 2, hidden: 1},
+		{limit: 3, expected: 3, hidden: 0},
+		{limit: 10, expected: 3, hidden: 0},
+	}
+	for _, testDef := range testDefs {
+		got, hidden := limitPeers(peers, testDef.limit)
+		if len(got) != testDef.expected || hidden != testDef.hidden {
+			t.Errorf(
+				"limit %d: got %d peers, %d hidden, expected %d, %d hidden",
+				testDef.limit,
+				len(got),
+				hidden,
+				testDef.expected,
+				testDef.hidden,
+			)
+		}
+		// The best peers are kept
+		if len(got) > 0 && got[0] != peers[0] {
+			t.Errorf("limit %d: expected the first peers", testDef.limit)
+		}
+	}
+}
+
+func TestGetPeerTextMaxPeersDisplayed(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldMax := cfg.App.MaxPeersDisplayed
+	t.Cleanup(func() {
+		cfg.App.MaxPeersDisplayed = oldMax
+		peersDirty.Store(false)
+		peersFiltered = nil
+		peerStats = PeerStats{}
+	})
+	testDefs := []struct {
+		max    int
+		rows   int
+		hidden string
+	}{
+		{max: 0, rows: 10},
+		{max: 3, rows: 3, hidden: " [yellow]… and 7 more[white]\n"},
+		{max: 10, rows: 10},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.MaxPeersDisplayed = testDef.max
+		setPeerFixture(10)
+		got := getPeerText(context.Background())
+		// Stats still cover all peers
+		if !strings.Contains(got, "Total / Undetermined : [white]10[white]") {
+			t.Errorf(
+				"max %d: got %q, expected stats for all peers",
+				testDef.max,
+				got,
+			)
+		}
+		var rows int
+		for _, line := range strings.Split(got, "\n") {
+			if strings.Contains(line, ":300") {
+				rows++
+			}
+		}
+		if rows != testDef.rows {
+			t.Errorf(
+				"max %d: got %d rows, expected %d",
+				testDef.max,
+				rows,
+				testDef.rows,
+			)
+		}
+		hidden := strings.Contains(got, " more[white]")
+		if hidden != (testDef.hidden != "") ||
+			!strings.Contains(got, testDef.hidden) {
+			t.Errorf(
+				"max %d: got %q, expected %q",
+				testDef.max,
+				got,
+				testDef.hidden,
+			)
+		}
+	}
+}
+
+// Waits for our background workers, failing the test if they don't stop
+func waitForWorkers(t *testing.T) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for workers to stop")
+	}
+}
+
+func TestRunWorkerStopsOnCancel(t *testing.T) {
+	oldLookup := lookupPublicIP
+	t.Cleanup(func() {
+		lookupPublicIP = oldLookup
+		publicIP = nil
+	})
+	lookupPublicIP = func(ctx context.Context) (net.IP, error) {
+		return nil, errors.New("i/o timeout")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var started sync.WaitGroup
+	started.Add(3)
+	runWorker(ctx, func() {
+		started.Done()
+		for sleepWithContext(ctx, time.Hour) {
+		}
+	})
+	runWorker(ctx, func() {
+		started.Done()
+		for sleepOrRefresh(ctx, time.Hour, refreshRequests) {
+		}
+	})
+	runWorker(ctx, func() {
+		started.Done()
+		updatePublicIP(ctx)
+	})
+	started.Wait()
+	cancel()
+	waitForWorkers(t)
+	// Workers aren't started once we're shutting down
+	ran := false
+	runWorker(ctx, func() { ran = true })
+	waitForWorkers(t)
+	if ran {
+		t.Errorf("expected the worker not to run after cancelling")
+	}
+}
+
+func TestGetChainTextNoEmoji(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldNoEmoji := cfg.App.NoEmoji
+	t.Cleanup(func() {
+		cfg.App.NoEmoji = oldNoEmoji
+	})
+	testDefs := []struct {
+		noEmoji  bool
+		expected string
+	}{
+		{noEmoji: false, expected: "12 😀"},
+		{noEmoji: true, expected: "12 OK"},
+	}
+	// Display width of the tip diff line before the next column
+	var widths []int
+	for _, testDef := range testDefs {
+		cfg.App.NoEmoji = testDef.noEmoji
+		text := getChainText(context.Background())
+		var line string
+		for _, l := range strings.Split(text, "\n") {
+			if strings.Contains(l, "Tip (diff)") {
+				line = l
+			}
+		}
+		if !strings.Contains(line, testDef.expected) {
+			t.Errorf(
+				"no emoji %v: got %q, expected it to contain %q",
+				testDef.noEmoji,
+				line,
+				testDef.expected,
+			)
+		}
+		before, _, found := strings.Cut(line, "Total Tx")
+		if !found {
+			t.Fatalf("got %q, expected a Total Tx column", line)
+		}
+		widths = append(widths, tview.TaggedStringWidth(before))
+	}
+	if widths[0] != widths[1] {
+		t.Errorf("got widths %v, expected the columns to line up", widths)
+	}
+}
+
+func TestProgressItems(t *testing.T) {
+	testDefs := []struct {
+		percent     float32
+		granularity int
+		expected    int
+	}{
+		{percent: 0, granularity: 68, expected: 0},
+		{percent: 50, granularity: 68, expected: 34},
+		{percent: 50, granularity: 34, expected: 17},
+		{percent: 50, granularity: 10, expected: 5},
+		{percent: 50, granularity: 200, expected: 100},
+		// Partial items are rounded down
+		{percent: 99.9, granularity: 68, expected: 67},
+		{percent: 33, granularity: 10, expected: 3},
+		{percent: 1, granularity: 34, expected: 0},
+		{percent: 100, granularity: 34, expected: 34},
+		{percent: 100, granularity: 1, expected: 1},
+		// Out of range percentages are clamped to the bar
+		{percent: 150, granularity: 68, expected: 68},
+		{percent: -5, granularity: 68, expected: 0},
+	}
+	for _, testDef := range testDefs {
+		got := progressItems(testDef.percent, testDef.granularity)
+		if got != testDef.expected {
+			t.Errorf(
+				"%v%% of %d: got %d, expected %d",
+				testDef.percent,
+				testDef.granularity,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetGranularity(t *testing.T) {
+	cfg := config.GetConfig()
+	oldGranularity := cfg.App.Granularity
+	t.Cleanup(func() {
+		cfg.App.Granularity = oldGranularity
+	})
+	testDefs := []struct {
+		granularity int
+		expected    int
+	}{
+		// Unset or invalid values use the default
+		{granularity: 0, expected: 68},
+		{granularity: -1, expected: 68},
+		{granularity: 20, expected: 20},
+		{granularity: 120, expected: 120},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.Granularity = testDef.granularity
+		if got := getGranularity(); got != testDef.expected {
+			t.Errorf(
+				"%d: got %d, expected %d",
+				testDef.granularity,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetEpochTextGranularity(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldGranularity := cfg.App.Granularity
+	t.Cleanup(func() {
+		cfg.App.Granularity = oldGranularity
+		epochBar = ""
+		epochBarLast = epochBarKey{}
+	})
+	// The fixture is 49.99% through the epoch
+	testDefs := []struct {
+		granularity int
+		marked      int
+	}{
+		{granularity: 20, marked: 9},
+		{granularity: 68, marked: 33},
+		{granularity: 120, marked: 58},
+		// Going back to an earlier granularity rebuilds the bar
+		{granularity: 20, marked: 9},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.Granularity = testDef.granularity
+		getEpochText(context.Background())
+		marked := strings.Count(epochBar, "▌")
+		unmarked := strings.Count(epochBar, "▖")
+		if marked != testDef.marked ||
+			marked+unmarked != testDef.granularity {
+			t.Errorf(
+				"granularity %d: got %d/%d items, expected %d/%d",
+				testDef.granularity,
+				marked,
+				marked+unmarked,
+				testDef.marked,
+				testDef.granularity,
+			)
+		}
+		// The bar is reused while nothing changes
+		bar := epochBar
+		getEpochText(context.Background())
+		if epochBar != bar {
+			t.Errorf("expected the cached epoch bar to be reused")
+		}
+	}
+}
+
+func TestGetEpochTextNewEpoch(t *testing.T) {
+	setPanelFixtures(t)
+	t.Cleanup(func() {
+		epochBar = ""
+		epochBarLast = epochBarKey{}
+	})
+	getEpochText(context.Background())
+	// Mark the cached bar so a rebuild can be seen
+	const staleBar = "stale"
+	epochBar = staleBar
+	getEpochText(context.Background())
+	if epochBar != staleBar {
+		t.Fatalf("expected the cached bar to be reused for the same epoch")
+	}
+	// A new epoch rebuilds the bar, even with the same number of items
+	currentEpoch++
+	text := getEpochText(context.Background())
+	if epochBar == staleBar || strings.Contains(text, staleBar) {
+		t.Errorf("expected the bar to be rebuilt for a new epoch")
+	}
+	if epochBarLast.epoch != currentEpoch {
+		t.Errorf(
+			"got cached epoch %d, expected %d",
+			epochBarLast.epoch,
+			currentEpoch,
+		)
+	}
+}
+
+func TestGetCPUText(t *testing.T) {
+	cfg := config.GetConfig()
+	oldMode := cfg.App.CPUMode
+	t.Cleanup(func() {
+		cfg.App.CPUMode = oldMode
+	})
+	testDefs := []struct {
+		mode     string
+		percent  float64
+		cores    int
+		expected string
+	}{
+		// Raw usage is summed across cores
+		{
+			mode:     "raw",
+			percent:  340,
+			cores:    8,
+			expected: " [green]CPU (sys)  : [white]340.00% [blue](8 cores)\n",
+		},
+		{
+			mode:     "",
+			percent:  12.5,
+			cores:    4,
+			expected: " [green]CPU (sys)  : [white]12.50% [blue](4 cores)\n",
+		},
+		// Normalized usage is divided by the number of cores
+		{
+			mode:     "normalized",
+			percent:  340,
+			cores:    8,
+			expected: " [green]CPU (norm) : [white]42.50%\n",
+		},
+		{
+			mode:     "Normalized",
+			percent:  50,
+			cores:    1,
+			expected: " [green]CPU (norm) : [white]50.00%\n",
+		},
+		// Raw usage is shown when the core count is unknown
+		{
+			mode:     "normalized",
+			percent:  75,
+			cores:    0,
+			expected: " [green]CPU (sys)  : [white]75.00% [blue](0 cores)\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.CPUMode = testDef.mode
+		got := getCPUText(testDef.percent, testDef.cores)
+		if got != testDef.expected {
+			t.Errorf(
+				"%q, %v%%, %d cores: got %q, expected %q",
+				testDef.mode,
+				testDef.percent,
+				testDef.cores,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetResourceTextCPUMode(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldMode := cfg.App.CPUMode
+	t.Cleanup(func() {
+		cfg.App.CPUMode = oldMode
+	})
+	testDefs := []struct {
+		mode     string
+		expected string
+	}{
+		{mode: "raw", expected: "[white]123.46% [blue](8 cores)"},
+		{mode: "normalized", expected: "[white]15.43%"},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.CPUMode = testDef.mode
+		text := getResourceText(context.Background())
+		if !strings.Contains(text, testDef.expected) {
+			t.Errorf(
+				"%s: expected %q in:\n%s",
+				testDef.mode,
+				testDef.expected,
+				text,
+			)
+		}
+	}
+}
+
+func TestGetTipDiffBucket(t *testing.T) {
+	cfg := config.GetConfig()
+	oldOK := cfg.App.TipDiffOK
+	oldSlow := cfg.App.TipDiffSlow
+	t.Cleanup(func() {
+		cfg.App.TipDiffOK = oldOK
+		cfg.App.TipDiffSlow = oldSlow
+	})
+	testDefs := []struct {
+		ok      uint64
+		slow    uint64
+		tipDiff uint64
+		bucket  tipDiffBucket
+		color   string
+	}{
+		// Default thresholds, which are inclusive
+		{ok: 20, slow: 600, tipDiff: 0, bucket: tipDiffOK, color: "white"},
+		{ok: 20, slow: 600, tipDiff: 19, bucket: tipDiffOK, color: "white"},
+		{ok: 20, slow: 600, tipDiff: 20, bucket: tipDiffOK, color: "white"},
+		{ok: 20, slow: 600, tipDiff: 21, bucket: tipDiffSlow, color: "yellow"},
+		{ok: 20, slow: 600, tipDiff: 599, bucket: tipDiffSlow, color: "yellow"},
+		{ok: 20, slow: 600, tipDiff: 600, bucket: tipDiffSlow, color: "yellow"},
+		{ok: 20, slow: 600, tipDiff: 601, bucket: tipDiffSyncing, color: "yellow"},
+		// Custom thresholds
+		{ok: 5, slow: 60, tipDiff: 6, bucket: tipDiffSlow, color: "yellow"},
+		{ok: 5, slow: 60, tipDiff: 61, bucket: tipDiffSyncing, color: "yellow"},
+		// Equal thresholds leave no SLOW bucket
+		{ok: 50, slow: 50, tipDiff: 50, bucket: tipDiffOK, color: "white"},
+		{ok: 50, slow: 50, tipDiff: 51, bucket: tipDiffSyncing, color: "yellow"},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.TipDiffOK = testDef.ok
+		cfg.App.TipDiffSlow = testDef.slow
+		got := getTipDiffBucket(testDef.tipDiff)
+		if got != testDef.bucket || got.Color() != testDef.color {
+			t.Errorf(
+				"%d with thresholds %d/%d: got %s (%s), expected %s (%s)",
+				testDef.tipDiff,
+				testDef.ok,
+				testDef.slow,
+				got,
+				got.Color(),
+				testDef.bucket,
+				testDef.color,
+			)
+		}
+	}
+}
+
+func TestGetChainTextTipDiffThresholds(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldOK := cfg.App.TipDiffOK
+	oldSlow := cfg.App.TipDiffSlow
+	oldNoEmoji := cfg.App.NoEmoji
+	t.Cleanup(func() {
+		cfg.App.TipDiffOK = oldOK
+		cfg.App.TipDiffSlow = oldSlow
+		cfg.App.NoEmoji = oldNoEmoji
+	})
+	cfg.App.TipDiffOK = 20
+	cfg.App.TipDiffSlow = 600
+	cfg.App.NoEmoji = true
+	// The fixture is 12 slots behind the reference tip
+	tipRef := promMetrics.SlotNum + 12
+	testDefs := []struct {
+		tipDiff  uint64
+		expected string
+	}{
+		{tipDiff: 20, expected: " Tip (diff) : [white]20 OK"},
+		{tipDiff: 21, expected: " Tip (diff) : [yellow]21 SLOW"},
+		{tipDiff: 600, expected: " Tip (diff) : [yellow]600 SLOW"},
+		{tipDiff: 601, expected: " Syncing    : [yellow]"},
+	}
+	for _, testDef := range testDefs {
+		promMetrics.SlotNum = tipRef - testDef.tipDiff
+		text := getChainText(context.Background())
+		if !strings.Contains(text, testDef.expected) {
+			t.Errorf(
+				"%d: expected %q in:\n%s",
+				testDef.tipDiff,
+				testDef.expected,
+				text,
+			)
+		}
+	}
+}
+
+func TestGetDensityRatio(t *testing.T) {
+	testDefs := []struct {
+		density  float64
+		coeff    float64
+		expected float64
+	}{
+		{density: 0.05, coeff: 0.05, expected: 100},
+		{density: 0.04891, coeff: 0.05, expected: 97.82},
+		{density: 0.035, coeff: 0.05, expected: 70},
+		{density: 0.06, coeff: 0.05, expected: 120},
+		{density: 0.025, coeff: 0.1, expected: 25},
+		// An unknown coefficient has no ratio
+		{density: 0.05, coeff: 0, expected: 0},
+	}
+	for _, testDef := range testDefs {
+		got := getDensityRatio(testDef.density, testDef.coeff)
+		if math.Abs(got-testDef.expected) > 1e-9 {
+			t.Errorf(
+				"%v of %v: got %v, expected %v",
+				testDef.density,
+				testDef.coeff,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetDensityColor(t *testing.T) {
+	testDefs := []struct {
+		ratio    float64
+		expected string
+	}{
+		{ratio: 0, expected: "red"},
+		{ratio: 79.9, expected: "red"},
+		{ratio: 80, expected: "yellow"},
+		{ratio: 89.9, expected: "yellow"},
+		{ratio: 90, expected: "white"},
+		{ratio: 100, expected: "white"},
+		{ratio: 120, expected: "white"},
+	}
+	for _, testDef := range testDefs {
+		if got := getDensityColor(testDef.ratio); got != testDef.expected {
+			t.Errorf(
+				"%v: got %s, expected %s",
+				testDef.ratio,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetDensityText(t *testing.T) {
+	cfg := config.GetConfig()
+	oldCoeff := cfg.Node.ShelleyGenesis.ActiveSlotsCoeff
+	t.Cleanup(func() {
+		cfg.Node.ShelleyGenesis.ActiveSlotsCoeff = oldCoeff
+	})
+	testDefs := []struct {
+		density  float64
+		coeff    float64
+		expected string
+	}{
+		{
+			density:  0.04891,
+			coeff:    0.05,
+			expected: " Density    : [white]4.891 [white]98%[green] ",
+		},
+		{
+			density:  0.042,
+			coeff:    0.05,
+			expected: " Density    : [white]4.200 [yellow]84%[green] ",
+		},
+		{
+			density:  0.03,
+			coeff:    0.05,
+			expected: " Density    : [white]3.000 [red]60%[green] ",
+		},
+		{
+			density:  0.06,
+			coeff:    0.05,
+			expected: " Density    : [white]6.000 [white]120%[green]",
+		},
+		// Only the density is shown without a coefficient
+		{
+			density:  0.04891,
+			coeff:    0,
+			expected: " Density    : [white]4.891     [green]",
+		},
+	}
+	for _, testDef := range testDefs {
+		cfg.Node.ShelleyGenesis.ActiveSlotsCoeff = testDef.coeff
+		got := getDensityText(testDef.density)
+		if got != testDef.expected {
+			t.Errorf(
+				"%v of %v: got %q, expected %q",
+				testDef.density,
+				testDef.coeff,
+				got,
+				testDef.expected,
+			)
+		}
+		// The column is always the same width
+		if width := tview.TaggedStringWidth(got); width != 24 {
+			t.Errorf("%q: got width %d, expected 24", got, width)
+		}
+	}
+}
+
+func TestCounterTrend(t *testing.T) {
+	var trend counterTrend
+	testDefs := []struct {
+		value    uint64
+		expected uint64
+	}{
+		// The first value is the baseline
+		{value: 7, expected: 0},
+		{value: 7, expected: 0},
+		{value: 9, expected: 2},
+		{value: 12, expected: 5},
+		// A node restart resets the counter and the baseline
+		{value: 1, expected: 0},
+		{value: 1, expected: 0},
+		{value: 4, expected: 3},
+		// Dropping to zero is also a restart
+		{value: 0, expected: 0},
+		{value: 2, expected: 2},
+	}
+	for i, testDef := range testDefs {
+		if got := trend.update(testDef.value); got != testDef.expected {
+			t.Errorf(
+				"update %d with %d: got %d, expected %d",
+				i,
+				testDef.value,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetChainTextForks(t *testing.T) {
+	setPanelFixtures(t)
+	testDefs := []struct {
+		forks    uint64
+		expected string
+	}{
+		{forks: 7, expected: " Forks      : [white]7 "},
+		{forks: 7, expected: " Forks      : [white]7 "},
+		{forks: 10, expected: " Forks      : [yellow]10 (+3) "},
+		// The node restarted
+		{forks: 2, expected: " Forks      : [white]2 "},
+		{forks: 3, expected: " Forks      : [yellow]3 (+1) "},
+	}
+	for _, testDef := range testDefs {
+		promMetrics.Forks = testDef.forks
+		text := getChainText(context.Background())
+		if !strings.Contains(text, testDef.expected) {
+			t.Errorf(
+				"%d: expected %q in:\n%s",
+				testDef.forks,
+				testDef.expected,
+				text,
+			)
+		}
+	}
+}
+
+func TestToggleMouse(t *testing.T) {
+	cfg := config.GetConfig()
+	oldEnableMouse := cfg.App.EnableMouse
+	oldEnableAppMouse := enableAppMouse
+	oldMouseEnabled := mouseEnabled
+	t.Cleanup(func() {
+		cfg.App.EnableMouse = oldEnableMouse
+		enableAppMouse = oldEnableAppMouse
+		mouseEnabled = oldMouseEnabled
+	})
+	var calls []bool
+	enableAppMouse = func(enabled bool) {
+		calls = append(calls, enabled)
+	}
+	testDefs := []struct {
+		enableMouse bool
+		toggled     bool
+		notice      string
+	}{
+		// Mouse support is off by default to keep copy and paste working
+		{
+			enableMouse: false,
+			toggled:     true,
+			notice:      "Mouse enabled: scroll with the wheel",
+		},
+		{
+			enableMouse: true,
+			toggled:     false,
+			notice:      "Mouse disabled: select text to copy",
+		},
+	}
+	for _, testDef := range testDefs {
+		calls = nil
+		cfg.App.EnableMouse = testDef.enableMouse
+		setMouseEnabled(cfg.App.EnableMouse)
+		if mouseEnabled != testDef.enableMouse {
+			t.Errorf(
+				"got %v, expected %v from the config",
+				mouseEnabled,
+				testDef.enableMouse,
+			)
+		}
+		if notice := toggleMouse(); notice != testDef.notice {
+			t.Errorf("got %q, expected %q", notice, testDef.notice)
+		}
+		expected := []bool{testDef.enableMouse, testDef.toggled}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("got app calls %v, expected %v", calls, expected)
+		}
+	}
+}
+
+func TestGetResourceTextMemoryUnit(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldUnit := cfg.App.MemoryUnit
+	t.Cleanup(func() {
+		cfg.App.MemoryUnit = oldUnit
+	})
+	testDefs := []struct {
+		unit     string
+		expected []string
+	}{
+		{
+			unit: "GiB",
+			expected: []string{
+				" [green]Mem (Live) : [white]5.00[blue]GiB\n",
+				" [green]Mem (RSS)  : [white]12.0[blue]GiB ",
+				" [green]Mem (Heap) : [white]9.00[blue]GiB\n",
+			},
+		},
+		{
+			unit: "GB",
+			expected: []string{
+				" [green]Mem (Live) : [white]5.37[blue]GB\n",
+				" [green]Mem (RSS)  : [white]12.9[blue]GB ",
+				" [green]Mem (Heap) : [white]9.66[blue]GB\n",
+			},
+		},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.MemoryUnit = testDef.unit
+		text := getResourceText(context.Background())
+		for _, expected := range testDef.expected {
+			if !strings.Contains(text, expected) {
+				t.Errorf(
+					"%s: expected %q in:\n%s",
+					testDef.unit,
+					expected,
+					text,
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Columns written to the metrics CSV file
+var metricsCsvHeader = []string{
+	"timestamp",
+	"block",
+	"slot",
+	"tip_diff",
+	"peers",
+	"rtt_avg_ms",
+	"cpu_percent",
+	"mem_rss_bytes",
+}
+
+// How often we flush buffered rows to disk
+const metricsCsvFlushInterval = 5 * time.Second
+
+var metricsCsv *metricsCsvWriter
+
+type metricsCsvWriter struct {
+	path      string
+	maxSize   int64
+	file      *os.File
+	writer    *csv.Writer
+	lastFlush time.Time
+}
+
+// Opens a metrics CSV file for appending, writing a header to new files
+func newMetricsCsvWriter(path string, maxSize int64) (*metricsCsvWriter, error) {
+	m := &metricsCsvWriter{
+		path:    path,
+		maxSize: maxSize,
+	}
+	if err := m.open(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *metricsCsvWriter) open() error {
+	f, err := os.OpenFile(
+		m.path,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0o640,
+	)
+	if err != nil {
+		return fmt.Errorf("error opening metrics CSV file: %s", err)
+	}
+	m.file = f
+	m.writer = csv.NewWriter(f)
+	m.lastFlush = time.Now()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading metrics CSV file: %s", err)
+	}
+	if info.Size() == 0 {
+		if err := m.writer.Write(metricsCsvHeader); err != nil {
+			return err
+		}
+		m.writer.Flush()
+		return m.writer.Error()
+	}
+	return nil
+}
+
+// Rotates the file to a ".1" suffix once it exceeds the max size
+func (m *metricsCsvWriter) rotate() error {
+	if m.maxSize <= 0 {
+		return nil
+	}
+	info, err := m.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < m.maxSize {
+		return nil
+	}
+	if err := m.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(m.path, m.path+".1"); err != nil {
+		return err
+	}
+	return m.open()
+}
+
+// Writes a row, flushing periodically
+func (m *metricsCsvWriter) Write(row []string) error {
+	if err := m.writer.Write(row); err != nil {
+		return err
+	}
+	if time.Since(m.lastFlush) < metricsCsvFlushInterval {
+		return nil
+	}
+	m.writer.Flush()
+	m.lastFlush = time.Now()
+	if err := m.writer.Error(); err != nil {
+		return err
+	}
+	return m.rotate()
+}
+
+// Flushes any buffered rows and closes the file
+func (m *metricsCsvWriter) Close() error {
+	m.writer.Flush()
+	if err := m.writer.Error(); err != nil {
+		return err
+	}
+	return m.file.Close()
+}
+
+// Builds a metrics CSV row from our current metrics
+func getMetricsCsvRow(ctx context.Context, now time.Time) []string {
+	var block, slot, tipDiff uint64
+	if promMetrics != nil {
+		block = promMetrics.BlockNum
+		slot = promMetrics.SlotNum
+		tipDiff = getTipDiff(promMetrics, getSlotTipRef())
+	}
+	var cpuPercent float64
+	var rss uint64
+	if processMetrics != nil && processMetrics.Pid() != 0 {
+		cpuPercent, _ = processMetrics.CPUPercent(ctx)
+		processMemory, err := processMetrics.MemoryInfo(ctx)
+		if err == nil {
+			rss = processMemory.RSS
+		}
+	}
+	return []string{
+		now.UTC().Format(time.RFC3339),
+		strconv.FormatUint(block, 10),
+		strconv.FormatUint(slot, 10),
+		strconv.FormatUint(tipDiff, 10),
+		strconv.Itoa(len(peersFiltered)),
+		strconv.Itoa(peerStats.RTTAVG),
+		strconv.FormatFloat(cpuPercent, 'f', 2, 64),
+		strconv.FormatUint(rss, 10),
+	}
+}
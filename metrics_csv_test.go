@@ -0,0 +1,166 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Reads the lines of a metrics CSV file
+func readMetricsCsv(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestMetricsCsvWriterHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	header := strings.Join(metricsCsvHeader, ",")
+	m, err := newMetricsCsvWriter(path, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The header is written as soon as a new file is opened
+	got := readMetricsCsv(t, path)
+	if !reflect.DeepEqual(got, []string{header}) {
+		t.Errorf("got %q, expected only the header", got)
+	}
+	row := []string{
+		"2025-01-01T00:00:00Z",
+		"1",
+		"2",
+		"3",
+		"4",
+		"5",
+		"6.00",
+		"7",
+	}
+	if err := m.Write(row); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// Reopening an existing file appends without another header
+	m, err = newMetricsCsvWriter(path, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := m.Write(row); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []string{
+		header,
+		strings.Join(row, ","),
+		strings.Join(row, ","),
+	}
+	if got = readMetricsCsv(t, path); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestMetricsCsvWriterRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	m, err := newMetricsCsvWriter(path, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	row := []string{"2025-01-01T00:00:00Z", "11612345", "150000000", "0"}
+	// Rows are buffered until the flush interval passes
+	for i := 0; i < 3; i++ {
+		if err := m.Write(row); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if _, err := os.Stat(path + ".1"); err == nil {
+		t.Fatalf("expected no rotation before a flush")
+	}
+	m.lastFlush = time.Now().Add(-metricsCsvFlushInterval)
+	if err := m.Write(row); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rotated := readMetricsCsv(t, path+".1")
+	if len(rotated) != 5 {
+		t.Errorf(
+			"got %d rotated lines, expected the header and 4 rows",
+			len(rotated),
+		)
+	}
+	// The new file starts with a header
+	header := strings.Join(metricsCsvHeader, ",")
+	got := readMetricsCsv(t, path)
+	if !reflect.DeepEqual(got, []string{header}) {
+		t.Errorf("got %q, expected only the header", got)
+	}
+}
+
+func TestGetMetricsCsvRow(t *testing.T) {
+	setPanelFixtures(t)
+	setPeerFixture(10)
+	got := getMetricsCsvRow(context.Background(), fixtureNow)
+	if len(got) != len(metricsCsvHeader) {
+		t.Fatalf(
+			"got %d columns, expected %d to match the header",
+			len(got),
+			len(metricsCsvHeader),
+		)
+	}
+	expected := []string{
+		fixtureNow.UTC().Format(time.RFC3339),
+		"11612345",
+		strconv.FormatUint(promMetrics.SlotNum, 10),
+		"12",
+		"10",
+		"123",
+		"123.46",
+		"12884901888",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+	// Zeros are written until we have metrics and a node process
+	promMetrics = nil
+	processMetrics = nil
+	peersFiltered = nil
+	peerStats.RTTAVG = 0
+	got = getMetricsCsvRow(context.Background(), fixtureNow)
+	expected = []string{
+		fixtureNow.UTC().Format(time.RFC3339),
+		"0",
+		"0",
+		"0",
+		"0",
+		"0",
+		"0.00",
+		"0",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
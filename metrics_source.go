@@ -0,0 +1,125 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Environment variable which points nview at a captured metrics file instead
+// of the node, for development and testing
+const metricsFileEnv = "NVIEW_METRICS_FILE"
+
+// A source of Prometheus metrics text
+type MetricsSource interface {
+	// Returns the raw metrics text and an HTTP status code
+	Fetch(ctx context.Context) ([]byte, int, error)
+}
+
+// The metrics source used by getPromMetrics
+var metricsSource MetricsSource = &httpMetricsSource{}
+
+// Fetches metrics from the node over HTTP
+type httpMetricsSource struct{}
+
+func (s *httpMetricsSource) Fetch(ctx context.Context) ([]byte, int, error) {
+	return getNodeMetrics(ctx)
+}
+
+// Reads metrics from a saved copy of the node's /metrics output
+type fileMetricsSource struct {
+	path string
+}
+
+func (s *fileMetricsSource) Fetch(ctx context.Context) ([]byte, int, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return data, http.StatusOK, nil
+}
+
+// Replays captured metrics from a file or a directory of snapshots
+//
+// Snapshots in a directory are returned in file name order, one per fetch,
+// so timestamped names replay in sequence. The last snapshot is repeated once
+// the series is exhausted.
+type replayMetricsSource struct {
+	sync.Mutex
+	files []string
+	next  int
+}
+
+func newReplayMetricsSource(path string) (*replayMetricsSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return &replayMetricsSource{files: []string{path}}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no metrics snapshots found in %s", path)
+	}
+	sort.Strings(files)
+	return &replayMetricsSource{files: files}, nil
+}
+
+// Returns the path of the next snapshot and advances the sequence
+func (s *replayMetricsSource) advance() string {
+	s.Lock()
+	defer s.Unlock()
+	file := s.files[s.next]
+	if s.next < len(s.files)-1 {
+		s.next++
+	}
+	return file
+}
+
+func (s *replayMetricsSource) Fetch(ctx context.Context) ([]byte, int, error) {
+	data, err := os.ReadFile(s.advance())
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return data, http.StatusOK, nil
+}
+
+// Returns the metrics source selected by the command line or environment
+func getMetricsSource() (MetricsSource, error) {
+	if cmdlineFlags.replay != "" {
+		return newReplayMetricsSource(cmdlineFlags.replay)
+	}
+	if path := os.Getenv(metricsFileEnv); path != "" {
+		return &fileMetricsSource{path: path}, nil
+	}
+	return &httpMetricsSource{}, nil
+}
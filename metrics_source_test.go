@@ -0,0 +1,140 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes metrics snapshots to a directory, returning its path
+func writeMetricsSnapshots(t *testing.T, snapshots map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range snapshots {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReplayMetricsSourceDirectory(t *testing.T) {
+	dir := writeMetricsSnapshots(t, map[string]string{
+		"20250101T000010.prom": "cardano_node_metrics_blockNum_int 2\n",
+		"20250101T000000.prom": "cardano_node_metrics_blockNum_int 1\n",
+		"20250101T000020.prom": "cardano_node_metrics_blockNum_int 3\n",
+	})
+	// Directories within the snapshot directory are skipped
+	if err := os.Mkdir(filepath.Join(dir, "old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	source, err := newReplayMetricsSource(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// Snapshots replay in name order, then the last one repeats
+	for i, expected := range []string{"1", "2", "3", "3", "3"} {
+		data, status, err := source.Fetch(context.Background())
+		if err != nil {
+			t.Fatalf("fetch %d: unexpected error: %s", i, err)
+		}
+		if status != http.StatusOK {
+			t.Errorf("fetch %d: got status %d, expected 200", i, status)
+		}
+		got := string(data)
+		if got != "cardano_node_metrics_blockNum_int "+expected+"\n" {
+			t.Errorf("fetch %d: got %q, expected block %s", i, got, expected)
+		}
+	}
+}
+
+func TestReplayMetricsSourceFile(t *testing.T) {
+	dir := writeMetricsSnapshots(t, map[string]string{
+		"metrics.prom": "cardano_node_metrics_blockNum_int 1\n",
+	})
+	source, err := newReplayMetricsSource(filepath.Join(dir, "metrics.prom"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		data, _, err := source.Fetch(context.Background())
+		if err != nil || string(data) != "cardano_node_metrics_blockNum_int 1\n" {
+			t.Errorf("fetch %d: got %q, %v, expected the snapshot", i, data, err)
+		}
+	}
+}
+
+func TestReplayMetricsSourceInvalid(t *testing.T) {
+	empty := t.TempDir()
+	if err := os.Mkdir(filepath.Join(empty, "old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		empty,
+		filepath.Join(t.TempDir(), "missing"),
+	} {
+		if _, err := newReplayMetricsSource(path); err == nil {
+			t.Errorf("%s: expected an error", path)
+		}
+	}
+	// A snapshot removed while replaying is a failed fetch
+	dir := writeMetricsSnapshots(t, map[string]string{
+		"metrics.prom": "cardano_node_metrics_blockNum_int 1\n",
+	})
+	source, err := newReplayMetricsSource(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "metrics.prom")); err != nil {
+		t.Fatal(err)
+	}
+	_, status, err := source.Fetch(context.Background())
+	if err == nil || status != http.StatusInternalServerError {
+		t.Errorf("got status %d, %v, expected a failed fetch", status, err)
+	}
+}
+
+func TestGetMetricsSourceReplay(t *testing.T) {
+	oldReplay := cmdlineFlags.replay
+	t.Cleanup(func() {
+		cmdlineFlags.replay = oldReplay
+	})
+	cmdlineFlags.replay = writeMetricsSnapshots(t, map[string]string{
+		"metrics.prom": "cardano_node_metrics_blockNum_int 1\n",
+	})
+	// Replaying takes priority over a metrics file
+	t.Setenv(metricsFileEnv, filepath.Join("testdata", "metrics.prom"))
+	source, err := getMetricsSource()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := source.(*replayMetricsSource); !ok {
+		t.Errorf("got %T, expected a replay metrics source", source)
+	}
+	cmdlineFlags.replay = ""
+	t.Setenv(metricsFileEnv, "")
+	source, err = getMetricsSource()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := source.(*httpMetricsSource); !ok {
+		t.Errorf("got %T, expected an HTTP metrics source", source)
+	}
+}
@@ -17,10 +17,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
-
-	"github.com/shirou/gopsutil/v3/process"
+	"time"
 
 	"github.com/blinklabs-io/nview/internal/config"
 )
@@ -43,37 +46,253 @@ func setRole() {
 	}
 }
 
-func getP2P(ctx context.Context, processMetrics *process.Process) bool {
+func getP2P(ctx context.Context, processMetrics nodeProcess) bool {
+	if processMetrics == nil {
+		return p2p
+	}
+	args, err := processMetrics.Cmdline(ctx)
+	if err != nil {
+		return p2p
+	}
+	// Older nodes used a separate p2p config file
+	if strings.Contains(strings.Join(args, " "), "p2p") {
+		p2p = true
+		return p2p
+	}
+	nodeConfigFile := getCmdlineArg(args, "--config")
+	if nodeConfigFile == "" {
+		return p2p
+	}
+	nc, err := readNodeConfigFile(nodeConfigFile)
+	if err != nil {
+		p2p = false
+	} else if nc.EnableP2P != nil {
+		p2p = *nc.EnableP2P
+	}
+	return p2p
+}
+
+// Track the node implementation and binary detected from the running process
+var (
+	detectedNodeName   string
+	detectedNodeBinary string
+)
+
+// The configured binary name which matched the node process, when it was found
+// by name
+var matchedNodeBinary string
+
+// Detects the node implementation from the running process name
+func detectNodeType(ctx context.Context, processMetrics nodeProcess) {
+	name, err := processMetrics.Name(ctx)
+	if err != nil {
+		return
+	}
+	detectedNodeName = getNodeTypeName(name)
+	detectedNodeBinary = name
+	slog.Debug(
+		"detected node process",
+		"name", name,
+		"type", detectedNodeName,
+		"matchedBinary", matchedNodeBinary,
+	)
+}
+
+// Returns the binary names to match against process names when finding the
+// node process
+func getNodeBinaries() []string {
 	cfg := config.GetConfig()
-	if cfg.Node.Network == "mainnet" {
-		if processMetrics == nil {
-			return p2p
+	if len(cfg.Node.Binaries) > 0 {
+		return cfg.Node.Binaries
+	}
+	return []string{cfg.Node.Binary}
+}
+
+// Returns the first binary name contained in a process name, or an empty
+// string when none match
+func matchNodeBinary(name string, binaries []string) string {
+	for _, binary := range binaries {
+		if binary != "" && strings.Contains(name, binary) {
+			return binary
+		}
+	}
+	return ""
+}
+
+// Returns the node binary to run for version info
+//
+// An explicitly configured binary is always used as-is, with the detected
+// binary only replacing the default.
+func getEffectiveNodeBinary() string {
+	cfg := config.GetConfig()
+	if cfg.NodeBinarySet() || detectedNodeBinary == "" {
+		return cfg.Node.Binary
+	}
+	return detectedNodeBinary
+}
+
+// Returns the display name for a node implementation from its binary name
+func getNodeTypeName(binary string) string {
+	switch {
+	case strings.Contains(binary, "dingo"):
+		return "Dingo"
+	case strings.Contains(binary, "amaru"):
+		return "Amaru"
+	default:
+		return config.DefaultNodeName
+	}
+}
+
+// Returns the node name to display
+//
+// In auto mode, this is always the detected node implementation. In manual
+// mode, the configured name is used unless it's the default.
+func getEffectiveNodeName() string {
+	cfg := config.GetConfig()
+	if detectedNodeName == "" {
+		return cfg.App.NodeName
+	}
+	if strings.ToLower(cfg.App.NodeNameMode) == "auto" ||
+		cfg.App.NodeName == config.DefaultNodeName {
+		return detectedNodeName
+	}
+	return cfg.App.NodeName
+}
+
+// Finds the node process to monitor, which is replaced in tests
+var findNodeProcess = getProcessMetrics
+
+// Monitors a node process, filling unset config values from it, for both the
+// UI and headless modes
+func setNodeProcess(ctx context.Context, proc nodeProcess) {
+	processMetrics = proc
+	applyNodeConfig(ctx, proc)
+	processThreads = getProcessThreads(ctx, proc)
+}
+
+// Returns whether process and peer data are available from a local node,
+// which they aren't when replaying metrics or in remote mode
+func isLocalNode() bool {
+	return cmdlineFlags.replay == "" && !config.GetConfig().App.RemoteMode
+}
+
+// Polls for the local node process until the context is done, without ever
+// looking for one when the node isn't local
+func pollNodeProcess(ctx context.Context) {
+	for isLocalNode() {
+		proc, err := findNodeProcess(ctx)
+		if err != nil {
+			slog.Debug("failed to get node process", "error", err)
+			failCount++
+		} else {
+			setNodeProcess(ctx, proc)
 		}
-		cmd, err := processMetrics.CmdlineWithContext(ctx)
+		if !sleepWithContext(ctx, time.Second*1) {
+			return
+		}
+	}
+}
+
+// Track whether we've applied values from the running node
+var nodeConfigApplied bool = false
+
+// Fills unset config values from the running node's cmdline and config file
+func applyNodeConfig(ctx context.Context, processMetrics nodeProcess) {
+	if nodeConfigApplied || processMetrics == nil ||
+		processMetrics.Pid() == 0 {
+		return
+	}
+	args, err := processMetrics.Cmdline(ctx)
+	if err != nil {
+		return
+	}
+	nodeConfigApplied = true
+	detectNodeType(ctx, processMetrics)
+	p2p = getP2P(ctx, processMetrics)
+	var port uint32
+	if tmpPort := getCmdlineArg(args, "--port"); tmpPort != "" {
+		p, err := strconv.ParseUint(tmpPort, 10, 16)
 		if err == nil {
-			if !strings.Contains(cmd, "p2p") &&
-				strings.Contains(cmd, "--config") {
-				cmdArray := strings.Split(cmd, " ")
-				for p, arg := range cmdArray {
-					if arg == "--config" {
-						nodeConfigFile := cmdArray[p+1]
-						buf, err := os.ReadFile(nodeConfigFile)
-						if err == nil {
-							type nodeConfig struct {
-								EnableP2P bool `json:"EnableP2P"`
-							}
-							var nc nodeConfig
-							err = json.Unmarshal(buf, &nc)
-							if err != nil {
-								p2p = false
-							} else {
-								p2p = nc.EnableP2P
-							}
-						}
-					}
-				}
-			}
+			port = uint32(p)
 		}
 	}
-	return p2p
+	var networkMagic uint32
+	if nodeConfigFile := getCmdlineArg(args, "--config"); nodeConfigFile != "" {
+		nc, err := readNodeConfigFile(nodeConfigFile)
+		if err == nil {
+			networkMagic, _ = nc.getNetworkMagic(nodeConfigFile)
+		}
+	}
+	if err := config.ApplyNodeConfig(port, networkMagic); err != nil {
+		slog.Warn("failed to apply node config", "error", err)
+	}
+}
+
+// Returns the value of the given argument from a cmdline, supporting both
+// "--arg value" and "--arg=value" forms and quoted values
+func getCmdlineArg(args []string, name string) string {
+	for p, arg := range args {
+		var value string
+		if arg == name && p+1 < len(args) {
+			value = args[p+1]
+		} else if strings.HasPrefix(arg, name+"=") {
+			value = strings.TrimPrefix(arg, name+"=")
+		} else {
+			continue
+		}
+		if len(value) >= 2 &&
+			(value[0] == '"' || value[0] == '\'') &&
+			value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value
+	}
+	return ""
+}
+
+// Subset of the cardano-node config file which we use
+type nodeConfigFile struct {
+	EnableP2P          *bool  `json:"EnableP2P"`
+	ShelleyGenesisFile string `json:"ShelleyGenesisFile"`
+}
+
+// Reads a cardano-node config file
+func readNodeConfigFile(path string) (*nodeConfigFile, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseNodeConfigFile(buf)
+}
+
+// Parses the JSON contents of a cardano-node config file
+func parseNodeConfigFile(buf []byte) (*nodeConfigFile, error) {
+	var nc nodeConfigFile
+	if err := json.Unmarshal(buf, &nc); err != nil {
+		return nil, err
+	}
+	return &nc, nil
+}
+
+// Reads the network magic from the Shelley genesis file, which is relative to
+// the node config file unless an absolute path
+func (nc *nodeConfigFile) getNetworkMagic(path string) (uint32, error) {
+	if nc.ShelleyGenesisFile == "" {
+		return 0, fmt.Errorf("no shelley genesis file in node config")
+	}
+	genesisFile := nc.ShelleyGenesisFile
+	if !filepath.IsAbs(genesisFile) {
+		genesisFile = filepath.Join(filepath.Dir(path), genesisFile)
+	}
+	buf, err := os.ReadFile(genesisFile)
+	if err != nil {
+		return 0, err
+	}
+	var genesis struct {
+		NetworkMagic uint32 `json:"networkMagic"`
+	}
+	if err := json.Unmarshal(buf, &genesis); err != nil {
+		return 0, err
+	}
+	return genesis.NetworkMagic, nil
 }
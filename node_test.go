@@ -0,0 +1,579 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+func TestMatchNodeBinary(t *testing.T) {
+	binaries := []string{"cardano-node", "dingo", "amaru"}
+	testDefs := []struct {
+		name     string
+		binaries []string
+		expected string
+	}{
+		{name: "cardano-node", binaries: binaries, expected: "cardano-node"},
+		{name: "dingo", binaries: binaries, expected: "dingo"},
+		{name: "amaru", binaries: binaries, expected: "amaru"},
+		// Process names may include a path or suffix
+		{
+			name:     "/usr/local/bin/cardano-node",
+			binaries: binaries,
+			expected: "cardano-node",
+		},
+		{name: "cardano-node-9.2", binaries: binaries, expected: "cardano-node"},
+		// The first matching binary in the list wins
+		{
+			name:     "dingo-cardano-node",
+			binaries: binaries,
+			expected: "cardano-node",
+		},
+		{
+			name:     "dingo-cardano-node",
+			binaries: []string{"dingo", "cardano-node"},
+			expected: "dingo",
+		},
+		{name: "bash", binaries: binaries, expected: ""},
+		{name: "", binaries: binaries, expected: ""},
+		// Empty binary names never match
+		{name: "cardano-node", binaries: []string{""}, expected: ""},
+		{name: "cardano-node", binaries: nil, expected: ""},
+	}
+	for _, testDef := range testDefs {
+		got := matchNodeBinary(testDef.name, testDef.binaries)
+		if got != testDef.expected {
+			t.Errorf(
+				"%q with %v: got %q, expected %q",
+				testDef.name,
+				testDef.binaries,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+// Writes a sample node config and Shelley genesis file, returning the node
+// config path
+func writeNodeConfigFixture(t *testing.T, genesisFile string) string {
+	t.Helper()
+	dir := t.TempDir()
+	nodeConfig := `{
+    "EnableP2P": true,
+    "Protocol": "Cardano",
+    "RequiresNetworkMagic": "RequiresMagic",
+    "ShelleyGenesisFile": "` + genesisFile + `",
+    "TraceBlockFetchClient": false
+}`
+	genesis := `{
+    "activeSlotsCoeff": 0.05,
+    "epochLength": 86400,
+    "networkId": "Testnet",
+    "networkMagic": 2,
+    "slotLength": 1
+}`
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(nodeConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(
+		filepath.Join(dir, "shelley-genesis.json"),
+		[]byte(genesis),
+		0o644,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseNodeConfigFile(t *testing.T) {
+	enabled, disabled := true, false
+	testDefs := []struct {
+		config    string
+		enableP2P *bool
+		genesis   string
+		err       bool
+	}{
+		{
+			config:    `{"EnableP2P": true, "ShelleyGenesisFile": "shelley.json"}`,
+			enableP2P: &enabled,
+			genesis:   "shelley.json",
+		},
+		{
+			config:    `{"EnableP2P": false}`,
+			enableP2P: &disabled,
+		},
+		// Older node configs have no EnableP2P setting
+		{config: `{"Protocol": "Cardano"}`},
+		{config: `not json`, err: true},
+	}
+	for _, testDef := range testDefs {
+		nc, err := parseNodeConfigFile([]byte(testDef.config))
+		if testDef.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", testDef.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", testDef.config, err)
+			continue
+		}
+		if (nc.EnableP2P == nil) != (testDef.enableP2P == nil) ||
+			(nc.EnableP2P != nil && *nc.EnableP2P != *testDef.enableP2P) {
+			t.Errorf(
+				"%s: got EnableP2P %v, expected %v",
+				testDef.config,
+				nc.EnableP2P,
+				testDef.enableP2P,
+			)
+		}
+		if nc.ShelleyGenesisFile != testDef.genesis {
+			t.Errorf(
+				"%s: got genesis file %q, expected %q",
+				testDef.config,
+				nc.ShelleyGenesisFile,
+				testDef.genesis,
+			)
+		}
+	}
+}
+
+func TestGetNetworkMagic(t *testing.T) {
+	// Relative to the node config file
+	path := writeNodeConfigFixture(t, "shelley-genesis.json")
+	nc, err := readNodeConfigFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	magic, err := nc.getNetworkMagic(path)
+	if err != nil || magic != 2 {
+		t.Errorf("got %d (error %v), expected %d", magic, err, 2)
+	}
+	// Absolute
+	nc.ShelleyGenesisFile = filepath.Join(
+		filepath.Dir(path),
+		"shelley-genesis.json",
+	)
+	magic, err = nc.getNetworkMagic("/somewhere/else/config.json")
+	if err != nil || magic != 2 {
+		t.Errorf("got %d (error %v), expected %d", magic, err, 2)
+	}
+	// Missing
+	nc.ShelleyGenesisFile = "missing.json"
+	if _, err := nc.getNetworkMagic(path); err == nil {
+		t.Errorf("expected an error for a missing genesis file")
+	}
+	nc.ShelleyGenesisFile = ""
+	if _, err := nc.getNetworkMagic(path); err == nil {
+		t.Errorf("expected an error without a genesis file")
+	}
+}
+
+// The port and network magic are read from the running node's cmdline and
+// config file
+func TestApplyNodeConfigFromProcess(t *testing.T) {
+	cfg := config.GetConfig()
+	oldCfg := *cfg
+	oldP2P := p2p
+	t.Cleanup(func() {
+		*config.GetConfig() = oldCfg
+		p2p = oldP2P
+		nodeConfigApplied = false
+		detectedNodeName, detectedNodeBinary = "", ""
+	})
+	nodeConfigApplied = false
+	path := writeNodeConfigFixture(t, "shelley-genesis.json")
+	proc := &fakeProcess{
+		pid:  1234,
+		name: "cardano-node",
+		cmdline: []string{
+			"cardano-node",
+			"run",
+			"--config=" + path,
+			"--port",
+			"6000",
+		},
+	}
+	applyNodeConfig(context.Background(), proc)
+	node := config.GetConfig().Node
+	if node.Port != 6000 {
+		t.Errorf("got port %d, expected %d", node.Port, 6000)
+	}
+	if node.NetworkMagic != 2 || node.Network != "preview" {
+		t.Errorf(
+			"got network %s (%d), expected preview (2)",
+			node.Network,
+			node.NetworkMagic,
+		)
+	}
+	if node.ShelleyGenesis.EpochLength != 86400 {
+		t.Errorf(
+			"got epoch length %d, expected %d",
+			node.ShelleyGenesis.EpochLength,
+			86400,
+		)
+	}
+	if !p2p {
+		t.Errorf("expected P2P from the node config")
+	}
+}
+
+func TestGetCmdlineArg(t *testing.T) {
+	testDefs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"cardano-node", "run", "--config", "config.json"},
+			expected: "config.json",
+		},
+		{
+			args:     []string{"cardano-node", "run", "--config=config.json"},
+			expected: "config.json",
+		},
+		// Paths with spaces are a single argument, and may be quoted
+		{
+			args:     []string{"cardano-node", "--config", "/opt/my node/c.json"},
+			expected: "/opt/my node/c.json",
+		},
+		{
+			args:     []string{"cardano-node", `--config="/opt/my node/c.json"`},
+			expected: "/opt/my node/c.json",
+		},
+		{
+			args:     []string{"cardano-node", "--config", "'c.json'"},
+			expected: "c.json",
+		},
+		// Similar argument names don't match
+		{
+			args:     []string{"cardano-node", "--config-dir", "/opt"},
+			expected: "",
+		},
+		// A trailing argument has no value
+		{args: []string{"cardano-node", "--config"}, expected: ""},
+		{args: nil, expected: ""},
+	}
+	for _, testDef := range testDefs {
+		got := getCmdlineArg(testDef.args, "--config")
+		if got != testDef.expected {
+			t.Errorf(
+				"%q: got %q, expected %q",
+				testDef.args,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetP2P(t *testing.T) {
+	oldP2P := p2p
+	t.Cleanup(func() {
+		p2p = oldP2P
+	})
+	dir := filepath.Join(t.TempDir(), "preview node")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	enabled := writeConfig("enabled.json", `{"EnableP2P": true}`)
+	disabled := writeConfig("disabled.json", `{"EnableP2P": false}`)
+	unset := writeConfig("unset.json", `{"Protocol": "Cardano"}`)
+	testDefs := []struct {
+		args     []string
+		previous bool
+		expected bool
+	}{
+		{args: []string{"cardano-node", "--config", enabled}, expected: true},
+		{args: []string{"cardano-node", "--config=" + enabled}, expected: true},
+		{
+			args:     []string{"cardano-node", "--config", disabled},
+			previous: true,
+			expected: false,
+		},
+		// An unset EnableP2P keeps the last result
+		{
+			args:     []string{"cardano-node", "--config", unset},
+			previous: true,
+			expected: true,
+		},
+		// An unreadable config is treated as legacy networking
+		{
+			args:     []string{"cardano-node", "--config", dir + "/missing.json"},
+			previous: true,
+			expected: false,
+		},
+		// Older nodes used a separate p2p config file
+		{
+			args:     []string{"cardano-node", "--config", "p2p-config.json"},
+			expected: true,
+		},
+		// Without a config there's nothing to detect
+		{args: []string{"cardano-node", "run"}, previous: true, expected: true},
+	}
+	for _, testDef := range testDefs {
+		p2p = testDef.previous
+		got := getP2P(
+			context.Background(),
+			&fakeProcess{cmdline: testDef.args},
+		)
+		if got != testDef.expected {
+			t.Errorf(
+				"%q: got %v, expected %v",
+				testDef.args,
+				got,
+				testDef.expected,
+			)
+		}
+		if p2p != got {
+			t.Errorf("%q: expected the result to be kept", testDef.args)
+		}
+	}
+	// Without a node process, the last result is kept
+	p2p = true
+	if !getP2P(context.Background(), nil) {
+		t.Errorf("expected the last result without a node process")
+	}
+}
+
+func TestGetEffectiveNodeName(t *testing.T) {
+	cfg := config.GetConfig()
+	oldMode := cfg.App.NodeNameMode
+	oldNodeName := cfg.App.NodeName
+	oldDetectedName := detectedNodeName
+	oldDetectedBinary := detectedNodeBinary
+	t.Cleanup(func() {
+		cfg.App.NodeNameMode = oldMode
+		cfg.App.NodeName = oldNodeName
+		detectedNodeName = oldDetectedName
+		detectedNodeBinary = oldDetectedBinary
+	})
+	testDefs := []struct {
+		mode     string
+		nodeName string
+		process  string
+		expected string
+	}{
+		// Manual mode brands the default name with the detected node
+		{
+			mode:     "manual",
+			nodeName: config.DefaultNodeName,
+			process:  "dingo",
+			expected: "Dingo",
+		},
+		{
+			mode:     "manual",
+			nodeName: config.DefaultNodeName,
+			process:  "amaru",
+			expected: "Amaru",
+		},
+		{
+			mode:     "manual",
+			nodeName: config.DefaultNodeName,
+			process:  "cardano-node",
+			expected: config.DefaultNodeName,
+		},
+		// An explicit name is kept in manual mode
+		{
+			mode:     "manual",
+			nodeName: "Relay 1",
+			process:  "dingo",
+			expected: "Relay 1",
+		},
+		// Auto mode always uses the detected node
+		{mode: "auto", nodeName: "Relay 1", process: "dingo", expected: "Dingo"},
+		{mode: "AUTO", nodeName: "Relay 1", process: "amaru", expected: "Amaru"},
+		{
+			mode:     "auto",
+			nodeName: "Relay 1",
+			process:  "/usr/local/bin/cardano-node",
+			expected: config.DefaultNodeName,
+		},
+		// Without a node process, the configured name is used
+		{mode: "auto", nodeName: "Relay 1", expected: "Relay 1"},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.NodeNameMode = testDef.mode
+		cfg.App.NodeName = testDef.nodeName
+		detectedNodeName = ""
+		if testDef.process != "" {
+			detectNodeType(
+				context.Background(),
+				&fakeProcess{name: testDef.process},
+			)
+		}
+		if got := getEffectiveNodeName(); got != testDef.expected {
+			t.Errorf(
+				"%s mode, %q with %q: got %q, expected %q",
+				testDef.mode,
+				testDef.nodeName,
+				testDef.process,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+// Loads the config with the given node binary, or the default when empty
+func setNodeBinary(t *testing.T, binary string) {
+	t.Helper()
+	cfg := config.GetConfig()
+	oldCfg := *cfg
+	oldDetectedBinary := detectedNodeBinary
+	t.Cleanup(func() {
+		*cfg = oldCfg
+		detectedNodeBinary = oldDetectedBinary
+	})
+	cfg.Node.Binary = ""
+	t.Setenv("CARDANO_NODE_BINARY", binary)
+	if _, err := config.LoadConfig(""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestGetEffectiveNodeBinary(t *testing.T) {
+	testDefs := []struct {
+		binary   string
+		detected string
+		expected string
+	}{
+		// The default binary is replaced by the detected one
+		{binary: "", detected: "", expected: config.DefaultNodeBinary},
+		{binary: "", detected: "dingo", expected: "dingo"},
+		// An explicitly configured binary always wins
+		{
+			binary:   "/opt/cardano/bin/cardano-node",
+			detected: "dingo",
+			expected: "/opt/cardano/bin/cardano-node",
+		},
+		{binary: "cardano-node", detected: "amaru", expected: "cardano-node"},
+		{binary: "amaru", detected: "", expected: "amaru"},
+	}
+	for _, testDef := range testDefs {
+		setNodeBinary(t, testDef.binary)
+		detectedNodeBinary = ""
+		if testDef.detected != "" {
+			detectNodeType(
+				context.Background(),
+				&fakeProcess{name: testDef.detected},
+			)
+		}
+		if got := getEffectiveNodeBinary(); got != testDef.expected {
+			t.Errorf(
+				"%q with %q detected: got %q, expected %q",
+				testDef.binary,
+				testDef.detected,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestExecNodeVersionBinary(t *testing.T) {
+	// A configured binary is run even when another node process is detected
+	binary := filepath.Join(t.TempDir(), "node-version")
+	script := "#!/bin/sh\necho 'cardano-node 10.1.4 - linux-x86_64'\n" +
+		"echo 'git rev 1f63dbf2'\n"
+	if err := os.WriteFile(binary, []byte(script), 0o700); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	setNodeBinary(t, binary)
+	detectNodeType(context.Background(), &fakeProcess{name: "dingo"})
+	version, revision, err := execNodeVersion()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if version != "10.1.4" || revision != "1f63dbf2" {
+		t.Errorf(
+			"got %s %s, expected 10.1.4 1f63dbf2",
+			version,
+			revision,
+		)
+	}
+	// Without a configured binary, the detected one is run instead
+	setNodeBinary(t, "")
+	detectNodeType(context.Background(), &fakeProcess{name: binary})
+	if version, _, err = execNodeVersion(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if version != "10.1.4" {
+		t.Errorf("got %s, expected 10.1.4", version)
+	}
+}
+
+func TestPollNodeProcess(t *testing.T) {
+	cfg := config.GetConfig()
+	oldRemoteMode := cfg.App.RemoteMode
+	oldReplay := cmdlineFlags.replay
+	oldFindNodeProcess := findNodeProcess
+	oldFailCount := failCount
+	t.Cleanup(func() {
+		cfg.App.RemoteMode = oldRemoteMode
+		cmdlineFlags.replay = oldReplay
+		findNodeProcess = oldFindNodeProcess
+		failCount = oldFailCount
+	})
+	var calls int
+	findNodeProcess = func(ctx context.Context) (nodeProcess, error) {
+		calls++
+		return nil, os.ErrNotExist
+	}
+	// A cancelled context stops polling after the first lookup
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testDefs := []struct {
+		remoteMode bool
+		replay     string
+		expected   int
+	}{
+		{expected: 1},
+		// Neither a remote node nor replayed metrics have a local process
+		{remoteMode: true, expected: 0},
+		{replay: "metrics", expected: 0},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.RemoteMode = testDef.remoteMode
+		cmdlineFlags.replay = testDef.replay
+		calls = 0
+		failCount = 0
+		pollNodeProcess(ctx)
+		if calls != testDef.expected || failCount != uint32(calls) {
+			t.Errorf(
+				"remote %v, replay %q: got %d lookups (%d failures), expected %d",
+				testDef.remoteMode,
+				testDef.replay,
+				calls,
+				failCount,
+				testDef.expected,
+			)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// How long a peer analysis result is reused before the peer is checked again
+var peerCacheTTL = 600 * time.Second
+
+// Returns the checked peer results which were updated since the expiry time
+func filterFreshPeers(peers []*Peer, expire time.Time) []*Peer {
+	var ret []*Peer
+	for _, peer := range peers {
+		if peer != nil && peer.IP != "" && peer.RTT != 0 &&
+			peer.UpdatedAt.After(expire) {
+			ret = append(ret, peer)
+		}
+	}
+	return ret
+}
+
+// Loads saved peer analysis results from the configured file, if any,
+// skipping results older than the cache TTL
+func loadPeerCache() {
+	cfg := config.GetConfig()
+	peerCacheTTL = time.Duration(cfg.App.PeerCacheTTL) * time.Second
+	if cfg.App.PeerCacheFile == "" {
+		return
+	}
+	buf, err := os.ReadFile(cfg.App.PeerCacheFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("failed to read peer cache", "error", err)
+		}
+		return
+	}
+	var peers []*Peer
+	if err := json.Unmarshal(buf, &peers); err != nil {
+		slog.Warn("failed to parse peer cache", "error", err)
+		return
+	}
+	for _, peer := range filterFreshPeers(
+		peers,
+		time.Now().Add(-peerCacheTTL),
+	) {
+		peerStats.RTTresultsMap[peer.IP] = peer
+	}
+}
+
+// Saves the peer analysis results to the configured file, if any
+func savePeerCache() {
+	path := config.GetConfig().App.PeerCacheFile
+	if path == "" {
+		return
+	}
+	peers := make([]*Peer, 0, len(peerStats.RTTresultsMap))
+	for _, peer := range peerStats.RTTresultsMap {
+		peers = append(peers, peer)
+	}
+	buf, err := json.Marshal(peers)
+	if err != nil {
+		slog.Warn("failed to encode peer cache", "error", err)
+		return
+	}
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		slog.Warn("failed to write peer cache", "error", err)
+	}
+}
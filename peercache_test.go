@@ -0,0 +1,180 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// Sets a peer cache file and TTL, with empty peer results
+func setPeerCacheFixture(t *testing.T, ttl uint32) string {
+	t.Helper()
+	cfg := config.GetConfig()
+	oldPath, oldTTL := cfg.App.PeerCacheFile, cfg.App.PeerCacheTTL
+	oldCacheTTL := peerCacheTTL
+	t.Cleanup(func() {
+		cfg.App.PeerCacheFile, cfg.App.PeerCacheTTL = oldPath, oldTTL
+		peerCacheTTL = oldCacheTTL
+		peerStats = PeerStats{}
+	})
+	cfg.App.PeerCacheFile = filepath.Join(t.TempDir(), "peers.json")
+	cfg.App.PeerCacheTTL = ttl
+	peerStats = PeerStats{RTTresultsMap: make(peerRTTresultsMap)}
+	return cfg.App.PeerCacheFile
+}
+
+// Returns the IPs of the peer results, in order
+func getPeerCacheIPs() []string {
+	var ips []string
+	for ip := range peerStats.RTTresultsMap {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func TestFilterFreshPeers(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	expire := now.Add(-10 * time.Minute)
+	peers := []*Peer{
+		{IP: "192.0.2.1", RTT: 25, UpdatedAt: now.Add(-time.Minute)},
+		// Unreachable peers are cached too
+		{IP: "192.0.2.2", RTT: 99999, UpdatedAt: now.Add(-time.Minute)},
+		// Expired, never measured, or invalid results are skipped
+		{IP: "192.0.2.3", RTT: 25, UpdatedAt: expire},
+		{IP: "192.0.2.4", RTT: 0, UpdatedAt: now},
+		{IP: "", RTT: 25, UpdatedAt: now},
+		nil,
+	}
+	var got []string
+	for _, peer := range filterFreshPeers(peers, expire) {
+		got = append(got, peer.IP)
+	}
+	expected := []string{"192.0.2.1", "192.0.2.2"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestPeerCacheRoundTrip(t *testing.T) {
+	setPeerCacheFixture(t, 600)
+	now := time.Now().UTC().Truncate(time.Second)
+	fresh := &Peer{
+		Direction: "o",
+		IP:        "192.0.2.1",
+		RTT:       25,
+		Port:      3001,
+		Location:  "Amsterdam, NL",
+		Pool:      "BLINK",
+		Hostname:  "relay1.example.com",
+		Distance:  357.4,
+		Checks:    3,
+		Failures:  1,
+		LastError: "i/o timeout",
+		FirstSeen: now.Add(-time.Hour),
+		UpdatedAt: now.Add(-time.Minute),
+	}
+	peerStats.RTTresultsMap[fresh.IP] = fresh
+	peerStats.RTTresultsMap["192.0.2.2"] = &Peer{
+		IP:        "192.0.2.2",
+		RTT:       80,
+		UpdatedAt: now.Add(-20 * time.Minute),
+	}
+	savePeerCache()
+	peerStats.RTTresultsMap = make(peerRTTresultsMap)
+	loadPeerCache()
+	// Only results within the TTL are loaded
+	if got := getPeerCacheIPs(); !reflect.DeepEqual(got, []string{"192.0.2.1"}) {
+		t.Fatalf("got %v, expected only the fresh peer", got)
+	}
+	if got := peerStats.RTTresultsMap[fresh.IP]; !reflect.DeepEqual(got, fresh) {
+		t.Errorf("got %+v, expected %+v", got, fresh)
+	}
+}
+
+func TestLoadPeerCacheTTL(t *testing.T) {
+	setPeerCacheFixture(t, 60)
+	now := time.Now().UTC()
+	peerStats.RTTresultsMap = peerRTTresultsMap{
+		"192.0.2.1": {IP: "192.0.2.1", RTT: 25, UpdatedAt: now},
+		"192.0.2.2": {
+			IP:        "192.0.2.2",
+			RTT:       25,
+			UpdatedAt: now.Add(-2 * time.Minute),
+		},
+	}
+	savePeerCache()
+	peerStats.RTTresultsMap = make(peerRTTresultsMap)
+	loadPeerCache()
+	// The configured TTL applies to loaded and checked peers alike
+	if peerCacheTTL != time.Minute {
+		t.Errorf("got TTL %s, expected 1m0s", peerCacheTTL)
+	}
+	if got := getPeerCacheIPs(); !reflect.DeepEqual(got, []string{"192.0.2.1"}) {
+		t.Errorf("got %v, expected only the peer within the TTL", got)
+	}
+}
+
+func TestLoadPeerCacheInvalid(t *testing.T) {
+	testDefs := []struct {
+		name    string
+		content string
+		warning string
+	}{
+		// A missing file is expected on the first run
+		{name: "missing"},
+		{
+			name:    "corrupt",
+			content: "[{",
+			warning: "failed to parse peer cache",
+		},
+	}
+	for _, testDef := range testDefs {
+		path := setPeerCacheFixture(t, 600)
+		logs := setLogCapture(t)
+		if testDef.content != "" {
+			err := os.WriteFile(path, []byte(testDef.content), 0o600)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+		loadPeerCache()
+		if len(peerStats.RTTresultsMap) != 0 {
+			t.Errorf(
+				"%s: got %v, expected no peers",
+				testDef.name,
+				getPeerCacheIPs(),
+			)
+		}
+		warned := logs.String()
+		if testDef.warning == "" && warned != "" ||
+			!strings.Contains(warned, testDef.warning) {
+			t.Errorf(
+				"%s: got logs %q, expected %q",
+				testDef.name,
+				warned,
+				testDef.warning,
+			)
+		}
+	}
+}
@@ -16,11 +16,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	netutil "github.com/shirou/gopsutil/v3/net"
@@ -45,11 +49,7 @@ func filterPeers(ctx context.Context) error {
 	cfg := config.GetConfig()
 
 	// Get process in/out connections
-	connections, err := netutil.ConnectionsPidWithContext(
-		ctx,
-		"tcp",
-		processMetrics.Pid,
-	)
+	connections, err := processMetrics.Connections(ctx, "tcp")
 	if err != nil {
 		return err
 	}
@@ -64,15 +64,20 @@ func filterPeers(ctx context.Context) error {
 			if c.Laddr.Port == cfg.Node.Port {
 				peersIn = append(
 					peersIn,
-					fmt.Sprintf("%s:%d", c.Raddr.IP, c.Raddr.Port),
+					net.JoinHostPort(
+						c.Raddr.IP,
+						strconv.FormatUint(uint64(c.Raddr.Port), 10),
+					),
 				)
 			}
-			// If local port != node port, ekg port, or prometheus port, it's outgoing
-			if c.Laddr.Port != cfg.Node.Port && c.Laddr.Port != uint32(12788) &&
-				c.Laddr.Port != cfg.Prometheus.Port {
+			// If local port isn't one of the node's listeners, it's outgoing
+			if !isNodeListenerPort(c.Laddr.Port) {
 				peersOut = append(
 					peersOut,
-					fmt.Sprintf("%s:%d", c.Raddr.IP, c.Raddr.Port),
+					net.JoinHostPort(
+						c.Raddr.IP,
+						strconv.FormatUint(uint64(c.Raddr.Port), 10),
+					),
 				)
 			}
 		}
@@ -84,170 +89,248 @@ func filterPeers(ctx context.Context) error {
 		return nil
 	}
 
-	// Process peersIn
+	// Process peersIn, then peersOut, marking peers in both as duplex
 	for _, peer := range peersIn {
-		p := strings.Split(peer, ":")
-		if p == nil {
+		peers = addFilteredPeer(peers, peer, "i")
+	}
+	for _, peer := range peersOut {
+		peers = addFilteredPeer(peers, peer, "o")
+	}
+	// TODO: do this better than just a length check
+	if len(peers) != len(peersFiltered) {
+		peersFiltered = peers
+		peersDirty.Store(true)
+	}
+	return nil
+}
+
+// Adds a remote address to a list of filtered peers in "ip;port;direction"
+// form, skipping ourselves and merging directions for peers we've already
+// seen. IPv6 addresses are stored without brackets.
+func addFilteredPeer(peers []string, address string, direction string) []string {
+	cfg := config.GetConfig()
+	peerIP, peerPORT, err := net.SplitHostPort(address)
+	if err != nil {
+		return peers
+	}
+	ip := net.ParseIP(peerIP)
+	if ip == nil || ip.IsLoopback() {
+		return peers
+	}
+	// Normalize the address, which also unmaps IPv4-mapped IPv6 addresses
+	peerIP = ip.String()
+	if publicIP != nil && ip.Equal(*publicIP) &&
+		peerPORT == strconv.FormatUint(uint64(cfg.Node.Port), 10) {
+		return peers
+	}
+	for i, toCheck := range peers {
+		checkArr := strings.Split(toCheck, ";")
+		if len(checkArr) < 3 || checkArr[0] != peerIP {
 			continue
 		}
-		peerIP := p[0]
-		peerPORT := p[1]
-		if strings.HasPrefix(peerIP, "[") { // IPv6
-			peerIP = strings.TrimPrefix(strings.TrimSuffix(peerIP, "]"), "[")
+		if checkArr[2] != direction {
+			// Remove and re-add as duplex (i+o)
+			peers = append(peers[:i], peers[i+1:]...)
+			peers = append(peers, fmt.Sprintf("%s;%s;i+o", peerIP, peerPORT))
 		}
+		return peers
+	}
+	return append(peers, fmt.Sprintf("%s;%s;%s", peerIP, peerPORT, direction))
+}
 
-		if peerIP == "127.0.0.1" ||
-			(publicIP != nil && peerIP == publicIP.String() && peerPORT == strconv.FormatUint(uint64(cfg.Node.Port), 10)) {
-			// Do nothing
-			continue
-		} else {
-			added := false
-			for i, toCheck := range peers {
-				checkIPArr := strings.Split(toCheck, ":")
-				if checkIPArr == nil {
-					continue
-				}
-				checkIP := checkIPArr[0]
-				if checkIP == peerIP {
-					if p[2] != "i" {
-						// Remove and re-add as duplex (i+o)
-						peers = append(peers[:i], peers[i+1:]...)
-						peers = append(peers, fmt.Sprintf("%s;%s;i+o", peerIP, peerPORT))
-						added = true
-						break
-					}
-				}
-			}
-			if !added {
-				peers = append(peers, fmt.Sprintf("%s;%s;i", peerIP, peerPORT))
-			}
-		}
+// Checks whether a local port is one of the node's listeners (NtN, NtC, EKG,
+// or Prometheus)
+func isNodeListenerPort(port uint32) bool {
+	cfg := config.GetConfig()
+	switch port {
+	case cfg.Node.Port, uint32(12788), cfg.Prometheus.Port:
+		return true
 	}
+	return cfg.Node.N2CPort != 0 && port == cfg.Node.N2CPort
+}
 
-	// Process peersOut
-	for _, peer := range peersOut {
-		p := strings.Split(peer, ":")
-		if p == nil {
+// Counts local node-to-client connections on the node socket and optional
+// NtC TCP port
+func countLocalClients(
+	unixConns []netutil.ConnectionStat,
+	tcpConns []netutil.ConnectionStat,
+	socketPath string,
+	n2cPort uint32,
+) int {
+	var count int
+	var listening bool
+	for _, c := range unixConns {
+		if socketPath == "" || c.Laddr.IP != socketPath {
 			continue
 		}
-		peerIP := p[0]
-		peerPORT := p[1]
-		if strings.HasPrefix(peerIP, "[") { // IPv6
-			peerIP = strings.TrimPrefix(strings.TrimSuffix(peerIP, "]"), "[")
-		}
-
-		if peerIP == "127.0.0.1" ||
-			(publicIP != nil && peerIP == publicIP.String() && peerPORT == strconv.FormatUint(uint64(cfg.Node.Port), 10)) {
-			// Do nothing
+		// Accepted connections share the socket path with the listener,
+		// which we skip once
+		if !listening {
+			listening = true
 			continue
-		} else {
-			added := false
-			for i, toCheck := range peers {
-				checkIPArr := strings.Split(toCheck, ":")
-				if checkIPArr == nil {
-					continue
-				}
-				checkIP := checkIPArr[0]
-				if checkIP == peerIP {
-					if p[2] != "o" {
-						// Remove and re-add as duplex (i+o)
-						peers = append(peers[:i], peers[i+1:]...)
-						peers = append(peers, fmt.Sprintf("%s;%s;i+o", peerIP, peerPORT))
-						added = true
-						break
-					}
-				}
-			}
-			if !added {
-				peers = append(peers, fmt.Sprintf("%s;%s;o", peerIP, peerPORT))
+		}
+		count++
+	}
+	if n2cPort != 0 {
+		for _, c := range tcpConns {
+			if c.Status == "ESTABLISHED" && c.Laddr.Port == n2cPort {
+				count++
 			}
 		}
 	}
-	// TODO: do this better than just a length check
-	if len(peers) != len(peersFiltered) {
-		peersFiltered = peers
+	return count
+}
+
+// Returns the number of TCP connections in each state, such as ESTABLISHED or
+// TIME_WAIT
+func getConnectionStateCounts(
+	connections []netutil.ConnectionStat,
+) map[string]int {
+	counts := make(map[string]int)
+	for _, c := range connections {
+		counts[c.Status]++
+	}
+	return counts
+}
+
+// Track when the current peer analysis started
+var peerAnalysisStart time.Time
+
+// Returns the percentage complete, rate in peers per second, and estimated
+// time remaining for a peer analysis
+func getPeerAnalysisProgress(
+	done int,
+	total int,
+	elapsed time.Duration,
+) (float64, float64, time.Duration) {
+	if total <= 0 {
+		return 0, 0, 0
+	}
+	done = min(done, total)
+	pct := float64(done) / float64(total) * 100
+	if done == 0 || elapsed <= 0 {
+		return pct, 0, 0
+	}
+	rate := float64(done) / elapsed.Seconds()
+	eta := time.Duration(float64(total-done) / rate * float64(time.Second))
+	return pct, rate, eta.Round(time.Second)
+}
+
+// Returns the peer analysis progress line shown while checking peers
+func getPeerAnalysisText() string {
+	done := len(peerStats.RTTresultsSlice)
+	total := len(peersFiltered)
+	var elapsed time.Duration
+	if !peerAnalysisStart.IsZero() {
+		elapsed = timeNow().Sub(peerAnalysisStart)
+	}
+	pct, rate, eta := getPeerAnalysisProgress(done, total, elapsed)
+	ret := fmt.Sprintf(
+		" [yellow]%s [blue]%d[white]/[green]%d[white] ([blue]%.0f%%[white])",
+		"Peer analysis started... please wait!",
+		done,
+		total,
+		pct,
+	)
+	if rate > 0 {
+		ret += fmt.Sprintf(
+			" [blue]%.1f[white] peers/s, ETA [blue]%s[white]",
+			rate,
+			eta,
+		)
+	}
+	return ret + "\n"
+}
+
+// Context of the in-flight peer analysis, which is cancelled on reset
+var peerAnalysisCtx context.Context
+var peerAnalysisCancel context.CancelFunc
+var peerAnalysisMutex sync.Mutex
+
+// Returns the context for the current peer analysis, starting a new one if
+// needed
+func getPeerAnalysisContext(ctx context.Context) context.Context {
+	peerAnalysisMutex.Lock()
+	defer peerAnalysisMutex.Unlock()
+	if peerAnalysisCancel == nil {
+		peerAnalysisCtx, peerAnalysisCancel = context.WithCancel(ctx)
+	}
+	return peerAnalysisCtx
+}
+
+// Cancels the in-flight peer analysis, stopping any outstanding dials
+func cancelPeerAnalysis() {
+	peerAnalysisMutex.Lock()
+	defer peerAnalysisMutex.Unlock()
+	if peerAnalysisCancel != nil {
+		peerAnalysisCancel()
+		peerAnalysisCancel = nil
 	}
-	return nil
 }
 
 func pingPeers(ctx context.Context) error {
 	scrollPeers = false
-	var granularity int = 68
-	granularitySmall := granularity / 2
-	if checkPeers {
+	// Once the analysis is done, pinging a subset of peers each cycle keeps
+	// going to keep the RTT stats fresh
+	sampleSize := config.GetConfig().App.PeerPingSampleSize
+	refreshing := !checkPeers && sampleSize > 0 &&
+		len(peerStats.RTTresultsSlice) != 0
+	if checkPeers && peerAnalysisStart.IsZero() {
+		peerAnalysisStart = time.Now()
+	}
+	granularitySmall := getGranularity() / 2
+	if checkPeers || refreshing {
+		peerCtx := getPeerAnalysisContext(ctx)
+		enrichSem := make(
+			chan struct{},
+			config.GetConfig().App.PeerEnrichConcurrency,
+		)
 		// counters, etc.
 		peerCount := len(peersFiltered)
-		var peerRTT int
 		var wg sync.WaitGroup
-		for _, v := range peersFiltered {
+		// Only ping a subset of peers each cycle when configured, working
+		// through all of them over several cycles
+		var peersToPing []string
+		peersToPing, peerPingOffset = getPeerPingSubset(
+			peersFiltered,
+			peerPingOffset,
+			sampleSize,
+		)
+		results := make([]*Peer, len(peersToPing))
+		for i, v := range peersToPing {
+			// Stop checking peers on shutdown or when cancelled
+			if peerCtx.Err() != nil {
+				break
+			}
 			// increment waitgroup counter
 			wg.Add(1)
-			// Avoid re-use of v in all go-routines
+			// Avoid re-use of i and v in all go-routines
 			// https://go.dev/doc/faq#closures_and_goroutines
-			v := v
+			i, v := i, v
 
 			go func() {
 				defer wg.Done()
-				peerArr := strings.Split(v, ";")
-				if peerArr == nil {
-					return
-				}
-				peerIP := peerArr[0]
-				peerPORT := peerArr[1]
-				peerDIR := peerArr[2]
-
-				// Return early if we've been checked recently
-				now := time.Now()
-				expire := now.Add(-600 * time.Second)
-				existing, ok := peerStats.RTTresultsMap[peerIP]
-				if ok {
-					if existing.UpdatedAt.After(expire) && existing.RTT != 0 {
-						return
-					}
-					if existing.Location != "---" {
-						return
-					}
-				}
-
-				// Start RTT loop
-				// for tool in ... return peerRTT
-				peerRTT = tcpinfoRtt(fmt.Sprintf("%s:%s", peerIP, peerPORT))
-				if peerRTT != 99999 {
-					peerStats.RTTSUM = peerStats.RTTSUM + peerRTT
-				}
-				// Update counters
-				if peerRTT < 50 {
-					peerStats.CNT1 = peerStats.CNT1 + 1
-				} else if peerRTT < 100 {
-					peerStats.CNT2 = peerStats.CNT2 + 1
-				} else if peerRTT < 200 {
-					peerStats.CNT3 = peerStats.CNT3 + 1
-				} else if peerRTT < 99999 {
-					peerStats.CNT4 = peerStats.CNT4 + 1
-				} else {
-					peerStats.CNT0 = peerStats.CNT0 + 1
-				}
-				peerPort, err := strconv.Atoi(peerPORT)
-				if err != nil {
-					peerPort = 0
-				}
-				peerLocation := getGeoIP(ctx, peerIP)
-				peer := &Peer{
-					IP:        peerIP,
-					Port:      peerPort,
-					Direction: peerDIR,
-					RTT:       peerRTT,
-					Location:  peerLocation,
-					UpdatedAt: time.Now(),
-				}
-				peerStats.RTTresultsMap[peerIP] = peer
-				peerStats.RTTresultsSlice = append(
-					peerStats.RTTresultsSlice,
-					peer,
-				)
-				sort.Sort(peerStats.RTTresultsSlice)
+				results[i] = checkPeer(peerCtx, enrichSem, v, refreshing)
 			}()
-			wg.Wait()
+		}
+		wg.Wait()
+		// Results from a cancelled analysis are discarded
+		if peerCtx.Err() != nil {
+			return nil
+		}
+		for _, peer := range results {
+			if peer == nil {
+				continue
+			}
+			peerStats.RTTresultsMap[peer.IP] = peer
+			peerStats.RTTresultsSlice = replacePeerResult(
+				peerStats.RTTresultsSlice,
+				peer,
+			)
+		}
+		sort.Sort(peerStats.RTTresultsSlice)
+		if !config.GetConfig().App.AirGapped {
+			countPeerRTTs(&peerStats)
 		}
 		peerCNTreachable := peerCount - peerStats.CNT0
 		if peerCNTreachable > 0 {
@@ -257,36 +340,343 @@ func pingPeers(ctx context.Context) error {
 			) / float32(
 				peerCNTreachable,
 			) * 100
-			peerStats.PCT1items = int(peerStats.PCT1) * granularitySmall / 100
+			peerStats.PCT1items = progressItems(
+				peerStats.PCT1,
+				granularitySmall,
+			)
 			peerStats.PCT2 = float32(
 				peerStats.CNT2,
 			) / float32(
 				peerCNTreachable,
 			) * 100
-			peerStats.PCT2items = int(peerStats.PCT2) * granularitySmall / 100
+			peerStats.PCT2items = progressItems(
+				peerStats.PCT2,
+				granularitySmall,
+			)
 			peerStats.PCT3 = float32(
 				peerStats.CNT3,
 			) / float32(
 				peerCNTreachable,
 			) * 100
-			peerStats.PCT3items = int(peerStats.PCT3) * granularitySmall / 100
+			peerStats.PCT3items = progressItems(
+				peerStats.PCT3,
+				granularitySmall,
+			)
 			peerStats.PCT4 = float32(
 				peerStats.CNT4,
 			) / float32(
 				peerCNTreachable,
 			) * 100
-			peerStats.PCT4items = int(peerStats.PCT4) * granularitySmall / 100
+			peerStats.PCT4items = progressItems(
+				peerStats.PCT4,
+				granularitySmall,
+			)
 		}
-		if len(peerStats.RTTresultsSlice) != 0 &&
+		if refreshing {
+			peersDirty.Store(true)
+		} else if len(peerStats.RTTresultsSlice) != 0 &&
 			len(peerStats.RTTresultsSlice) >= peerCount {
 			checkPeers = false
 			scrollPeers = true
+			peersDirty.Store(true)
+			peerAnalysisStart = time.Time{}
+			cancelPeerAnalysis()
 		}
 	}
 	failCount = 0
 	return nil
 }
 
+// Measures the RTT to a peer, which is replaced in tests
+var measurePeerRTT = tcpinfoRtt
+
+// Checks a peer, given as "ip;port;direction", returning its result, or nil
+// when it was already checked in this analysis. Recent results are reused
+// unless refreshing the stats after the analysis.
+func checkPeer(
+	ctx context.Context,
+	enrichSem chan struct{},
+	v string,
+	refreshing bool,
+) *Peer {
+	peerArr := strings.Split(v, ";")
+	if len(peerArr) < 3 {
+		return nil
+	}
+	peerIP := peerArr[0]
+	peerPORT := peerArr[1]
+	peerDIR := peerArr[2]
+	rttTimeout := time.Duration(
+		config.GetConfig().App.PeerRTTTimeout,
+	) * time.Millisecond
+	airGapped := config.GetConfig().App.AirGapped
+
+	// Return early if we've been checked in this analysis, and reuse a
+	// result from the last peerCacheTTL, such as one saved before a restart
+	now := time.Now()
+	expire := now.Add(-peerCacheTTL)
+	existing, ok := peerStats.RTTresultsMap[peerIP]
+	var cached bool
+	if ok && !refreshing {
+		if !existing.analyzedAt.Before(peerAnalysisStart) {
+			return nil
+		}
+		cached = existing.UpdatedAt.After(expire) && existing.RTT != 0
+	}
+
+	// Carry over failure accounting from earlier checks
+	var peerChecks, peerFailures int
+	var peerLastError string
+	if existing != nil {
+		peerChecks = existing.Checks
+		peerFailures = existing.Failures
+		peerLastError = existing.LastError
+	}
+	var peerRTT int
+	peerUpdatedAt := now
+	if airGapped {
+		// Air-gapped mode makes no outbound connections, so the RTT is
+		// never measured
+		peerRTT = 99999
+	} else if cached {
+		peerRTT = existing.RTT
+		peerUpdatedAt = existing.UpdatedAt
+	} else {
+		var rttErr error
+		peerRTT, rttErr = measurePeerRTT(
+			ctx,
+			net.JoinHostPort(peerIP, peerPORT),
+			rttTimeout,
+		)
+		peerChecks, peerFailures = countPeerCheck(
+			peerChecks,
+			peerFailures,
+			rttErr,
+		)
+		if rttErr != nil {
+			peerLastError = rttErr.Error()
+		}
+	}
+	peerPort, err := strconv.Atoi(peerPORT)
+	if err != nil {
+		peerPort = 0
+	}
+	peerLocation, peerHostname, peerDistance := enrichPeer(
+		ctx,
+		enrichSem,
+		peerIP,
+		existing,
+	)
+	// Keep when we first saw this peer
+	peerFirstSeen := now
+	if existing != nil && !existing.FirstSeen.IsZero() {
+		peerFirstSeen = existing.FirstSeen
+	}
+	return &Peer{
+		IP:        peerIP,
+		Port:      peerPort,
+		Direction: peerDIR,
+		RTT:       peerRTT,
+		Location:  peerLocation,
+		Pool:      getPoolTicker(peerIP),
+		Hostname:  peerHostname,
+		Distance:  peerDistance,
+		Checks:    peerChecks,
+		Failures:  peerFailures,
+		LastError: peerLastError,
+		FirstSeen: peerFirstSeen,
+		UpdatedAt: peerUpdatedAt,
+
+		analyzedAt: now,
+	}
+}
+
+// Returns the peer results with the result for the same connection replaced,
+// or the result added when there's none
+func replacePeerResult(
+	peers peerRTTresultsSlice,
+	peer *Peer,
+) peerRTTresultsSlice {
+	for i, p := range peers {
+		if p.IP == peer.IP && p.Port == peer.Port &&
+			p.Direction == peer.Direction {
+			peers[i] = peer
+			return peers
+		}
+	}
+	return append(peers, peer)
+}
+
+// Recounts the RTT buckets and sum from the peer results
+func countPeerRTTs(stats *PeerStats) {
+	stats.CNT0, stats.CNT1, stats.CNT2, stats.CNT3, stats.CNT4 = 0, 0, 0, 0, 0
+	stats.RTTSUM = 0
+	for _, peer := range stats.RTTresultsSlice {
+		if peer.RTT != 99999 {
+			stats.RTTSUM += peer.RTT
+		}
+		if peer.RTT < 50 {
+			stats.CNT1++
+		} else if peer.RTT < 100 {
+			stats.CNT2++
+		} else if peer.RTT < 200 {
+			stats.CNT3++
+		} else if peer.RTT < 99999 {
+			stats.CNT4++
+		} else {
+			stats.CNT0++
+		}
+	}
+}
+
+// Position of the next peer to ping when pinging a subset of peers each cycle
+var peerPingOffset int
+
+// Returns up to size peers starting at offset, wrapping around, along with the
+// offset for the next subset. All peers are returned when size is 0 or covers
+// every peer.
+func getPeerPingSubset(
+	peers []string,
+	offset int,
+	size int,
+) ([]string, int) {
+	if size <= 0 || size >= len(peers) {
+		return peers, 0
+	}
+	offset %= len(peers)
+	subset := make([]string, 0, size)
+	for i := 0; i < size; i++ {
+		subset = append(subset, peers[(offset+i)%len(peers)])
+	}
+	return subset, (offset + size) % len(peers)
+}
+
+// Runs a single peer analysis without the TUI and writes the results
+func runPeersOnce(ctx context.Context, w io.Writer, jsonOutput bool) error {
+	proc, err := findNodeProcess(ctx)
+	if err != nil {
+		return err
+	}
+	if proc == nil || proc.Pid() == 0 {
+		return fmt.Errorf("unable to find a running node process")
+	}
+	// Discover the node's port and network like the UI, so peers are
+	// filtered the same way
+	setNodeProcess(ctx, proc)
+	if ip := getStaticPublicIP(); ip != nil {
+		publicIP = &ip
+	} else if !config.GetConfig().App.DisablePublicIP &&
+		!config.GetConfig().App.AirGapped {
+		ip, err := lookupPublicIP(ctx)
+		if err == nil {
+			publicIP = &ip
+		}
+	}
+	if err := filterPeers(ctx); err != nil {
+		return err
+	}
+	checkPeers = true
+	// Keep pinging until every peer is done, since only a subset of peers
+	// may be pinged each time, stopping if a round makes no progress
+	for checkPeers {
+		done := len(peerStats.RTTresultsSlice)
+		if err := pingPeers(ctx); err != nil {
+			return err
+		}
+		if ctx.Err() != nil || len(peerStats.RTTresultsSlice) == done {
+			break
+		}
+	}
+	if jsonOutput {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(peerStats.RTTresultsSlice)
+	}
+	fmt.Fprintf(
+		w,
+		"%4s %39s:%-5s %-3s %-5s %-8s %s\n",
+		"#",
+		"REMOTE PEER",
+		"PORT",
+		"I/O",
+		"RTT",
+		"POOL",
+		"GEOLOCATION",
+	)
+	for peerNbr, peer := range peerStats.RTTresultsSlice {
+		rtt := "---"
+		if peer.RTT < 99999 {
+			rtt = strconv.Itoa(peer.RTT)
+		}
+		fmt.Fprintf(
+			w,
+			"%4d %39s:%-5d %-3s %-5s %-8s %s\n",
+			peerNbr+1,
+			peer.IP,
+			peer.Port,
+			peer.Direction,
+			rtt,
+			peer.Pool,
+			getPeerLocationText(peer),
+		)
+	}
+	return nil
+}
+
+// Looks up the location, hostname, and distance of a peer, holding a slot in
+// the semaphore channel so that only a limited number of lookups, which can
+// include slow reverse DNS, run at once
+func enrichPeer(
+	ctx context.Context,
+	sem chan struct{},
+	peerIP string,
+	existing *Peer,
+) (string, string, float64) {
+	select {
+	case sem <- struct{}{}:
+		defer func() { <-sem }()
+	case <-ctx.Done():
+		return "---", "", 0
+	}
+	cfg := config.GetConfig()
+	location := getGeoIP(ctx, peerIP)
+	// Reuse any hostname we've already resolved
+	var hostname string
+	if existing != nil && existing.Hostname != "" {
+		hostname = existing.Hostname
+	} else if cfg.App.PeerReverseDNS && !cfg.App.AirGapped {
+		hostname = getPeerHostname(ctx, peerIP)
+	}
+	var distance float64
+	if cfg.App.PeerDistance {
+		distance = getPeerDistance(peerIP)
+	}
+	return location, hostname, distance
+}
+
+// Returns the updated check and failure counts for a peer after an RTT check,
+// so consistently unreachable peers can be told apart from flapping ones
+func countPeerCheck(checks int, failures int, err error) (int, int) {
+	checks++
+	if err != nil {
+		failures++
+	}
+	return checks, failures
+}
+
+// Returns the peer location, followed by its distance and hostname when
+// known
+func getPeerLocationText(peer *Peer) string {
+	location := peer.Location
+	if peer.Distance > 0 {
+		location = fmt.Sprintf("%s - %.0f km", location, peer.Distance)
+	}
+	if peer.Hostname != "" {
+		location = fmt.Sprintf("%s (%s)", location, peer.Hostname)
+	}
+	return location
+}
+
 func resetPeers() {
 	peerStats.CNT0 = 0
 	peerStats.CNT1 = 0
@@ -295,12 +685,20 @@ func resetPeers() {
 	peerStats.CNT4 = 0
 	peerStats.RTTSUM = 0
 	peerStats.RTTresultsSlice = []*Peer{}
+	peerAnalysisStart = time.Time{}
+	cancelPeerAnalysis()
 	for _, peerIP := range peerStats.RTTresultsMap {
 		peerIP.RTT = 0
 	}
 	peersFiltered = []string{}
+	peerPingOffset = 0
+	peersDirty.Store(true)
 }
 
+// Set when the peer data shown in the Peers panel changes, so the panel is
+// only rebuilt when needed, since that's costly with many peers
+var peersDirty atomic.Bool
+
 var peerStats PeerStats
 
 type PeerStats struct {
@@ -324,12 +722,22 @@ type PeerStats struct {
 }
 
 type Peer struct {
-	Direction string
-	IP        string
-	RTT       int
-	Port      int
-	Location  string
-	UpdatedAt time.Time
+	Direction string    `json:"direction"`
+	IP        string    `json:"ip"`
+	RTT       int       `json:"rtt"`
+	Port      int       `json:"port"`
+	Location  string    `json:"location"`
+	Pool      string    `json:"pool,omitempty"`
+	Hostname  string    `json:"hostname,omitempty"`
+	Distance  float64   `json:"distance,omitempty"`
+	Checks    int       `json:"checks"`
+	Failures  int       `json:"failures"`
+	LastError string    `json:"lastError,omitempty"`
+	FirstSeen time.Time `json:"firstSeen"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// When this result was added to the current analysis
+	analyzedAt time.Time
 }
 
 type peerRTTresultsMap map[string]*Peer
@@ -349,3 +757,35 @@ func (p peerRTTresultsSlice) Swap(i, j int) {
 func (p peerRTTresultsSlice) Less(i, j int) bool {
 	return p[i].RTT < p[j].RTT
 }
+
+// Show peers with the worst RTT first, rather than the best
+var peerSortDescending atomic.Bool
+
+// Returns peers, which are kept sorted by RTT, in display order, sorting a
+// copy when showing the worst first
+func getDisplayPeers(
+	peers peerRTTresultsSlice,
+	descending bool,
+) peerRTTresultsSlice {
+	if !descending {
+		return peers
+	}
+	ret := make(peerRTTresultsSlice, len(peers))
+	copy(ret, peers)
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].RTT > ret[j].RTT
+	})
+	return ret
+}
+
+// Toggles the peer sort direction, returning a notice for the footer. Only
+// the direction changes here, since the peers are sorted when rendered.
+func togglePeerSort() string {
+	descending := !peerSortDescending.Load()
+	peerSortDescending.Store(descending)
+	peersDirty.Store(true)
+	if descending {
+		return "Peers sorted by RTT, worst first"
+	}
+	return "Peers sorted by RTT, best first"
+}
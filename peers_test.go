@@ -0,0 +1,1230 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	netutil "github.com/shirou/gopsutil/v3/net"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+func TestGetPeerPingSubset(t *testing.T) {
+	peers := []string{"a", "b", "c", "d", "e"}
+	testDefs := []struct {
+		offset     int
+		size       int
+		expected   []string
+		nextOffset int
+	}{
+		// Size 0 pings every peer
+		{offset: 3, size: 0, expected: peers, nextOffset: 0},
+		// Sizes covering every peer ping every peer
+		{offset: 2, size: 5, expected: peers, nextOffset: 0},
+		{offset: 2, size: 10, expected: peers, nextOffset: 0},
+		{offset: 0, size: 2, expected: []string{"a", "b"}, nextOffset: 2},
+		{offset: 2, size: 2, expected: []string{"c", "d"}, nextOffset: 4},
+		// Wraps around the end of the list
+		{offset: 4, size: 2, expected: []string{"e", "a"}, nextOffset: 1},
+		{offset: 3, size: 4, expected: []string{"d", "e", "a", "b"}, nextOffset: 2},
+		// Offsets past the end, such as after the peer list shrinks
+		{offset: 7, size: 2, expected: []string{"c", "d"}, nextOffset: 4},
+	}
+	for _, testDef := range testDefs {
+		subset, nextOffset := getPeerPingSubset(
+			peers,
+			testDef.offset,
+			testDef.size,
+		)
+		if !reflect.DeepEqual(subset, testDef.expected) {
+			t.Errorf(
+				"offset %d, size %d: got %v, expected %v",
+				testDef.offset,
+				testDef.size,
+				subset,
+				testDef.expected,
+			)
+		}
+		if nextOffset != testDef.nextOffset {
+			t.Errorf(
+				"offset %d, size %d: got next offset %d, expected %d",
+				testDef.offset,
+				testDef.size,
+				nextOffset,
+				testDef.nextOffset,
+			)
+		}
+	}
+}
+
+// Successive subsets cover every peer
+func TestGetPeerPingSubsetCycle(t *testing.T) {
+	peers := []string{"a", "b", "c", "d", "e", "f", "g"}
+	seen := make(map[string]int)
+	offset := 0
+	for i := 0; i < 7; i++ {
+		var subset []string
+		subset, offset = getPeerPingSubset(peers, offset, 3)
+		for _, peer := range subset {
+			seen[peer]++
+		}
+	}
+	for _, peer := range peers {
+		if seen[peer] != 3 {
+			t.Errorf("peer %s pinged %d times, expected %d", peer, seen[peer], 3)
+		}
+	}
+}
+
+func TestReplacePeerResult(t *testing.T) {
+	peers := peerRTTresultsSlice{
+		{Direction: "out", IP: "192.0.2.1", Port: 3001, RTT: 40},
+		{Direction: "in", IP: "192.0.2.1", Port: 3001, RTT: 60},
+	}
+	peers = replacePeerResult(
+		peers,
+		&Peer{Direction: "in", IP: "192.0.2.1", Port: 3001, RTT: 80},
+	)
+	peers = replacePeerResult(
+		peers,
+		&Peer{Direction: "out", IP: "192.0.2.2", Port: 3001, RTT: 120},
+	)
+	expected := []int{40, 80, 120}
+	if len(peers) != len(expected) {
+		t.Fatalf("got %d peers, expected %d", len(peers), len(expected))
+	}
+	for i, rtt := range expected {
+		if peers[i].RTT != rtt {
+			t.Errorf("peer %d: got RTT %d, expected %d", i, peers[i].RTT, rtt)
+		}
+	}
+}
+
+func TestCountPeerRTTs(t *testing.T) {
+	stats := PeerStats{
+		// Stale counts from a previous analysis are replaced
+		CNT1:   10,
+		RTTSUM: 1000,
+		RTTresultsSlice: peerRTTresultsSlice{
+			{RTT: 10},
+			{RTT: 49},
+			{RTT: 50},
+			{RTT: 150},
+			{RTT: 250},
+			{RTT: 99999},
+		},
+	}
+	countPeerRTTs(&stats)
+	got := []int{
+		stats.CNT0,
+		stats.CNT1,
+		stats.CNT2,
+		stats.CNT3,
+		stats.CNT4,
+		stats.RTTSUM,
+	}
+	expected := []int{1, 2, 1, 1, 1, 509}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestGetDisplayPeers(t *testing.T) {
+	peers := peerRTTresultsSlice{
+		{IP: "192.0.2.1", RTT: 10},
+		{IP: "192.0.2.2", RTT: 40},
+		{IP: "192.0.2.3", RTT: 40},
+		{IP: "192.0.2.4", RTT: 99999},
+	}
+	testDefs := []struct {
+		descending bool
+		expected   []string
+	}{
+		{
+			descending: false,
+			expected: []string{
+				"192.0.2.1",
+				"192.0.2.2",
+				"192.0.2.3",
+				"192.0.2.4",
+			},
+		},
+		{
+			descending: true,
+			expected: []string{
+				"192.0.2.4",
+				"192.0.2.2",
+				"192.0.2.3",
+				"192.0.2.1",
+			},
+		},
+	}
+	for _, testDef := range testDefs {
+		var got []string
+		for _, peer := range getDisplayPeers(peers, testDef.descending) {
+			got = append(got, peer.IP)
+		}
+		if !reflect.DeepEqual(got, testDef.expected) {
+			t.Errorf(
+				"descending %v: got %v, expected %v",
+				testDef.descending,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+	// The shared slice is left sorted best first
+	if peers[0].IP != "192.0.2.1" || peers[3].IP != "192.0.2.4" {
+		t.Errorf("got %v, expected the peers to be left in place", peers)
+	}
+}
+
+func TestGetConnectionStateCounts(t *testing.T) {
+	conn := func(status string) netutil.ConnectionStat {
+		return netutil.ConnectionStat{Status: status}
+	}
+	testDefs := []struct {
+		connections []netutil.ConnectionStat
+		expected    map[string]int
+	}{
+		{connections: nil, expected: map[string]int{}},
+		{
+			connections: []netutil.ConnectionStat{
+				conn("ESTABLISHED"),
+				conn("ESTABLISHED"),
+				conn("LISTEN"),
+				conn("SYN_SENT"),
+				conn("TIME_WAIT"),
+				conn("TIME_WAIT"),
+				conn("TIME_WAIT"),
+				conn("CLOSE_WAIT"),
+			},
+			expected: map[string]int{
+				"ESTABLISHED": 2,
+				"LISTEN":      1,
+				"SYN_SENT":    1,
+				"TIME_WAIT":   3,
+				"CLOSE_WAIT":  1,
+			},
+		},
+	}
+	for _, testDef := range testDefs {
+		got := getConnectionStateCounts(testDef.connections)
+		if !reflect.DeepEqual(got, testDef.expected) {
+			t.Errorf("got %v, expected %v", got, testDef.expected)
+		}
+	}
+}
+
+func TestTogglePeerSort(t *testing.T) {
+	oldDescending := peerSortDescending.Load()
+	t.Cleanup(func() {
+		peerSortDescending.Store(oldDescending)
+	})
+	peerSortDescending.Store(false)
+	peersDirty.Store(false)
+	testDefs := []struct {
+		descending bool
+		notice     string
+	}{
+		{true, "Peers sorted by RTT, worst first"},
+		{false, "Peers sorted by RTT, best first"},
+	}
+	for _, testDef := range testDefs {
+		notice := togglePeerSort()
+		if notice != testDef.notice {
+			t.Errorf("got notice %q, expected %q", notice, testDef.notice)
+		}
+		if got := peerSortDescending.Load(); got != testDef.descending {
+			t.Errorf("got descending %v, expected %v", got, testDef.descending)
+		}
+		if !peersDirty.Swap(false) {
+			t.Errorf("expected the peers to be marked for redraw")
+		}
+	}
+}
+
+// Sets a stubbed node process and peer RTTs for a headless peer analysis,
+// where the node listens on port 3002 per its cmdline
+func setPeersOnceFixture(t *testing.T) {
+	t.Helper()
+	cfg := config.GetConfig()
+	oldCfg := *cfg
+	oldFindNodeProcess := findNodeProcess
+	oldMeasurePeerRTT := measurePeerRTT
+	oldP2P := p2p
+	t.Cleanup(func() {
+		// Discovering the node port replaces the config instance
+		*config.GetConfig() = oldCfg
+		findNodeProcess = oldFindNodeProcess
+		measurePeerRTT = oldMeasurePeerRTT
+		p2p = oldP2P
+		nodeConfigApplied = false
+		detectedNodeName, detectedNodeBinary = "", ""
+		processMetrics = nil
+		processThreads = 0
+		publicIP = nil
+		peersFiltered = nil
+		peerStats = PeerStats{}
+		checkPeers = false
+		peerPingOffset = 0
+		peerAnalysisStart = time.Time{}
+	})
+	cfg.App.PublicIP = "198.51.100.7"
+	cfg.App.PeerPingSampleSize = 0
+	nodeConfigApplied = false
+	peersFiltered = nil
+	peerStats = PeerStats{RTTresultsMap: make(peerRTTresultsMap)}
+	established := func(
+		local uint32,
+		ip string,
+		port uint32,
+	) netutil.ConnectionStat {
+		return netutil.ConnectionStat{
+			Status: "ESTABLISHED",
+			Laddr:  netutil.Addr{IP: "10.0.0.1", Port: local},
+			Raddr:  netutil.Addr{IP: ip, Port: port},
+		}
+	}
+	proc := &fakeProcess{
+		pid:     1234,
+		name:    "cardano-node",
+		cmdline: []string{"cardano-node", "run", "--port", "3002"},
+		conns: map[string][]netutil.ConnectionStat{
+			"tcp": {
+				// Inbound on the discovered node port
+				established(3002, "203.0.113.1", 40000),
+				established(45000, "203.0.113.2", 3001),
+				established(45001, "2001:db8::1", 3001),
+				// Skipped connections
+				established(45002, "127.0.0.1", 3001),
+				established(45003, "198.51.100.7", 3002),
+				{
+					Status: "TIME_WAIT",
+					Laddr:  netutil.Addr{IP: "10.0.0.1", Port: 45004},
+					Raddr:  netutil.Addr{IP: "203.0.113.3", Port: 3001},
+				},
+			},
+		},
+	}
+	findNodeProcess = func(ctx context.Context) (nodeProcess, error) {
+		return proc, nil
+	}
+	rtts := map[string]int{
+		"203.0.113.1:40000":  80,
+		"203.0.113.2:3001":   25,
+		"[2001:db8::1]:3001": 140,
+	}
+	measurePeerRTT = func(
+		ctx context.Context,
+		address string,
+		timeout time.Duration,
+	) (int, error) {
+		if rtt, ok := rtts[address]; ok {
+			return rtt, nil
+		}
+		return 99999, errors.New("connection refused")
+	}
+}
+
+func TestRunPeersOnce(t *testing.T) {
+	setPeersOnceFixture(t)
+	var buf bytes.Buffer
+	if err := runPeersOnce(context.Background(), &buf, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var peers []Peer
+	if err := json.Unmarshal(buf.Bytes(), &peers); err != nil {
+		t.Fatalf("failed to decode output: %s\n%s", err, buf.String())
+	}
+	expected := []struct {
+		ip        string
+		port      int
+		direction string
+		rtt       int
+	}{
+		{ip: "203.0.113.2", port: 3001, direction: "o", rtt: 25},
+		{ip: "203.0.113.1", port: 40000, direction: "i", rtt: 80},
+		{ip: "2001:db8::1", port: 3001, direction: "o", rtt: 140},
+	}
+	if len(peers) != len(expected) {
+		t.Fatalf(
+			"got %d peers, expected %d:\n%s",
+			len(peers),
+			len(expected),
+			buf.String(),
+		)
+	}
+	for i, peer := range peers {
+		if peer.IP != expected[i].ip || peer.Port != expected[i].port ||
+			peer.Direction != expected[i].direction ||
+			peer.RTT != expected[i].rtt {
+			t.Errorf(
+				"peer %d: got %s:%d %s %d, expected %s:%d %s %d",
+				i,
+				peer.IP,
+				peer.Port,
+				peer.Direction,
+				peer.RTT,
+				expected[i].ip,
+				expected[i].port,
+				expected[i].direction,
+				expected[i].rtt,
+			)
+		}
+	}
+	// The node port was discovered from the process, like in the UI
+	if port := config.GetConfig().Node.Port; port != 3002 {
+		t.Errorf("got node port %d, expected %d", port, 3002)
+	}
+}
+
+func TestRunPeersOnceText(t *testing.T) {
+	setPeersOnceFixture(t)
+	var buf bytes.Buffer
+	if err := runPeersOnce(context.Background(), &buf, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, expected %d:\n%s", len(lines), 4, buf.String())
+	}
+	for i, expected := range []string{
+		"REMOTE PEER:PORT  I/O RTT",
+		"203.0.113.2:3001  o   25",
+		"203.0.113.1:40000 i   80",
+		"2001:db8::1:3001  o   140",
+	} {
+		if !strings.Contains(lines[i], expected) {
+			t.Errorf(
+				"line %d: got %q, expected it to contain %q",
+				i,
+				lines[i],
+				expected,
+			)
+		}
+	}
+}
+
+func TestRunPeersOnceNoProcess(t *testing.T) {
+	setPeersOnceFixture(t)
+	findNodeProcess = func(ctx context.Context) (nodeProcess, error) {
+		return &fakeProcess{}, nil
+	}
+	var buf bytes.Buffer
+	if err := runPeersOnce(context.Background(), &buf, false); err == nil {
+		t.Errorf("expected an error without a node process")
+	}
+}
+
+func TestAddFilteredPeerStaticPublicIP(t *testing.T) {
+	cfg := config.GetConfig()
+	oldPublicIP := cfg.App.PublicIP
+	oldPort := cfg.Node.Port
+	t.Cleanup(func() {
+		cfg.App.PublicIP = oldPublicIP
+		cfg.Node.Port = oldPort
+		publicIP = nil
+	})
+	cfg.App.PublicIP = "198.51.100.7"
+	cfg.Node.Port = 3001
+	updatePublicIP(context.Background())
+	if got := getStaticPublicIP(); !got.Equal(*publicIP) {
+		t.Fatalf("got public IP %v, expected %v", publicIP, got)
+	}
+	var peers []string
+	for _, address := range []string{
+		// Our own address on the node port is skipped
+		"198.51.100.7:3001",
+		"[::ffff:198.51.100.7]:3001",
+		// Other ports and addresses are kept
+		"198.51.100.7:6000",
+		"198.51.100.8:3001",
+	} {
+		peers = addFilteredPeer(peers, address, "o")
+	}
+	expected := []string{"198.51.100.7;6000;o", "198.51.100.8;3001;o"}
+	if !reflect.DeepEqual(peers, expected) {
+		t.Errorf("got %v, expected %v", peers, expected)
+	}
+}
+
+// Returns a connection between a local and remote address
+func newTestConnection(
+	status string,
+	localIP string,
+	localPort uint32,
+	remoteIP string,
+	remotePort uint32,
+) netutil.ConnectionStat {
+	return netutil.ConnectionStat{
+		Status: status,
+		Laddr:  netutil.Addr{IP: localIP, Port: localPort},
+		Raddr:  netutil.Addr{IP: remoteIP, Port: remotePort},
+	}
+}
+
+// Connections for a node on port 3001 with an NtC socket and NtC TCP port 3100
+func getLocalClientFixture() (unixConns, tcpConns []netutil.ConnectionStat) {
+	socket := "/opt/cardano/ipc/socket"
+	unixConns = []netutil.ConnectionStat{
+		// The listener, then accepted client connections
+		newTestConnection("NONE", socket, 0, "", 0),
+		newTestConnection("NONE", socket, 0, "", 0),
+		newTestConnection("NONE", socket, 0, "", 0),
+		// Other sockets, such as a tracer
+		newTestConnection("NONE", "/tmp/tracer.socket", 0, "", 0),
+	}
+	tcpConns = []netutil.ConnectionStat{
+		newTestConnection("ESTABLISHED", "10.0.0.5", 3100, "10.0.0.9", 41000),
+		newTestConnection("ESTABLISHED", "10.0.0.5", 3100, "10.0.0.9", 41001),
+		newTestConnection("TIME_WAIT", "10.0.0.5", 3100, "10.0.0.9", 41002),
+		newTestConnection("LISTEN", "0.0.0.0", 3100, "", 0),
+		// Peers
+		newTestConnection("ESTABLISHED", "10.0.0.5", 3001, "198.51.100.1", 5000),
+		newTestConnection("ESTABLISHED", "10.0.0.5", 45000, "198.51.100.2", 3001),
+	}
+	return unixConns, tcpConns
+}
+
+func TestCountLocalClients(t *testing.T) {
+	unixConns, tcpConns := getLocalClientFixture()
+	testDefs := []struct {
+		socketPath string
+		n2cPort    uint32
+		expected   int
+	}{
+		{socketPath: "/opt/cardano/ipc/socket", n2cPort: 3100, expected: 4},
+		{socketPath: "/opt/cardano/ipc/socket", expected: 2},
+		{n2cPort: 3100, expected: 2},
+		// Peer connections are never local clients
+		{n2cPort: 3001, expected: 1},
+		{socketPath: "/opt/cardano/ipc/other", expected: 0},
+	}
+	for _, testDef := range testDefs {
+		got := countLocalClients(
+			unixConns,
+			tcpConns,
+			testDef.socketPath,
+			testDef.n2cPort,
+		)
+		if got != testDef.expected {
+			t.Errorf(
+				"socket %q, port %d: got %d, expected %d",
+				testDef.socketPath,
+				testDef.n2cPort,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetConnectionTextLocalClients(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	oldP2P := p2p
+	oldPort := cfg.Node.Port
+	oldN2CPort := cfg.Node.N2CPort
+	oldSocketPath := cfg.Node.SocketPath
+	t.Cleanup(func() {
+		p2p = oldP2P
+		cfg.Node.Port = oldPort
+		cfg.Node.N2CPort = oldN2CPort
+		cfg.Node.SocketPath = oldSocketPath
+	})
+	p2p = false
+	cfg.Node.Port = 3001
+	cfg.Node.N2CPort = 3100
+	cfg.Node.SocketPath = "/opt/cardano/ipc/socket"
+	unixConns, tcpConns := getLocalClientFixture()
+	processMetrics = &fakeProcess{
+		pid: 1234,
+		conns: map[string][]netutil.ConnectionStat{
+			"unix": unixConns,
+			"tcp":  tcpConns,
+		},
+	}
+	got := getConnectionText(context.Background())
+	// NtC connections are counted as local clients rather than peers
+	for _, expected := range []string{
+		" [green]Incoming   : [white]1\n",
+		" [green]Outgoing   : [white]1\n",
+		" [green]NtC Clients: [white]4\n",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("got %q, expected it to contain %q", got, expected)
+		}
+	}
+}
+
+func TestEnrichPeerHostname(t *testing.T) {
+	cfg := config.GetConfig()
+	oldReverseDNS := cfg.App.PeerReverseDNS
+	oldAirGapped := cfg.App.AirGapped
+	oldDistance := cfg.App.PeerDistance
+	t.Cleanup(func() {
+		cfg.App.PeerReverseDNS = oldReverseDNS
+		cfg.App.AirGapped = oldAirGapped
+		cfg.App.PeerDistance = oldDistance
+	})
+	cfg.App.PeerDistance = false
+	testDefs := []struct {
+		reverseDNS bool
+		airGapped  bool
+		existing   *Peer
+		expected   string
+		lookups    int
+	}{
+		// Reverse DNS is off by default
+		{expected: "", lookups: 0},
+		{reverseDNS: true, expected: "relay1.example.com", lookups: 1},
+		{reverseDNS: true, airGapped: true, expected: "", lookups: 0},
+		// A hostname resolved on an earlier pass is reused
+		{
+			reverseDNS: true,
+			existing:   &Peer{Hostname: "cached.example.com"},
+			expected:   "cached.example.com",
+			lookups:    0,
+		},
+	}
+	for _, testDef := range testDefs {
+		resolver := &fakePeerResolver{
+			names: map[string][]string{
+				"192.0.2.1": {"relay1.example.com."},
+			},
+		}
+		setPeerResolverFixture(t, resolver)
+		cfg.App.PeerReverseDNS = testDef.reverseDNS
+		cfg.App.AirGapped = testDef.airGapped
+		sem := make(chan struct{}, 1)
+		_, hostname, _ := enrichPeer(
+			context.Background(),
+			sem,
+			"192.0.2.1",
+			testDef.existing,
+		)
+		if hostname != testDef.expected {
+			t.Errorf("got %q, expected %q", hostname, testDef.expected)
+		}
+		if got := len(resolver.getCalls()); got != testDef.lookups {
+			t.Errorf("got %d lookups, expected %d", got, testDef.lookups)
+		}
+	}
+}
+
+// Resolves peer hostnames once released, tracking how many lookups run at
+// the same time
+type gatedPeerResolver struct {
+	release   chan struct{}
+	mu        sync.Mutex
+	active    int
+	maxActive int
+	calls     int
+}
+
+func (r *gatedPeerResolver) LookupAddr(
+	ctx context.Context,
+	addr string,
+) ([]string, error) {
+	r.mu.Lock()
+	r.active++
+	r.calls++
+	r.maxActive = max(r.maxActive, r.active)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.active--
+		r.mu.Unlock()
+	}()
+	select {
+	case <-r.release:
+		return []string{"relay.example.com."}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *gatedPeerResolver) getCounts() (int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls, r.maxActive
+}
+
+func TestEnrichPeerConcurrency(t *testing.T) {
+	cfg := config.GetConfig()
+	oldReverseDNS := cfg.App.PeerReverseDNS
+	oldAirGapped := cfg.App.AirGapped
+	oldDistance := cfg.App.PeerDistance
+	oldResolver := peerResolver
+	t.Cleanup(func() {
+		cfg.App.PeerReverseDNS = oldReverseDNS
+		cfg.App.AirGapped = oldAirGapped
+		cfg.App.PeerDistance = oldDistance
+		peerResolver = oldResolver
+	})
+	cfg.App.PeerReverseDNS = true
+	cfg.App.AirGapped = false
+	cfg.App.PeerDistance = false
+	resolver := &gatedPeerResolver{release: make(chan struct{})}
+	peerResolver = resolver
+	// More peers than the semaphore allows are enriched at once
+	sem := make(chan struct{}, 2)
+	hostnames := make(chan string, 6)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, hostname, _ := enrichPeer(
+				context.Background(),
+				sem,
+				fmt.Sprintf("192.0.2.%d", i+1),
+				nil,
+			)
+			hostnames <- hostname
+		}(i)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if calls, _ := resolver.getCounts(); calls == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for lookups to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// The rest wait for a slot rather than starting a lookup
+	time.Sleep(50 * time.Millisecond)
+	if calls, _ := resolver.getCounts(); calls != 2 {
+		t.Errorf("got %d lookups while blocked, expected 2", calls)
+	}
+	close(resolver.release)
+	wg.Wait()
+	close(hostnames)
+	for hostname := range hostnames {
+		if hostname != "relay.example.com" {
+			t.Errorf("got %q, expected relay.example.com", hostname)
+		}
+	}
+	calls, maxActive := resolver.getCounts()
+	if calls != 6 || maxActive != 2 {
+		t.Errorf(
+			"got %d lookups, %d at once, expected 6, 2 at once",
+			calls,
+			maxActive,
+		)
+	}
+	if len(sem) != 0 {
+		t.Errorf("got %d held slots, expected none", len(sem))
+	}
+}
+
+func TestEnrichPeerCancelled(t *testing.T) {
+	resolver := &fakePeerResolver{}
+	setPeerResolverFixture(t, resolver)
+	// Waiting for a slot stops when the context is done
+	sem := make(chan struct{}, 1)
+	sem <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	location, hostname, distance := enrichPeer(ctx, sem, "192.0.2.1", nil)
+	if location != "---" || hostname != "" || distance != 0 {
+		t.Errorf(
+			"got %q, %q, %v, expected ---, no hostname or distance",
+			location,
+			hostname,
+			distance,
+		)
+	}
+	if len(resolver.getCalls()) != 0 || len(sem) != 1 {
+		t.Errorf("expected no lookups and the slot left held")
+	}
+}
+
+func TestRunPeersOnceIPv6(t *testing.T) {
+	setPeersOnceFixture(t)
+	established := func(
+		local uint32,
+		ip string,
+		port uint32,
+	) netutil.ConnectionStat {
+		return netutil.ConnectionStat{
+			Status: "ESTABLISHED",
+			Laddr:  netutil.Addr{IP: "2001:db8::100", Port: local},
+			Raddr:  netutil.Addr{IP: ip, Port: port},
+		}
+	}
+	proc := &fakeProcess{
+		pid:     1234,
+		name:    "cardano-node",
+		cmdline: []string{"cardano-node", "run", "--port", "3002"},
+		conns: map[string][]netutil.ConnectionStat{
+			"tcp": {
+				established(45000, "2001:67c:2e8:22::c100:68b", 3001),
+				established(3002, "2001:4860:4860::8888", 40000),
+				// IPv4-mapped addresses are unmapped
+				established(45001, "::ffff:8.8.8.8", 3001),
+				established(45002, "::1", 3001),
+			},
+		},
+	}
+	findNodeProcess = func(ctx context.Context) (nodeProcess, error) {
+		return proc, nil
+	}
+	var mu sync.Mutex
+	var dialed []string
+	measurePeerRTT = func(
+		ctx context.Context,
+		address string,
+		timeout time.Duration,
+	) (int, error) {
+		mu.Lock()
+		dialed = append(dialed, address)
+		mu.Unlock()
+		return 50, nil
+	}
+	var buf bytes.Buffer
+	if err := runPeersOnce(context.Background(), &buf, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedFiltered := []string{
+		"2001:4860:4860::8888;40000;i",
+		"2001:67c:2e8:22::c100:68b;3001;o",
+		"8.8.8.8;3001;o",
+	}
+	if !reflect.DeepEqual(peersFiltered, expectedFiltered) {
+		t.Errorf("got %v, expected %v", peersFiltered, expectedFiltered)
+	}
+	sort.Strings(dialed)
+	expectedDialed := []string{
+		"8.8.8.8:3001",
+		"[2001:4860:4860::8888]:40000",
+		"[2001:67c:2e8:22::c100:68b]:3001",
+	}
+	if !reflect.DeepEqual(dialed, expectedDialed) {
+		t.Errorf("got %v, expected %v", dialed, expectedDialed)
+	}
+	testDefs := []struct {
+		ip       string
+		location string
+	}{
+		{ip: "2001:67c:2e8:22::c100:68b", location: "Amsterdam, NL"},
+		{ip: "2001:4860:4860::8888", location: "US"},
+		{ip: "8.8.8.8", location: "US"},
+	}
+	for _, testDef := range testDefs {
+		peer, ok := peerStats.RTTresultsMap[testDef.ip]
+		if !ok {
+			t.Errorf("%s: expected a peer result", testDef.ip)
+			continue
+		}
+		if peer.Location != testDef.location {
+			t.Errorf(
+				"%s: got location %q, expected %q",
+				testDef.ip,
+				peer.Location,
+				testDef.location,
+			)
+		}
+	}
+}
+
+func TestRunPeersOnceAirGapped(t *testing.T) {
+	setPeersOnceFixture(t)
+	oldLookup := lookupPublicIP
+	t.Cleanup(func() {
+		lookupPublicIP = oldLookup
+	})
+	cfg := config.GetConfig()
+	cfg.App.AirGapped = true
+	cfg.App.PeerReverseDNS = true
+	// Without a static public IP, it would otherwise be looked up
+	cfg.App.PublicIP = ""
+	var ipLookups, dials int
+	lookupPublicIP = func(ctx context.Context) (net.IP, error) {
+		ipLookups++
+		return net.ParseIP("198.51.100.7"), nil
+	}
+	measurePeerRTT = func(
+		ctx context.Context,
+		address string,
+		timeout time.Duration,
+	) (int, error) {
+		dials++
+		return 50, nil
+	}
+	resolver := &fakePeerResolver{}
+	setPeerResolverFixture(t, resolver)
+	var buf bytes.Buffer
+	if err := runPeersOnce(context.Background(), &buf, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ipLookups != 0 || dials != 0 || len(resolver.getCalls()) != 0 {
+		t.Errorf(
+			"got %d public IP lookups, %d dials, %d reverse DNS lookups, "+
+				"expected none",
+			ipLookups,
+			dials,
+			len(resolver.getCalls()),
+		)
+	}
+	// Peers are still listed from the node's connections, without an RTT
+	if len(peerStats.RTTresultsSlice) == 0 {
+		t.Fatalf("expected the peers to be listed")
+	}
+	for _, peer := range peerStats.RTTresultsSlice {
+		if peer.RTT != 99999 {
+			t.Errorf("%s: got RTT %d, expected 99999", peer.IP, peer.RTT)
+		}
+	}
+}
+
+func TestPingPeersFailureAccounting(t *testing.T) {
+	setPeersOnceFixture(t)
+	config.GetConfig().App.PeerPingSampleSize = 1
+	// Results of each ping cycle, with an error for a failed dial
+	results := []struct {
+		rtt int
+		err error
+	}{
+		{rtt: 99999, err: errors.New("i/o timeout")},
+		{rtt: 40},
+		{rtt: 99999, err: errors.New("connection refused")},
+		{rtt: 30},
+	}
+	var cycle int
+	measurePeerRTT = func(
+		ctx context.Context,
+		address string,
+		timeout time.Duration,
+	) (int, error) {
+		return results[cycle].rtt, results[cycle].err
+	}
+	peersFiltered = []string{"192.0.2.1;3001;o"}
+	checkPeers = true
+	expected := []struct {
+		checks    int
+		failures  int
+		lastError string
+	}{
+		{checks: 1, failures: 1, lastError: "i/o timeout"},
+		// The last error is kept after a success
+		{checks: 2, failures: 1, lastError: "i/o timeout"},
+		{checks: 3, failures: 2, lastError: "connection refused"},
+		{checks: 4, failures: 2, lastError: "connection refused"},
+	}
+	for cycle = range results {
+		if err := pingPeers(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		peer, ok := peerStats.RTTresultsMap["192.0.2.1"]
+		if !ok {
+			t.Fatalf("cycle %d: expected a peer result", cycle)
+		}
+		if peer.RTT != results[cycle].rtt ||
+			peer.Checks != expected[cycle].checks ||
+			peer.Failures != expected[cycle].failures ||
+			peer.LastError != expected[cycle].lastError {
+			t.Errorf(
+				"cycle %d: got RTT %d, %d/%d failed, %q, "+
+					"expected RTT %d, %d/%d failed, %q",
+				cycle,
+				peer.RTT,
+				peer.Failures,
+				peer.Checks,
+				peer.LastError,
+				results[cycle].rtt,
+				expected[cycle].failures,
+				expected[cycle].checks,
+				expected[cycle].lastError,
+			)
+		}
+	}
+}
+
+func TestRunPeersOnceRTTTimeout(t *testing.T) {
+	setPeersOnceFixture(t)
+	config.GetConfig().App.PeerRTTTimeout = 750
+	var mu sync.Mutex
+	var timeouts []time.Duration
+	measurePeerRTT = func(
+		ctx context.Context,
+		address string,
+		timeout time.Duration,
+	) (int, error) {
+		mu.Lock()
+		timeouts = append(timeouts, timeout)
+		mu.Unlock()
+		return 50, nil
+	}
+	var buf bytes.Buffer
+	if err := runPeersOnce(context.Background(), &buf, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(timeouts) == 0 {
+		t.Fatalf("expected peers to be measured")
+	}
+	// Every peer is dialed with the configured timeout
+	for _, timeout := range timeouts {
+		if timeout != 750*time.Millisecond {
+			t.Errorf("got %s, expected 750ms", timeout)
+		}
+	}
+}
+
+func TestGetPeerLocationText(t *testing.T) {
+	testDefs := []struct {
+		peer     Peer
+		expected string
+	}{
+		{peer: Peer{Location: "Amsterdam, NL"}, expected: "Amsterdam, NL"},
+		{
+			peer:     Peer{Location: "Amsterdam, NL", Distance: 357.4},
+			expected: "Amsterdam, NL - 357 km",
+		},
+		{
+			peer: Peer{
+				Location: "US",
+				Distance: 5870.6,
+				Hostname: "relay1.example.com",
+			},
+			expected: "US - 5871 km (relay1.example.com)",
+		},
+	}
+	for _, testDef := range testDefs {
+		got := getPeerLocationText(&testDef.peer)
+		if got != testDef.expected {
+			t.Errorf("got %q, expected %q", got, testDef.expected)
+		}
+	}
+}
+
+func TestGetPeerAnalysisProgress(t *testing.T) {
+	testDefs := []struct {
+		done    int
+		total   int
+		elapsed time.Duration
+		pct     float64
+		rate    float64
+		eta     time.Duration
+	}{
+		// Nothing to check
+		{done: 0, total: 0, elapsed: time.Minute},
+		// Not started yet
+		{done: 0, total: 200, elapsed: 10 * time.Second},
+		{done: 50, total: 200, elapsed: 0, pct: 25},
+		{
+			done:    50,
+			total:   200,
+			elapsed: 10 * time.Second,
+			pct:     25,
+			rate:    5,
+			eta:     30 * time.Second,
+		},
+		{
+			done:    1,
+			total:   3,
+			elapsed: 2 * time.Second,
+			pct:     100.0 / 3,
+			rate:    0.5,
+			eta:     4 * time.Second,
+		},
+		// The ETA is rounded to the second
+		{
+			done:    3,
+			total:   10,
+			elapsed: 1 * time.Second,
+			pct:     30,
+			rate:    3,
+			eta:     2 * time.Second,
+		},
+		{
+			done:    200,
+			total:   200,
+			elapsed: time.Minute,
+			pct:     100,
+			rate:    200.0 / 60,
+		},
+		// More results than peers, such as after peers drop, are capped
+		{
+			done:    250,
+			total:   200,
+			elapsed: 20 * time.Second,
+			pct:     100,
+			rate:    10,
+		},
+	}
+	for _, testDef := range testDefs {
+		pct, rate, eta := getPeerAnalysisProgress(
+			testDef.done,
+			testDef.total,
+			testDef.elapsed,
+		)
+		if math.Abs(pct-testDef.pct) > 1e-9 ||
+			math.Abs(rate-testDef.rate) > 1e-9 ||
+			eta != testDef.eta {
+			t.Errorf(
+				"%d/%d in %s: got (%v, %v, %s), expected (%v, %v, %s)",
+				testDef.done,
+				testDef.total,
+				testDef.elapsed,
+				pct,
+				rate,
+				eta,
+				testDef.pct,
+				testDef.rate,
+				testDef.eta,
+			)
+		}
+	}
+}
+
+func TestGetPeerAnalysisText(t *testing.T) {
+	setPanelFixtures(t)
+	t.Cleanup(func() {
+		peerStats = PeerStats{}
+		peersFiltered = nil
+		peerAnalysisStart = time.Time{}
+	})
+	setPeerFixture(50)
+	peersFiltered = append(peersFiltered, make([]string, 150)...)
+	testDefs := []struct {
+		start    time.Time
+		expected string
+	}{
+		{
+			expected: " [yellow]Peer analysis started... please wait!" +
+				" [blue]50[white]/[green]200[white] ([blue]25%[white])\n",
+		},
+		{
+			start: fixtureNow.Add(-10 * time.Second),
+			expected: " [yellow]Peer analysis started... please wait!" +
+				" [blue]50[white]/[green]200[white] ([blue]25%[white])" +
+				" [blue]5.0[white] peers/s, ETA [blue]30s[white]\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		peerAnalysisStart = testDef.start
+		if got := getPeerAnalysisText(); got != testDef.expected {
+			t.Errorf("got %q, expected %q", got, testDef.expected)
+		}
+	}
+}
+
+// Sets up peers to analyze with dials which block until cancelled, returning
+// a channel which receives each dialed address
+func setBlockingPeersFixture(t *testing.T, n int) <-chan string {
+	t.Helper()
+	oldMeasurePeerRTT := measurePeerRTT
+	oldCheckPeers := checkPeers
+	t.Cleanup(func() {
+		cancelPeerAnalysis()
+		measurePeerRTT = oldMeasurePeerRTT
+		checkPeers = oldCheckPeers
+		peerStats = PeerStats{}
+		peersFiltered = nil
+		peerPingOffset = 0
+		peerAnalysisStart = time.Time{}
+	})
+	cancelPeerAnalysis()
+	peerStats = PeerStats{RTTresultsMap: make(peerRTTresultsMap)}
+	peersFiltered = nil
+	for i := 0; i < n; i++ {
+		peersFiltered = append(
+			peersFiltered,
+			fmt.Sprintf("203.0.113.%d;3001;o", i+1),
+		)
+	}
+	checkPeers = true
+	dials := make(chan string, n)
+	measurePeerRTT = func(
+		ctx context.Context,
+		address string,
+		timeout time.Duration,
+	) (int, error) {
+		dials <- address
+		<-ctx.Done()
+		return 99999, ctx.Err()
+	}
+	return dials
+}
+
+func TestPingPeersCancel(t *testing.T) {
+	dials := setBlockingPeersFixture(t, 5)
+	done := make(chan error, 1)
+	go func() {
+		done <- pingPeers(context.Background())
+	}()
+	// Wait for the dials to start
+	for i := 0; i < 5; i++ {
+		select {
+		case <-dials:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for peer dials")
+		}
+	}
+	cancelPeerAnalysis()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the analysis to stop")
+	}
+	// Results from the cancelled analysis are discarded
+	if len(peerStats.RTTresultsSlice) != 0 {
+		t.Errorf(
+			"got %d results, expected none",
+			len(peerStats.RTTresultsSlice),
+		)
+	}
+	// A new analysis starts with a fresh context
+	if err := getPeerAnalysisContext(context.Background()).Err(); err != nil {
+		t.Errorf("got %s, expected a new analysis context", err)
+	}
+}
+
+func TestPingPeersCancelled(t *testing.T) {
+	dials := setBlockingPeersFixture(t, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := pingPeers(ctx); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("got %s, expected pingPeers to return promptly", elapsed)
+	}
+	// No new dials are started once cancelled
+	if len(dials) != 0 {
+		t.Errorf("got %d dials, expected none", len(dials))
+	}
+}
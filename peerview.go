@@ -0,0 +1,173 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Full-screen peers page
+var peersPageTextView = tview.NewTextView().
+	SetDynamicColors(true).
+	SetChangedFunc(func() {
+		app.Draw()
+	})
+var peersPageText string
+
+// Sets up the full-screen peers page, which returns to the main page on esc
+func setupPeersPage() {
+	peersPageTextView.SetTitle("Peers (esc to return)").SetBorder(true)
+	peersPageTextView.SetInputCapture(
+		func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyEscape || event.Rune() == 'f' {
+				pages.SwitchToPage("Main")
+				app.SetFocus(flex)
+				return nil
+			}
+			return event
+		},
+	)
+	pages.AddPage("Peers", peersPageTextView, true, false)
+}
+
+// Returns the page to start on from its configured name, falling back to the
+// main page for unknown names
+func getStartPage(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "main":
+		return "Main"
+	case "peers":
+		return "Peers"
+	default:
+		slog.Warn("unknown start page, using main", "name", name)
+		return "Main"
+	}
+}
+
+// Shows the full-screen peers page
+func showPeersPage() {
+	updatePeersPage()
+	pages.SwitchToPage("Peers")
+	app.SetFocus(peersPageTextView)
+	peersPageTextView.ScrollToBeginning()
+}
+
+// Updates the full-screen peers page when it's shown
+func updatePeersPage() {
+	if name, _ := pages.GetFrontPage(); name != "Peers" {
+		return
+	}
+	tmpText := getPeersPageText(time.Now())
+	if tmpText != peersPageText {
+		peersPageText = tmpText
+		peersPageTextView.SetText(peersPageText)
+	}
+}
+
+// Returns the full-screen peers text with a wide row per peer
+func getPeersPageText(now time.Time) string {
+	var sb strings.Builder
+	if checkPeers {
+		sb.WriteString(getPeerAnalysisText())
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf(
+		" [green]%4s %45s %-3s %-5s %7s %-8s %8s %14s  %s\n",
+		"#",
+		"REMOTE PEER",
+		"I/O",
+		"RTT",
+		"FAILS",
+		"POOL",
+		"DISTANCE",
+		"FIRST SEEN",
+		"GEOLOCATION / HOSTNAME / LAST ERROR",
+	))
+	peers := getDisplayPeers(
+		peerStats.RTTresultsSlice,
+		peerSortDescending.Load(),
+	)
+	for peerNbr, peer := range peers {
+		sb.WriteString(formatWidePeerRow(peerNbr+1, peer, now))
+	}
+	return sb.String()
+}
+
+// Formats a single peer row for the full-screen peers page
+func formatWidePeerRow(peerNbr int, peer *Peer, now time.Time) string {
+	color := "fuchsia"
+	rtt := "---"
+	if peer.RTT < 99999 {
+		rtt = strconv.Itoa(peer.RTT)
+		if peer.RTT < 50 {
+			color = "green"
+		} else if peer.RTT < 100 {
+			color = "yellow"
+		} else if peer.RTT < 200 {
+			color = "red"
+		}
+	}
+	distance := "---"
+	if peer.Distance > 0 {
+		distance = fmt.Sprintf("%.0fkm", peer.Distance)
+	}
+	firstSeen := "---"
+	if !peer.FirstSeen.IsZero() {
+		firstSeen = now.Sub(peer.FirstSeen).Truncate(time.Second).String() +
+			" ago"
+	}
+	// Failed checks out of all checks, so a firewalled peer which always
+	// fails can be told apart from a flapping one
+	failures := "---"
+	failuresColor := "white"
+	if peer.Checks > 0 {
+		failures = fmt.Sprintf("%d/%d", peer.Failures, peer.Checks)
+		if peer.Failures == peer.Checks {
+			failuresColor = "fuchsia"
+		} else if peer.Failures > 0 {
+			failuresColor = "yellow"
+		}
+	}
+	location := peer.Location
+	if peer.Hostname != "" {
+		location = fmt.Sprintf("%s / %s", location, peer.Hostname)
+	}
+	var lastError string
+	if peer.LastError != "" {
+		lastError = " / [red]" + tview.Escape(peer.LastError) + "[white]"
+	}
+	return fmt.Sprintf(
+		" [white]%4d %45s %-3s [%s]%-5s[white] [%s]%7s[white] %-8s %8s %14s  %s%s\n",
+		peerNbr,
+		tview.Escape(fmt.Sprintf("%s:%d", peer.IP, peer.Port)),
+		peer.Direction,
+		color,
+		rtt,
+		failuresColor,
+		failures,
+		tview.Escape(peer.Pool),
+		distance,
+		firstSeen,
+		tview.Escape(location),
+		lastError,
+	)
+}
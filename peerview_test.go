@@ -0,0 +1,215 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestFormatWidePeerRow(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	testDefs := []struct {
+		name     string
+		peer     Peer
+		expected []string
+	}{
+		{
+			name: "fast",
+			peer: Peer{
+				IP:        "203.0.113.1",
+				Port:      3001,
+				Direction: "o",
+				RTT:       25,
+				Location:  "Amsterdam, NL",
+				Checks:    4,
+			},
+			expected: []string{
+				"203.0.113.1:3001 o   [green]25   ",
+				"[white]    0/4",
+				"   ---            ---  Amsterdam",
+				"  Amsterdam, NL\n",
+			},
+		},
+		{
+			name: "slow",
+			peer: Peer{
+				IP:        "2001:db8::1",
+				Port:      6000,
+				Direction: "i+o",
+				RTT:       150,
+				Location:  "US",
+				Hostname:  "relay1.example.com",
+				Pool:      "BLINK",
+				Distance:  5870.6,
+				Checks:    4,
+				Failures:  1,
+				FirstSeen: now.Add(-90*time.Minute - 1500*time.Millisecond),
+			},
+			expected: []string{
+				"2001:db8::1:6000 i+o [red]150  ",
+				"[yellow]    1/4",
+				"BLINK      5871km    1h30m1s ago",
+				"  US / relay1.example.com\n",
+			},
+		},
+		{
+			name: "medium",
+			peer: Peer{
+				IP:       "203.0.113.2",
+				Port:     3001,
+				RTT:      75,
+				Location: "DE",
+			},
+			expected: []string{
+				"[yellow]75   ",
+				"[white]    ---",
+			},
+		},
+		{
+			name:     "very slow",
+			peer:     Peer{IP: "203.0.113.3", Port: 3001, RTT: 250},
+			expected: []string{"[fuchsia]250  "},
+		},
+		{
+			name: "unreachable",
+			peer: Peer{
+				IP:        "203.0.113.4",
+				Port:      3001,
+				RTT:       99999,
+				Location:  "---",
+				Checks:    3,
+				Failures:  3,
+				LastError: "dial tcp: [timeout]",
+			},
+			expected: []string{
+				"[fuchsia]---  ",
+				"[fuchsia]    3/3",
+				" / [red]dial tcp: [timeout[][white]\n",
+			},
+		},
+	}
+	var widths []int
+	for _, testDef := range testDefs {
+		got := formatWidePeerRow(7, &testDef.peer, now)
+		if !strings.HasPrefix(got, " [white]   7 ") {
+			t.Errorf("%s: got %q, expected the peer number", testDef.name, got)
+		}
+		for _, expected := range testDef.expected {
+			if !strings.Contains(got, expected) {
+				t.Errorf(
+					"%s: expected %q in %q",
+					testDef.name,
+					expected,
+					got,
+				)
+			}
+		}
+		// Columns line up until the geolocation
+		idx := strings.LastIndex(got, "  "+testDef.peer.Location)
+		widths = append(widths, tview.TaggedStringWidth(got[:idx]))
+	}
+	for i, width := range widths {
+		if width != widths[0] {
+			t.Errorf(
+				"%s: got width %d, expected %d",
+				testDefs[i].name,
+				width,
+				widths[0],
+			)
+		}
+	}
+}
+
+func TestGetPeersPageText(t *testing.T) {
+	oldCheckPeers := checkPeers
+	t.Cleanup(func() {
+		checkPeers = oldCheckPeers
+		peerStats = PeerStats{}
+		peersFiltered = nil
+		peerSortDescending.Store(false)
+	})
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	setPeerFixture(3)
+	checkPeers = false
+	text := getPeersPageText(now)
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf(
+			"got %d lines, expected a header and 3 peers:\n%s",
+			len(lines),
+			text,
+		)
+	}
+	if !strings.Contains(lines[0], "FIRST SEEN") {
+		t.Errorf("got header %q, expected it to contain FIRST SEEN", lines[0])
+	}
+	for i, peer := range peerStats.RTTresultsSlice {
+		expected := strings.TrimSuffix(
+			formatWidePeerRow(i+1, peer, now),
+			"\n",
+		)
+		if lines[i+1] != expected {
+			t.Errorf("got %q, expected %q", lines[i+1], expected)
+		}
+	}
+	// Only the analysis progress is shown while peers are checked
+	checkPeers = true
+	text = getPeersPageText(now)
+	if strings.Contains(text, "FIRST SEEN") ||
+		!strings.Contains(text, "Peer analysis started") {
+		t.Errorf("got %q, expected the analysis progress", text)
+	}
+}
+
+func TestGetStartPage(t *testing.T) {
+	testDefs := []struct {
+		name     string
+		expected string
+		warning  bool
+	}{
+		// The main page is the default
+		{name: "", expected: "Main"},
+		{name: "main", expected: "Main"},
+		{name: "peers", expected: "Peers"},
+		{name: " Peers ", expected: "Peers"},
+		{name: "PEERS", expected: "Peers"},
+		// Unknown pages fall back to the main page
+		{name: "logs", expected: "Main", warning: true},
+	}
+	for _, testDef := range testDefs {
+		buf := setLogCapture(t)
+		if got := getStartPage(testDef.name); got != testDef.expected {
+			t.Errorf(
+				"%q: got %s, expected %s",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+		warned := strings.Contains(buf.String(), "unknown start page")
+		if warned != testDef.warning {
+			t.Errorf(
+				"%q: got warning %v, expected %v",
+				testDef.name,
+				warned,
+				testDef.warning,
+			)
+		}
+	}
+}
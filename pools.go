@@ -0,0 +1,109 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// Known stake pool relays, mapping relay IP addresses to pool tickers
+var poolRelays map[string]string
+
+// Loads known stake pool relays from a file
+//
+// The csv format has one relay per line as "ip,ticker". The json format is
+// an object mapping pool tickers to lists of relay IP addresses, like
+// {"ABC": ["192.0.2.1", "192.0.2.2"]}.
+func loadPoolRelays(path string, format string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var relays map[string]string
+	switch strings.ToLower(format) {
+	case "csv":
+		relays, err = parsePoolRelaysCsv(f)
+	case "json":
+		relays, err = parsePoolRelaysJson(f)
+	default:
+		return fmt.Errorf("unknown pool relays format: %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse pool relays: %w", err)
+	}
+	poolRelays = relays
+	return nil
+}
+
+func parsePoolRelaysCsv(r io.Reader) (map[string]string, error) {
+	ret := make(map[string]string)
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		addPoolRelay(ret, record[0], record[1])
+	}
+	return ret, nil
+}
+
+func parsePoolRelaysJson(r io.Reader) (map[string]string, error) {
+	var pools map[string][]string
+	if err := json.NewDecoder(r).Decode(&pools); err != nil {
+		return nil, err
+	}
+	ret := make(map[string]string)
+	for ticker, relays := range pools {
+		for _, relay := range relays {
+			addPoolRelay(ret, relay, ticker)
+		}
+	}
+	return ret, nil
+}
+
+// Adds a relay using its normalized IP address, skipping anything which
+// isn't an IP address
+func addPoolRelay(relays map[string]string, address string, ticker string) {
+	ip := net.ParseIP(strings.TrimSpace(address))
+	ticker = strings.TrimSpace(ticker)
+	if ip == nil || ticker == "" {
+		return
+	}
+	relays[ip.String()] = ticker
+}
+
+// Returns the ticker of the pool a peer IP address is a known relay for, or
+// an empty string
+func getPoolTicker(address string) string {
+	if poolRelays == nil {
+		return ""
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ""
+	}
+	return poolRelays[ip.String()]
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Writes a pool relays file, returning its path
+func writePoolRelaysFixture(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPoolRelays(t *testing.T) {
+	t.Cleanup(func() {
+		poolRelays = nil
+	})
+	expected := map[string]string{
+		"192.0.2.1":   "BLINK",
+		"192.0.2.2":   "BLINK",
+		"2001:db8::1": "OTHER",
+	}
+	testDefs := []struct {
+		format  string
+		content string
+	}{
+		{
+			format: "csv",
+			content: "# ip,ticker\n" +
+				"192.0.2.1,BLINK\n" +
+				" 192.0.2.2 , BLINK \n" +
+				"2001:DB8:0::1,OTHER\n" +
+				// Hostnames and missing tickers are skipped
+				"relay.example.com,SKIP\n" +
+				"192.0.2.3,\n",
+		},
+		{
+			format: "JSON",
+			content: `{
+				"BLINK": ["192.0.2.1", "::ffff:192.0.2.2"],
+				"OTHER": ["2001:db8::1", "relay.example.com"]
+			}`,
+		},
+	}
+	for _, testDef := range testDefs {
+		poolRelays = nil
+		path := writePoolRelaysFixture(t, "relays", testDef.content)
+		if err := loadPoolRelays(path, testDef.format); err != nil {
+			t.Fatalf("%s: unexpected error: %s", testDef.format, err)
+		}
+		if !reflect.DeepEqual(poolRelays, expected) {
+			t.Errorf(
+				"%s: got %v, expected %v",
+				testDef.format,
+				poolRelays,
+				expected,
+			)
+		}
+	}
+}
+
+func TestLoadPoolRelaysInvalid(t *testing.T) {
+	t.Cleanup(func() {
+		poolRelays = nil
+	})
+	poolRelays = map[string]string{"192.0.2.1": "BLINK"}
+	testDefs := []struct {
+		format   string
+		content  string
+		expected string
+	}{
+		{format: "yaml", content: "", expected: "unknown pool relays format"},
+		{
+			format:   "csv",
+			content:  "192.0.2.1,BLINK,extra\n",
+			expected: "failed to parse pool relays",
+		},
+		{
+			format:   "json",
+			content:  `["192.0.2.1"]`,
+			expected: "failed to parse pool relays",
+		},
+	}
+	for _, testDef := range testDefs {
+		path := writePoolRelaysFixture(t, "relays", testDef.content)
+		err := loadPoolRelays(path, testDef.format)
+		if err == nil || !strings.Contains(err.Error(), testDef.expected) {
+			t.Errorf(
+				"%s: got error %v, expected %q",
+				testDef.format,
+				err,
+				testDef.expected,
+			)
+		}
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := loadPoolRelays(missing, "csv"); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+	// Relays which were already loaded are kept
+	if poolRelays["192.0.2.1"] != "BLINK" {
+		t.Errorf("got %v, expected the loaded relays to be kept", poolRelays)
+	}
+}
+
+func TestGetPoolTicker(t *testing.T) {
+	t.Cleanup(func() {
+		poolRelays = nil
+	})
+	// Without a relays file, there's nothing to annotate
+	poolRelays = nil
+	if got := getPoolTicker("192.0.2.1"); got != "" {
+		t.Errorf("got %q, expected no ticker", got)
+	}
+	poolRelays = map[string]string{
+		"192.0.2.1":   "BLINK",
+		"2001:db8::1": "OTHER",
+	}
+	testDefs := []struct {
+		address  string
+		expected string
+	}{
+		{address: "192.0.2.1", expected: "BLINK"},
+		{address: "::ffff:192.0.2.1", expected: "BLINK"},
+		{address: "2001:0db8:0000::1", expected: "OTHER"},
+		{address: "192.0.2.9", expected: ""},
+		{address: "not-an-ip", expected: ""},
+	}
+	for _, testDef := range testDefs {
+		if got := getPoolTicker(testDef.address); got != testDef.expected {
+			t.Errorf(
+				"%s: got %q, expected %q",
+				testDef.address,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	netutil "github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Metrics we use from the node process, which allows replacing gopsutil
+type nodeProcess interface {
+	Pid() int32
+	Name(ctx context.Context) (string, error)
+	Cmdline(ctx context.Context) ([]string, error)
+	CreateTime(ctx context.Context) (int64, error)
+	CPUPercent(ctx context.Context) (float64, error)
+	MemoryInfo(ctx context.Context) (*process.MemoryInfoStat, error)
+	NumFDs(ctx context.Context) (int32, error)
+	NumThreads(ctx context.Context) (int32, error)
+	FDLimit(ctx context.Context) (uint64, error)
+	Connections(
+		ctx context.Context,
+		kind string,
+	) ([]netutil.ConnectionStat, error)
+	Children(ctx context.Context) ([]nodeProcess, error)
+}
+
+// A nodeProcess backed by gopsutil
+type gopsutilProcess struct {
+	proc *process.Process
+}
+
+func newNodeProcess(p *process.Process) nodeProcess {
+	return &gopsutilProcess{proc: p}
+}
+
+func (p *gopsutilProcess) Pid() int32 {
+	return p.proc.Pid
+}
+
+func (p *gopsutilProcess) Name(ctx context.Context) (string, error) {
+	return p.proc.NameWithContext(ctx)
+}
+
+func (p *gopsutilProcess) Cmdline(ctx context.Context) ([]string, error) {
+	return p.proc.CmdlineSliceWithContext(ctx)
+}
+
+func (p *gopsutilProcess) CreateTime(ctx context.Context) (int64, error) {
+	return p.proc.CreateTimeWithContext(ctx)
+}
+
+func (p *gopsutilProcess) CPUPercent(ctx context.Context) (float64, error) {
+	return p.proc.CPUPercentWithContext(ctx)
+}
+
+func (p *gopsutilProcess) MemoryInfo(
+	ctx context.Context,
+) (*process.MemoryInfoStat, error) {
+	return p.proc.MemoryInfoWithContext(ctx)
+}
+
+func (p *gopsutilProcess) NumFDs(ctx context.Context) (int32, error) {
+	return p.proc.NumFDsWithContext(ctx)
+}
+
+func (p *gopsutilProcess) NumThreads(ctx context.Context) (int32, error) {
+	return p.proc.NumThreadsWithContext(ctx)
+}
+
+// Returns the soft limit on open file descriptors, or 0 when unlimited
+func (p *gopsutilProcess) FDLimit(ctx context.Context) (uint64, error) {
+	limits, err := p.proc.RlimitWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, limit := range limits {
+		if limit.Resource == process.RLIMIT_NOFILE {
+			// Treat unlimited as no limit
+			if limit.Soft == math.MaxUint64 {
+				return 0, nil
+			}
+			return limit.Soft, nil
+		}
+	}
+	return 0, errors.New("no open file limit found")
+}
+
+func (p *gopsutilProcess) Connections(
+	ctx context.Context,
+	kind string,
+) ([]netutil.ConnectionStat, error) {
+	return netutil.ConnectionsPidWithContext(ctx, kind, p.proc.Pid)
+}
+
+// Returns the direct child processes, which is empty when there are none
+func (p *gopsutilProcess) Children(ctx context.Context) ([]nodeProcess, error) {
+	children, err := p.proc.ChildrenWithContext(ctx)
+	if err != nil {
+		if errors.Is(err, process.ErrorNoChildren) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ret := make([]nodeProcess, 0, len(children))
+	for _, child := range children {
+		ret = append(ret, newNodeProcess(child))
+	}
+	return ret, nil
+}
+
+// How often the node's child processes are looked up, since it's costly
+const processChildrenRefresh = 30 * time.Second
+
+// The node's descendant processes, from the last lookup
+var (
+	processChildren        []nodeProcess
+	processChildrenPid     int32
+	processChildrenUpdated time.Time
+)
+
+// Returns the descendant processes of the node process, looking them up again
+// only periodically or when the node process changes
+func getProcessChildren(
+	ctx context.Context,
+	proc nodeProcess,
+	now time.Time,
+) []nodeProcess {
+	if proc.Pid() == processChildrenPid &&
+		now.Sub(processChildrenUpdated) < processChildrenRefresh {
+		return processChildren
+	}
+	processChildren = getProcessDescendants(ctx, proc)
+	processChildrenPid = proc.Pid()
+	processChildrenUpdated = now
+	return processChildren
+}
+
+// Returns all descendant processes of a process
+func getProcessDescendants(ctx context.Context, proc nodeProcess) []nodeProcess {
+	children, err := proc.Children(ctx)
+	if err != nil {
+		slog.Debug(
+			"failed to get child processes",
+			"pid", proc.Pid(),
+			"error", err,
+		)
+		return nil
+	}
+	ret := append([]nodeProcess(nil), children...)
+	for _, child := range children {
+		ret = append(ret, getProcessDescendants(ctx, child)...)
+	}
+	return ret
+}
+
+// Returns the CPU percent and RSS summed over processes, skipping any which
+// have since exited
+func sumProcessUsage(
+	ctx context.Context,
+	procs []nodeProcess,
+) (float64, uint64) {
+	var cpuPercent float64
+	var rss uint64
+	for _, proc := range procs {
+		procCPU, err := proc.CPUPercent(ctx)
+		if err != nil {
+			continue
+		}
+		procMemory, err := proc.MemoryInfo(ctx)
+		if err != nil {
+			continue
+		}
+		cpuPercent += procCPU
+		rss += procMemory.RSS
+	}
+	return cpuPercent, rss
+}
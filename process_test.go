@@ -0,0 +1,432 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	netutil "github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// A nodeProcess with fixed values
+type fakeProcess struct {
+	pid      int32
+	name     string
+	cmdline  []string
+	created  int64
+	cpu      float64
+	rss      uint64
+	fds      int32
+	fdLimit  uint64
+	threads  int32
+	conns    map[string][]netutil.ConnectionStat
+	children []nodeProcess
+	// Returned by every call which can fail, like for an exited process
+	err error
+	// Number of child process lookups
+	childrenCalls int
+}
+
+func (p *fakeProcess) Pid() int32 { return p.pid }
+
+func (p *fakeProcess) Name(ctx context.Context) (string, error) {
+	return p.name, p.err
+}
+
+func (p *fakeProcess) Cmdline(ctx context.Context) ([]string, error) {
+	return p.cmdline, p.err
+}
+
+func (p *fakeProcess) CreateTime(ctx context.Context) (int64, error) {
+	return p.created, p.err
+}
+
+func (p *fakeProcess) CPUPercent(ctx context.Context) (float64, error) {
+	return p.cpu, p.err
+}
+
+func (p *fakeProcess) MemoryInfo(
+	ctx context.Context,
+) (*process.MemoryInfoStat, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &process.MemoryInfoStat{RSS: p.rss}, nil
+}
+
+func (p *fakeProcess) NumFDs(ctx context.Context) (int32, error) {
+	return p.fds, p.err
+}
+
+func (p *fakeProcess) NumThreads(ctx context.Context) (int32, error) {
+	return p.threads, p.err
+}
+
+func (p *fakeProcess) FDLimit(ctx context.Context) (uint64, error) {
+	return p.fdLimit, p.err
+}
+
+func (p *fakeProcess) Connections(
+	ctx context.Context,
+	kind string,
+) ([]netutil.ConnectionStat, error) {
+	return p.conns[kind], p.err
+}
+
+func (p *fakeProcess) Children(ctx context.Context) ([]nodeProcess, error) {
+	p.childrenCalls++
+	return p.children, p.err
+}
+
+// Returns the PIDs of processes
+func getPids(procs []nodeProcess) []int32 {
+	ret := make([]int32, 0, len(procs))
+	for _, proc := range procs {
+		ret = append(ret, proc.Pid())
+	}
+	return ret
+}
+
+func TestGetProcessDescendants(t *testing.T) {
+	grandchild := &fakeProcess{pid: 4}
+	root := &fakeProcess{
+		pid: 1,
+		children: []nodeProcess{
+			&fakeProcess{pid: 2, children: []nodeProcess{grandchild}},
+			&fakeProcess{pid: 3},
+		},
+	}
+	got := getPids(getProcessDescendants(context.Background(), root))
+	expected := []int32{2, 3, 4}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+	failed := &fakeProcess{pid: 1, err: errors.New("no such process")}
+	if got := getProcessDescendants(context.Background(), failed); got != nil {
+		t.Errorf("got %v, expected no processes", getPids(got))
+	}
+}
+
+// Child processes are only looked up again periodically, or when the node
+// process changes
+func TestGetProcessChildren(t *testing.T) {
+	t.Cleanup(func() {
+		processChildren = nil
+		processChildrenPid = 0
+		processChildrenUpdated = time.Time{}
+	})
+	start := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	proc := &fakeProcess{
+		pid:      1,
+		children: []nodeProcess{&fakeProcess{pid: 2}},
+	}
+	testDefs := []struct {
+		proc   *fakeProcess
+		offset time.Duration
+		calls  int
+	}{
+		{proc: proc, offset: 0, calls: 1},
+		{proc: proc, offset: processChildrenRefresh - time.Second, calls: 1},
+		{proc: proc, offset: processChildrenRefresh, calls: 2},
+		{proc: &fakeProcess{pid: 5}, offset: processChildrenRefresh, calls: 1},
+	}
+	for i, testDef := range testDefs {
+		getProcessChildren(
+			context.Background(),
+			testDef.proc,
+			start.Add(testDef.offset),
+		)
+		if testDef.proc.childrenCalls != testDef.calls {
+			t.Errorf(
+				"lookup %d: got %d child lookups, expected %d",
+				i,
+				testDef.proc.childrenCalls,
+				testDef.calls,
+			)
+		}
+	}
+}
+
+func TestSumProcessUsage(t *testing.T) {
+	procs := []nodeProcess{
+		&fakeProcess{pid: 2, cpu: 10.5, rss: 100},
+		&fakeProcess{pid: 3, cpu: 4.5, rss: 50},
+		// Exited processes are skipped
+		&fakeProcess{pid: 4, cpu: 99, rss: 999, err: errors.New("exited")},
+	}
+	cpu, rss := sumProcessUsage(context.Background(), procs)
+	if cpu != 15 || rss != 150 {
+		t.Errorf("got %v%%, %d bytes, expected 15%%, 150 bytes", cpu, rss)
+	}
+}
+
+func TestGetProcessThreads(t *testing.T) {
+	testDefs := []struct {
+		proc     nodeProcess
+		expected int32
+	}{
+		{proc: nil, expected: 0},
+		{proc: &fakeProcess{pid: 0, threads: 42}, expected: 0},
+		{
+			proc:     &fakeProcess{pid: 1, err: errors.New("exited")},
+			expected: 0,
+		},
+		{proc: &fakeProcess{pid: 1, threads: 42}, expected: 42},
+	}
+	for i, testDef := range testDefs {
+		got := getProcessThreads(context.Background(), testDef.proc)
+		if got != testDef.expected {
+			t.Errorf("process %d: got %d, expected %d", i, got, testDef.expected)
+		}
+	}
+}
+
+func TestGetUptimes(t *testing.T) {
+	oldNow, oldUptimes := timeNow, uptimes
+	t.Cleanup(func() {
+		timeNow = oldNow
+		uptimes = oldUptimes
+	})
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	uptimes = 0
+	proc := &fakeProcess{pid: 1, created: now.Add(-time.Hour).UnixMilli()}
+	if got := getUptimes(context.Background(), proc); got != 3600 {
+		t.Errorf("got %d, expected %d", got, 3600)
+	}
+	// The last uptime is kept when the process can't be read
+	proc.err = errors.New("exited")
+	if got := getUptimes(context.Background(), proc); got != 3600 {
+		t.Errorf("got %d, expected %d", got, 3600)
+	}
+}
+
+// Child process usage is included in the Resources panel when aggregating
+func TestGetResourceTextAggregateChildren(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	t.Cleanup(func() {
+		cfg.App.AggregateChildren = false
+		processChildren = nil
+		processChildrenPid = 0
+		processChildrenUpdated = time.Time{}
+	})
+	cfg.App.AggregateChildren = true
+	proc := processMetrics.(*fakeProcess)
+	proc.children = []nodeProcess{
+		&fakeProcess{pid: 2, cpu: 6.544, rss: 1 << 30},
+	}
+	text := getResourceText(context.Background())
+	for _, expected := range []string{
+		"CPU (sys)  : [white]130.00%",
+		"Mem (RSS)  : [white]13.0[blue]GiB",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in:\n%s", expected, text)
+		}
+	}
+}
+
+// The whole process tree is summed, and only when aggregating
+func TestGetResourceTextProcessTree(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	t.Cleanup(func() {
+		cfg.App.AggregateChildren = false
+		processChildren = nil
+		processChildrenPid = 0
+		processChildrenUpdated = time.Time{}
+	})
+	testDefs := []struct {
+		aggregate bool
+		expected  []string
+		lookups   int
+	}{
+		{
+			aggregate: false,
+			expected: []string{
+				"CPU (sys)  : [white]123.46%",
+				"Mem (RSS)  : [white]12.0[blue]GiB",
+			},
+			lookups: 0,
+		},
+		{
+			aggregate: true,
+			expected: []string{
+				"CPU (sys)  : [white]130.00%",
+				"Mem (RSS)  : [white]14.0[blue]GiB",
+			},
+			lookups: 1,
+		},
+	}
+	for _, testDef := range testDefs {
+		processChildren = nil
+		processChildrenPid = 0
+		processChildrenUpdated = time.Time{}
+		cfg.App.AggregateChildren = testDef.aggregate
+		proc := processMetrics.(*fakeProcess)
+		proc.childrenCalls = 0
+		proc.children = []nodeProcess{
+			&fakeProcess{
+				pid: 2,
+				cpu: 1.5,
+				rss: 1 << 30,
+				children: []nodeProcess{
+					&fakeProcess{pid: 3, cpu: 5.044, rss: 1 << 30},
+				},
+			},
+		}
+		text := getResourceText(context.Background())
+		for _, expected := range testDef.expected {
+			if !strings.Contains(text, expected) {
+				t.Errorf(
+					"aggregate %v: expected %q in:\n%s",
+					testDef.aggregate,
+					expected,
+					text,
+				)
+			}
+		}
+		if proc.childrenCalls != testDef.lookups {
+			t.Errorf(
+				"aggregate %v: got %d child lookups, expected %d",
+				testDef.aggregate,
+				proc.childrenCalls,
+				testDef.lookups,
+			)
+		}
+	}
+}
+
+func TestGetResourceTextProcessErrors(t *testing.T) {
+	setPanelFixtures(t)
+	processMetrics.(*fakeProcess).err = errors.New("no such process")
+	oldFailCount := failCount
+	t.Cleanup(func() { failCount = oldFailCount })
+	text := getResourceText(context.Background())
+	if !strings.HasPrefix(text, "cannot parse CPU usage: no such process") {
+		t.Errorf("got %q, expected a CPU usage error", text)
+	}
+}
+
+func TestGetProcessMetricsPidFirst(t *testing.T) {
+	cfg := config.GetConfig()
+	oldPid := cfg.Node.Pid
+	oldPidFile := cfg.Node.PidFile
+	t.Cleanup(func() {
+		cfg.Node.Pid = oldPid
+		cfg.Node.PidFile = oldPidFile
+	})
+	self := int32(os.Getpid())
+	dir := t.TempDir()
+	writePidFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	selfPidFile := writePidFile("self.pid", strconv.Itoa(os.Getpid())+"\n")
+	invalidPidFile := writePidFile("invalid.pid", "cardano-node\n")
+	testDefs := []struct {
+		pid     int32
+		pidFile string
+		err     string
+	}{
+		// The PID takes priority over the PID file
+		{pid: self, pidFile: invalidPidFile},
+		{pidFile: selfPidFile},
+		{pidFile: invalidPidFile, err: "failed to parse pid file"},
+		{
+			pidFile: filepath.Join(dir, "missing.pid"),
+			err:     "failed to read pid file",
+		},
+		{pid: math.MaxInt32, err: "failed to find process"},
+	}
+	for _, testDef := range testDefs {
+		cfg.Node.Pid = testDef.pid
+		cfg.Node.PidFile = testDef.pidFile
+		proc, err := getProcessMetrics(context.Background())
+		if testDef.err != "" {
+			if err == nil || !strings.Contains(err.Error(), testDef.err) {
+				t.Errorf(
+					"pid %d, pid file %q: got error %v, expected %q",
+					testDef.pid,
+					testDef.pidFile,
+					err,
+					testDef.err,
+				)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if proc.Pid() != self {
+			t.Errorf("got pid %d, expected %d", proc.Pid(), self)
+		}
+	}
+}
+
+func TestGetProcessMetricsByPid(t *testing.T) {
+	// A process which has exited
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("unable to run a process: %s", err)
+	}
+	exited := int32(cmd.Process.Pid)
+	testDefs := []struct {
+		pid int32
+		err string
+	}{
+		{pid: int32(os.Getpid())},
+		{pid: 0, err: "invalid pid: 0"},
+		{pid: -1, err: "invalid pid: -1"},
+		{pid: exited, err: fmt.Sprintf("failed to find process %d", exited)},
+	}
+	for _, testDef := range testDefs {
+		p, err := getProcessMetricsByPid(context.Background(), testDef.pid)
+		if testDef.err == "" {
+			if err != nil {
+				t.Errorf("pid %d: unexpected error: %s", testDef.pid, err)
+			} else if p.Pid != testDef.pid {
+				t.Errorf("got pid %d, expected %d", p.Pid, testDef.pid)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), testDef.err) {
+			t.Errorf(
+				"pid %d: got error %v, expected %q",
+				testDef.pid,
+				err,
+				testDef.err,
+			)
+		}
+	}
+}
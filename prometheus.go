@@ -15,23 +15,83 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/expfmt"
+
+	"github.com/blinklabs-io/nview/internal/config"
 )
 
 // Track current epoch
 var currentEpoch uint32 = 0
 
-func setCurrentEpoch() {
+// Last metric epoch which failed validation, to avoid repeated warnings
+var epochMismatchLast uint64
+
+// Sets the current epoch from the node metrics, falling back to the epoch
+// computed from the wall clock when metrics are unavailable
+func setCurrentEpoch() error {
+	computed := computeEpochFromSlot()
 	if promMetrics != nil {
+		// A syncing node reports an earlier epoch, but never a later one
+		// unless the genesis values don't match the node's network
+		if computed != 0 && promMetrics.EpochNum > computed &&
+			promMetrics.EpochNum != epochMismatchLast {
+			epochMismatchLast = promMetrics.EpochNum
+			slog.Warn(
+				"node epoch is ahead of the epoch computed from genesis, check the network configuration",
+				"epoch", promMetrics.EpochNum,
+				"computed", computed,
+			)
+		}
 		currentEpoch = uint32(promMetrics.EpochNum)
+		return nil
+	}
+	if computed == 0 {
+		return errors.New("no node metrics or genesis values to derive epoch")
+	}
+	currentEpoch = uint32(computed)
+	return errors.New("no node metrics, using derived epoch")
+}
+
+// Detects the start of a new epoch from successive epoch values
+type epochTransition struct {
+	last uint32
+}
+
+// Updates the detector with the latest epoch and returns true when it has
+// moved forward from a previously seen epoch
+func (t *epochTransition) update(epoch uint32) bool {
+	started := t.last != 0 && epoch > t.last
+	t.last = epoch
+	return started
+}
+
+var epochTracker epochTransition
+
+// Handles the start of a new epoch, notifying the operator once synced, since
+// epochs pass quickly while syncing
+func onEpochStarted(epoch uint32) {
+	// Reset per-epoch baselines
+	luckBase = nil
+	recordEvent(fmt.Sprintf("Epoch %d started", epoch))
+	if !isSynced(promMetrics, getSlotTipRef()) {
+		return
 	}
+	slog.Info("epoch started", "epoch", epoch)
+	setFooterNotice(fmt.Sprintf("Epoch %d started", epoch))
 }
 
 var promMetrics *PromMetrics
@@ -63,6 +123,8 @@ type PromMetrics struct {
 	BlocksW1s           float64 `json:"cardano_node_metrics_blockfetchclient_blockdelay_cdfOne"`
 	BlocksW3s           float64 `json:"cardano_node_metrics_blockfetchclient_blockdelay_cdfThree"`
 	BlocksW5s           float64 `json:"cardano_node_metrics_blockfetchclient_blockdelay_cdfFive"`
+	BlockDelayP50       float64 `json:"cardano_node_metrics_blockfetchclient_blockdelay_p50"`
+	BlockDelayP95       float64 `json:"cardano_node_metrics_blockfetchclient_blockdelay_p95"`
 	PeersCold           uint64  `json:"cardano_node_metrics_peerSelection_cold"`
 	PeersWarm           uint64  `json:"cardano_node_metrics_peerSelection_warm"`
 	PeersHot            uint64  `json:"cardano_node_metrics_peerSelection_hot"`
@@ -71,65 +133,438 @@ type PromMetrics struct {
 	ConnUniDir          uint64  `json:"cardano_node_metrics_connectionManager_unidirectionalConns"`
 	ConnBiDir           uint64  `json:"cardano_node_metrics_connectionManager_duplexConns"`
 	ConnDuplex          uint64  `json:"cardano_node_metrics_connectionManager_prunableConns"`
+	// Version and revision from the node's build info metric, if any
+	BuildVersion  string `json:"-"`
+	BuildRevision string `json:"-"`
+	// All parsed metric values by name, for the raw metrics page
+	Raw map[string]float64 `json:"-"`
+}
+
+// Default metric aliases, mapping metric names used by some node versions to
+// the canonical names we read
+var defaultMetricAliases = map[string]string{
+	// Names used by the new tracing system
+	"cardano_node_metrics_slotsMissed_int":                  "cardano_node_metrics_slotsMissedNum_int",
+	"cardano_node_metrics_blockfetchclient_blockdelay_real": "cardano_node_metrics_blockfetchclient_blockdelay_s",
+}
+
+// Returns the metric aliases, with configured aliases added to and
+// overriding the defaults
+func getMetricAliases() map[string]string {
+	cfg := config.GetConfig()
+	ret := make(
+		map[string]string,
+		len(defaultMetricAliases)+len(cfg.App.MetricAliases),
+	)
+	for alias, canonical := range defaultMetricAliases {
+		ret[alias] = canonical
+	}
+	for alias, canonical := range cfg.App.MetricAliases {
+		ret[alias] = canonical
+	}
+	return ret
+}
+
+// Copies aliased metric values to their canonical names. A metric reported
+// under its canonical name takes precedence over any alias.
+func applyMetricAliases(values map[string]float64, aliases map[string]string) {
+	for alias, canonical := range aliases {
+		value, ok := values[alias]
+		if !ok {
+			continue
+		}
+		if _, ok := values[canonical]; ok {
+			continue
+		}
+		values[canonical] = value
+	}
 }
 
 // Gets metrics from prometheus and return a PromMetrics instance
 func getPromMetrics(ctx context.Context) (*PromMetrics, error) {
 	var metrics *PromMetrics
 	var respBodyBytes []byte
-	respBodyBytes, statusCode, err := getNodeMetrics(ctx)
+	respBodyBytes, statusCode, err := metricsSource.Fetch(ctx)
 	if err != nil {
 		failCount++
+		logScrapeFailure(statusCode, err)
 		return metrics, fmt.Errorf("Failed getNodeMetrics: %s\n", err)
 	}
 	if statusCode != http.StatusOK {
 		failCount++
-		return metrics, fmt.Errorf("Failed HTTP: %d\n", statusCode)
+		err = fmt.Errorf("Failed HTTP: %d\n", statusCode)
+		logScrapeFailure(statusCode, err)
+		return metrics, err
 	}
 
-	b, err := prom2json(respBodyBytes)
+	families, err := parsePromMetricFamilies(respBodyBytes)
 	if err != nil {
 		failCount++
-		return metrics, fmt.Errorf("Failed prom2json: %s\n", err)
-	}
-
-	if err := json.Unmarshal(b, &metrics); err != nil {
-		failCount++
-		return metrics, fmt.Errorf("Failed JSON unmarshal: %s\n", err)
+		logScrapeFailure(statusCode, err)
+		return metrics, fmt.Errorf("Failed parsePromMetrics: %s\n", err)
 	}
+	values := getPromMetricValues(families)
+	applyMetricAliases(values, getMetricAliases())
+	metrics = newPromMetrics(values)
+	metrics.Raw = values
+	metrics.BuildVersion, metrics.BuildRevision = getBuildInfo(families)
 	failCount = 0
+	logScrapeSuccess()
 	return metrics, nil
 }
 
-// Converts a prometheus http response byte array into a JSON byte array
-func prom2json(prom []byte) ([]byte, error) {
-	// {"name": 0}
-	out := make(map[string]interface{})
-	b := []byte{}
-	parser := &expfmt.TextParser{}
-	families, err := parser.TextToMetricFamilies(
-		strings.NewReader(string(prom)),
-	)
+// Consecutive failed scrapes by the metrics worker, which is used to show
+// the node as unreachable
+var promFailures atomic.Uint32
+
+// Records the result of a scrape by the metrics worker, returning the number
+// of consecutive failures
+func recordScrapeResult(err error) uint32 {
 	if err != nil {
-		return b, err
+		return promFailures.Add(1)
+	}
+	promFailures.Store(0)
+	return 0
+}
+
+// Waits between metrics polls, which is replaced in tests
+var waitForPromPoll = func(ctx context.Context, d time.Duration) bool {
+	return sleepOrRefresh(ctx, d, refreshRequests)
+}
+
+// Polls the node metrics until the context is done, backing off exponentially
+// while the node is down, so it's polled less often, and returning to the
+// refresh interval on success
+func updatePromMetrics(ctx context.Context) {
+	cfg := config.GetConfig()
+	refresh := time.Second * time.Duration(cfg.Prometheus.Refresh)
+	backoff := refresh
+	for {
+		wait := refresh
+		prom, err := getPromMetrics(ctx)
+		recordScrapeResult(err)
+		if err != nil {
+			wait = backoff
+			backoff = min(backoff*2, max(promBackoffMax, refresh))
+		} else {
+			backoff = refresh
+		}
+		if err != nil && prom != nil {
+			failCount++
+		} else {
+			promMetrics = prom
+		}
+		if !waitForPromPoll(ctx, wait) {
+			return
+		}
+	}
+}
+
+// Track scrape failures for logging
+var scrapeFailures uint32 = 0
+var scrapeErrLast string
+
+// Logs a failed scrape at debug level, skipping repeats of the last error
+func logScrapeFailure(statusCode int, err error) {
+	scrapeFailures++
+	msg := strings.TrimSpace(err.Error())
+	if msg == scrapeErrLast {
+		return
+	}
+	scrapeErrLast = msg
+	slog.Debug(
+		"failed to scrape node metrics",
+		"status", statusCode,
+		"error", msg,
+		"failures", scrapeFailures,
+	)
+}
+
+// Logs once when scraping succeeds after one or more failures
+func logScrapeSuccess() {
+	if scrapeFailures > 0 {
+		slog.Info(
+			"node metrics connectivity recovered",
+			"failures", scrapeFailures,
+		)
+	}
+	scrapeFailures = 0
+	scrapeErrLast = ""
+}
+
+// Maps metric names to PromMetrics field indexes, using the JSON tags as the
+// metric names
+var promMetricFields = func() map[string]int {
+	ret := make(map[string]int)
+	t := reflect.TypeOf(PromMetrics{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("json"); name != "" && name != "-" {
+			ret[name] = i
+		}
+	}
+	return ret
+}()
+
+// Sets the PromMetrics field for a metric name, returning false for metrics
+// we don't track
+func (p *PromMetrics) set(name string, value float64) bool {
+	idx, ok := promMetricFields[name]
+	if !ok {
+		return false
+	}
+	field := reflect.ValueOf(p).Elem().Field(idx)
+	switch field.Kind() {
+	case reflect.Uint64:
+		if value < 0 {
+			value = 0
+		}
+		field.SetUint(uint64(value))
+	case reflect.Float64:
+		field.SetFloat(value)
+	default:
+		return false
 	}
+	return true
+}
+
+// Creates a PromMetrics instance from parsed metric values
+func newPromMetrics(values map[string]float64) *PromMetrics {
+	metrics := &PromMetrics{}
+	for name, value := range values {
+		metrics.set(name, value)
+	}
+	return metrics
+}
+
+// Parses a prometheus http response byte array into metric families
+//
+// The text parser fails on the first malformed line, so on failure each
+// family is parsed separately, skipping any which fail, rather than
+// discarding the whole response
+func parsePromMetricFamilies(
+	prom []byte,
+) (map[string]*dto.MetricFamily, error) {
+	parser := &expfmt.TextParser{}
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(prom))
+	if err == nil {
+		promSkippedLast = 0
+		return families, nil
+	}
+	families = make(map[string]*dto.MetricFamily)
+	var skipped int
+	for _, block := range splitPromFamilies(prom) {
+		parser := &expfmt.TextParser{}
+		blockFamilies, blockErr := parser.TextToMetricFamilies(
+			bytes.NewReader(block),
+		)
+		if blockErr != nil {
+			skipped++
+			continue
+		}
+		for name, family := range blockFamilies {
+			families[name] = family
+		}
+	}
+	if len(families) == 0 {
+		return nil, err
+	}
+	if skipped != promSkippedLast {
+		slog.Warn(
+			"skipped unparseable metric families",
+			"skipped", skipped,
+			"error", err,
+		)
+	}
+	promSkippedLast = skipped
+	return families, nil
+}
+
+// Count of metric families skipped in the last scrape, to avoid repeated
+// warnings
+var promSkippedLast int
+
+// Splits prometheus text into blocks of lines for each metric family. A block
+// starts at a HELP or TYPE comment for a new family, or a sample which
+// doesn't belong to the current family, like the untyped samples which
+// cardano-node exposes without comments.
+func splitPromFamilies(prom []byte) [][]byte {
+	var blocks [][]byte
+	var current []byte
+	var currentName string
+	var typed bool
+	flush := func() {
+		if len(current) > 0 {
+			blocks = append(blocks, current)
+		}
+		current = nil
+	}
+	for _, line := range bytes.Split(prom, []byte("\n")) {
+		trimmed := strings.TrimSpace(string(line))
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			fields := strings.Fields(trimmed)
+			if len(fields) >= 3 &&
+				(fields[1] == "HELP" || fields[1] == "TYPE") {
+				if fields[2] != currentName {
+					flush()
+					currentName = fields[2]
+				}
+				typed = true
+			}
+		} else {
+			name, _, _ := strings.Cut(trimmed, "{")
+			name, _, _ = strings.Cut(name, " ")
+			// Histogram and summary samples have suffixed names
+			belongs := name == currentName ||
+				(typed && strings.HasPrefix(name, currentName+"_"))
+			if !belongs {
+				flush()
+				currentName = name
+				typed = false
+			}
+		}
+		current = append(current, line...)
+		current = append(current, '\n')
+	}
+	flush()
+	return blocks
+}
+
+// Converts metric families into a map of metric names to values
+//
+// Families with multiple labelled samples, such as per-connection metrics, are
+// summed into the family name, and each sample is also kept under its
+// labelled name, like foo{bar="baz"}. Labelled histograms are only kept
+// under their labelled names.
+func getPromMetricValues(
+	families map[string]*dto.MetricFamily,
+) map[string]float64 {
+	out := make(map[string]float64)
 	for _, val := range families {
-		for _, m := range val.GetMetric() {
+		samples := val.GetMetric()
+		for _, m := range samples {
+			name := val.GetName()
+			labelled := len(samples) > 1
+			var value float64
 			switch val.GetType() {
 			case dto.MetricType_COUNTER:
-				out[val.GetName()] = m.GetCounter().GetValue()
+				value = m.GetCounter().GetValue()
 			case dto.MetricType_GAUGE:
-				out[val.GetName()] = m.GetGauge().GetValue()
+				value = m.GetGauge().GetValue()
 			case dto.MetricType_UNTYPED:
-				out[val.GetName()] = m.GetUntyped().GetValue()
+				value = m.GetUntyped().GetValue()
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				if labelled {
+					name = labelledMetricName(name, m)
+				}
+				addHistogramMetrics(out, name, m.GetHistogram())
+				continue
 			default:
-				return b, err
+				continue
+			}
+			if labelled {
+				out[name] += value
+				out[labelledMetricName(name, m)] = value
+			} else {
+				out[name] = value
 			}
 		}
 	}
-	b, err = json.MarshalIndent(out, "", "    ")
-	if err != nil {
-		return b, err
+	return out
+}
+
+// Returns the version and revision labels of the node's build info metric,
+// preferring cardano_build_info over the generic Go go_build_info
+func getBuildInfo(families map[string]*dto.MetricFamily) (string, string) {
+	var version, revision string
+	for name, val := range families {
+		if !strings.HasSuffix(name, "build_info") {
+			continue
+		}
+		for _, m := range val.GetMetric() {
+			var v, r string
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "version":
+					v = l.GetValue()
+				case "revision", "commit", "git_revision":
+					r = l.GetValue()
+				}
+			}
+			if v == "" {
+				continue
+			}
+			version, revision = v, r
+			if name != "go_build_info" {
+				return version, revision
+			}
+		}
+	}
+	return version, revision
+}
+
+// Returns a metric name with its labels, sorted by label name
+func labelledMetricName(name string, m *dto.Metric) string {
+	labels := m.GetLabel()
+	if len(labels) == 0 {
+		return name
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
+
+// Quantiles derived from histogram metrics
+var histogramQuantiles = map[string]float64{
+	"p50": 0.50,
+	"p95": 0.95,
+}
+
+// Adds derived values for a histogram metric
+//
+// A histogram named "foo" produces "foo_sum", "foo_count", and estimated
+// quantiles "foo_p50" and "foo_p95"
+func addHistogramMetrics(
+	out map[string]float64,
+	name string,
+	h *dto.Histogram,
+) {
+	out[name+"_sum"] = h.GetSampleSum()
+	out[name+"_count"] = float64(h.GetSampleCount())
+	for suffix, q := range histogramQuantiles {
+		out[name+"_"+suffix] = histogramQuantile(q, h)
+	}
+}
+
+// Estimates a quantile from cumulative histogram buckets, interpolating
+// linearly within a bucket like Prometheus' histogram_quantile()
+func histogramQuantile(q float64, h *dto.Histogram) float64 {
+	count := float64(h.GetSampleCount())
+	if count == 0 {
+		return 0
+	}
+	rank := q * count
+	var prevBound, prevCount float64
+	for _, b := range h.GetBucket() {
+		bound := b.GetUpperBound()
+		bucketCount := float64(b.GetCumulativeCount())
+		if bucketCount >= rank {
+			if math.IsInf(bound, 1) {
+				return prevBound
+			}
+			if bucketCount == prevCount {
+				return bound
+			}
+			return prevBound + (bound-prevBound)*
+				(rank-prevCount)/(bucketCount-prevCount)
+		}
+		prevBound = bound
+		prevCount = bucketCount
 	}
-	return b, nil
+	return prevBound
 }
@@ -0,0 +1,950 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+// Creates a PromMetrics instance with a JSON round-trip, which is how
+// metrics were populated before they were set directly
+func newPromMetricsJSON(values map[string]float64) (*PromMetrics, error) {
+	b, err := json.MarshalIndent(values, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	var metrics *PromMetrics
+	if err := json.Unmarshal(b, &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// Returns a value for every PromMetrics field, as integers for uint64 fields
+// and fractions for float64 fields, along with untracked metrics
+func getPromMetricsFixture(untracked int) map[string]float64 {
+	values := make(map[string]float64)
+	t := reflect.TypeOf(PromMetrics{})
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("json")
+		if name == "" || name == "-" {
+			continue
+		}
+		switch t.Field(i).Type.Kind() {
+		case reflect.Uint64:
+			values[name] = float64((i + 1) * 1000)
+		case reflect.Float64:
+			values[name] = float64(i) + 0.25
+		}
+	}
+	for i := 0; i < untracked; i++ {
+		values[fmt.Sprintf("untracked_metric_%d", i)] = float64(i)
+	}
+	return values
+}
+
+func TestNewPromMetricsMatchesJSON(t *testing.T) {
+	values := getPromMetricsFixture(10)
+	want, err := newPromMetricsJSON(values)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got := newPromMetrics(values)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, expected %+v", got, want)
+	}
+	// Every field with a metric name is populated
+	v := reflect.ValueOf(got).Elem()
+	for name, idx := range promMetricFields {
+		if v.Field(idx).IsZero() {
+			t.Errorf("field for %s was not set", name)
+		}
+	}
+}
+
+func TestPromMetricsSet(t *testing.T) {
+	testDefs := []struct {
+		name     string
+		value    float64
+		expected PromMetrics
+		ok       bool
+	}{
+		{
+			name:     "cardano_node_metrics_blockNum_int",
+			value:    123,
+			expected: PromMetrics{BlockNum: 123},
+			ok:       true,
+		},
+		// Negative values are clamped for unsigned fields
+		{
+			name:  "cardano_node_metrics_blockNum_int",
+			value: -5,
+			ok:    true,
+		},
+		{
+			name:     "cardano_node_metrics_density_real",
+			value:    -0.5,
+			expected: PromMetrics{Density: -0.5},
+			ok:       true,
+		},
+		{
+			name:  "untracked_metric",
+			value: 1,
+		},
+	}
+	for _, testDef := range testDefs {
+		var metrics PromMetrics
+		ok := metrics.set(testDef.name, testDef.value)
+		if ok != testDef.ok {
+			t.Errorf(
+				"set(%q, %v) returned %v, expected %v",
+				testDef.name,
+				testDef.value,
+				ok,
+				testDef.ok,
+			)
+		}
+		if !reflect.DeepEqual(metrics, testDef.expected) {
+			t.Errorf(
+				"set(%q, %v) got %+v, expected %+v",
+				testDef.name,
+				testDef.value,
+				metrics,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestNewPromMetricsClampsNegative(t *testing.T) {
+	values := getPromMetricsFixture(0)
+	for name, idx := range promMetricFields {
+		if reflect.TypeOf(PromMetrics{}).Field(idx).Type.Kind() ==
+			reflect.Uint64 {
+			values[name] = -1
+		}
+	}
+	got := newPromMetrics(values)
+	v := reflect.ValueOf(got).Elem()
+	for name, idx := range promMetricFields {
+		field := v.Field(idx)
+		if field.Kind() == reflect.Uint64 && field.Uint() != 0 {
+			t.Errorf("%s got %d, expected 0", name, field.Uint())
+		}
+	}
+	// The JSON round-trip failed the whole scrape on negative values
+	if _, err := newPromMetricsJSON(values); err == nil ||
+		!strings.Contains(err.Error(), "uint64") {
+		t.Errorf("expected a JSON error for negative values, got %v", err)
+	}
+}
+
+func BenchmarkNewPromMetrics(b *testing.B) {
+	values := getPromMetricsFixture(2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = newPromMetrics(values)
+	}
+}
+
+func BenchmarkNewPromMetricsJSON(b *testing.B) {
+	values := getPromMetricsFixture(2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := newPromMetricsJSON(values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Parses prometheus text into metric values for a test
+func getTestPromMetricValues(t *testing.T, prom string) map[string]float64 {
+	t.Helper()
+	families, err := parsePromMetricFamilies([]byte(prom))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return getPromMetricValues(families)
+}
+
+// Returns a histogram with cumulative bucket counts by upper bound
+func newTestHistogram(
+	count uint64,
+	bounds []float64,
+	counts []uint64,
+) *dto.Histogram {
+	h := &dto.Histogram{SampleCount: &count}
+	for i := range bounds {
+		h.Bucket = append(h.Bucket, &dto.Bucket{
+			UpperBound:      &bounds[i],
+			CumulativeCount: &counts[i],
+		})
+	}
+	return h
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	bounds := []float64{0.5, 1, 2, math.Inf(1)}
+	testDefs := []struct {
+		name     string
+		q        float64
+		h        *dto.Histogram
+		expected float64
+	}{
+		{
+			name:     "empty",
+			q:        0.5,
+			h:        newTestHistogram(0, nil, nil),
+			expected: 0,
+		},
+		{
+			name:     "bucket boundary",
+			q:        0.5,
+			h:        newTestHistogram(100, bounds, []uint64{50, 80, 95, 100}),
+			expected: 0.5,
+		},
+		{
+			name:     "interpolated",
+			q:        0.5,
+			h:        newTestHistogram(100, bounds, []uint64{20, 80, 95, 100}),
+			expected: 0.75,
+		},
+		{
+			name:     "upper bucket",
+			q:        0.95,
+			h:        newTestHistogram(100, bounds, []uint64{50, 80, 95, 100}),
+			expected: 2,
+		},
+		// The +Inf bucket has no upper bound, so use the highest bound
+		{
+			name:     "infinite bucket",
+			q:        0.95,
+			h:        newTestHistogram(100, bounds, []uint64{50, 80, 90, 100}),
+			expected: 2,
+		},
+	}
+	for _, testDef := range testDefs {
+		got := histogramQuantile(testDef.q, testDef.h)
+		if math.Abs(got-testDef.expected) > 1e-9 {
+			t.Errorf(
+				"%s: got %v, expected %v",
+				testDef.name,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestHistogramMetrics(t *testing.T) {
+	prom := `# HELP cardano_node_metrics_blockfetchclient_blockdelay Block delay
+# TYPE cardano_node_metrics_blockfetchclient_blockdelay histogram
+cardano_node_metrics_blockfetchclient_blockdelay_bucket{le="0.5"} 50
+cardano_node_metrics_blockfetchclient_blockdelay_bucket{le="1"} 80
+cardano_node_metrics_blockfetchclient_blockdelay_bucket{le="2"} 95
+cardano_node_metrics_blockfetchclient_blockdelay_bucket{le="+Inf"} 100
+cardano_node_metrics_blockfetchclient_blockdelay_sum 75.5
+cardano_node_metrics_blockfetchclient_blockdelay_count 100
+`
+	values := getTestPromMetricValues(t, prom)
+	expected := map[string]float64{
+		"cardano_node_metrics_blockfetchclient_blockdelay_sum":   75.5,
+		"cardano_node_metrics_blockfetchclient_blockdelay_count": 100,
+		"cardano_node_metrics_blockfetchclient_blockdelay_p50":   0.5,
+		"cardano_node_metrics_blockfetchclient_blockdelay_p95":   2,
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("got %v, expected %v", values, expected)
+	}
+	metrics := newPromMetrics(values)
+	if metrics.BlockDelayP50 != 0.5 || metrics.BlockDelayP95 != 2 {
+		t.Errorf(
+			"got p50 %v and p95 %v, expected 0.5 and 2",
+			metrics.BlockDelayP50,
+			metrics.BlockDelayP95,
+		)
+	}
+}
+
+func TestLabelledMetricName(t *testing.T) {
+	testDefs := []struct {
+		prom     string
+		expected []string
+	}{
+		{
+			prom:     "foo 1\n",
+			expected: []string{"foo"},
+		},
+		// Labels are sorted by name
+		{
+			prom:     "foo{b=\"2\",a=\"1\"} 1\n",
+			expected: []string{`foo{a="1",b="2"}`},
+		},
+		// Label values are quoted
+		{
+			prom:     "foo{a=\"x\\\"y\"} 1\n",
+			expected: []string{`foo{a="x\"y"}`},
+		},
+	}
+	for _, testDef := range testDefs {
+		families, err := parsePromMetricFamilies([]byte(testDef.prom))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var got []string
+		for name, family := range families {
+			for _, m := range family.GetMetric() {
+				got = append(got, labelledMetricName(name, m))
+			}
+		}
+		if !reflect.DeepEqual(got, testDef.expected) {
+			t.Errorf("got %q, expected %q", got, testDef.expected)
+		}
+	}
+}
+
+func TestLabelledMetrics(t *testing.T) {
+	prom := `# TYPE cardano_node_metrics_connectionManager_incomingConns gauge
+cardano_node_metrics_connectionManager_incomingConns{peer="a"} 2
+cardano_node_metrics_connectionManager_incomingConns{peer="b"} 3
+# TYPE delay histogram
+delay_bucket{peer="a",le="1"} 1
+delay_bucket{peer="a",le="+Inf"} 2
+delay_sum{peer="a"} 1.5
+delay_count{peer="a"} 2
+delay_bucket{peer="b",le="1"} 0
+delay_bucket{peer="b",le="+Inf"} 0
+delay_sum{peer="b"} 0
+delay_count{peer="b"} 0
+`
+	values := getTestPromMetricValues(t, prom)
+	expected := map[string]float64{
+		// Labelled samples are summed into the family name
+		"cardano_node_metrics_connectionManager_incomingConns":           5,
+		`cardano_node_metrics_connectionManager_incomingConns{peer="a"}`: 2,
+		`cardano_node_metrics_connectionManager_incomingConns{peer="b"}`: 3,
+		// Labelled histograms are only kept under their labelled names
+		`delay{peer="a"}_sum`:   1.5,
+		`delay{peer="a"}_count`: 2,
+		`delay{peer="a"}_p50`:   1,
+		`delay{peer="a"}_p95`:   1,
+		`delay{peer="b"}_sum`:   0,
+		`delay{peer="b"}_count`: 0,
+		`delay{peer="b"}_p50`:   0,
+		`delay{peer="b"}_p95`:   0,
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("got %v, expected %v", values, expected)
+	}
+	if metrics := newPromMetrics(values); metrics.ConnIncoming != 5 {
+		t.Errorf("got %d incoming connections, expected 5", metrics.ConnIncoming)
+	}
+}
+
+func TestApplyMetricAliases(t *testing.T) {
+	aliases := map[string]string{"old": "new"}
+	testDefs := []struct {
+		name     string
+		values   map[string]float64
+		expected map[string]float64
+	}{
+		{
+			name:     "alias only",
+			values:   map[string]float64{"old": 1},
+			expected: map[string]float64{"old": 1, "new": 1},
+		},
+		// The canonical name takes precedence over an alias
+		{
+			name:     "both",
+			values:   map[string]float64{"old": 1, "new": 2},
+			expected: map[string]float64{"old": 1, "new": 2},
+		},
+		{
+			name:     "canonical only",
+			values:   map[string]float64{"new": 2},
+			expected: map[string]float64{"new": 2},
+		},
+		{
+			name:     "neither",
+			values:   map[string]float64{"other": 3},
+			expected: map[string]float64{"other": 3},
+		},
+	}
+	for _, testDef := range testDefs {
+		applyMetricAliases(testDef.values, aliases)
+		if !reflect.DeepEqual(testDef.values, testDef.expected) {
+			t.Errorf(
+				"%s: got %v, expected %v",
+				testDef.name,
+				testDef.values,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetMetricAliases(t *testing.T) {
+	cfg := config.GetConfig()
+	oldAliases := cfg.App.MetricAliases
+	t.Cleanup(func() {
+		cfg.App.MetricAliases = oldAliases
+	})
+	// Configured aliases are added to and override the defaults
+	cfg.App.MetricAliases = map[string]string{
+		"my_block_num":                         "cardano_node_metrics_blockNum_int",
+		"cardano_node_metrics_slotsMissed_int": "cardano_node_metrics_Forge_didnt_adopt_int",
+	}
+	values := map[string]float64{
+		"my_block_num":                                          123,
+		"cardano_node_metrics_slotsMissed_int":                  4,
+		"cardano_node_metrics_blockfetchclient_blockdelay_real": 0.5,
+	}
+	applyMetricAliases(values, getMetricAliases())
+	metrics := newPromMetrics(values)
+	if metrics.BlockNum != 123 {
+		t.Errorf("got block %d, expected 123", metrics.BlockNum)
+	}
+	if metrics.DidntAdopt != 4 || metrics.MissedSlots != 0 {
+		t.Errorf(
+			"got didn't adopt %d and missed slots %d, expected 4 and 0",
+			metrics.DidntAdopt,
+			metrics.MissedSlots,
+		)
+	}
+	if metrics.BlockDelay != 0.5 {
+		t.Errorf("got block delay %v, expected 0.5", metrics.BlockDelay)
+	}
+}
+
+func TestSplitPromFamilies(t *testing.T) {
+	prom := `cardano_node_metrics_blockNum_int 123
+cardano_node_metrics_slotNum_int 456
+
+# HELP delay Block delay
+# TYPE delay histogram
+delay_bucket{le="+Inf"} 1
+delay_sum 0.5
+delay_count 1
+# TYPE conns gauge
+conns{peer="a"} 1
+conns{peer="b"} 2
+`
+	expected := []string{
+		"cardano_node_metrics_blockNum_int 123\n",
+		"cardano_node_metrics_slotNum_int 456\n",
+		"# HELP delay Block delay\n# TYPE delay histogram\n" +
+			"delay_bucket{le=\"+Inf\"} 1\ndelay_sum 0.5\ndelay_count 1\n",
+		"# TYPE conns gauge\nconns{peer=\"a\"} 1\nconns{peer=\"b\"} 2\n",
+	}
+	var got []string
+	for _, block := range splitPromFamilies([]byte(prom)) {
+		got = append(got, string(block))
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestParsePromMetricFamiliesMalformed(t *testing.T) {
+	prom := `cardano_node_metrics_blockNum_int 123
+# TYPE broken gauge
+broken{label="x" 1
+cardano_node_metrics_slotNum_int 456
+`
+	// The malformed family is skipped, keeping the rest of the scrape
+	values := getTestPromMetricValues(t, prom)
+	expected := map[string]float64{
+		"cardano_node_metrics_blockNum_int": 123,
+		"cardano_node_metrics_slotNum_int":  456,
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("got %v, expected %v", values, expected)
+	}
+	// An error when no family parses
+	if _, err := parsePromMetricFamilies(
+		[]byte("broken{label=\"x\" 1\n"),
+	); err == nil {
+		t.Errorf("expected an error when no families parse")
+	}
+}
+
+// Returns a fixed response for each fetch
+type fakeMetricsSource struct {
+	data   string
+	status int
+	err    error
+}
+
+func (s *fakeMetricsSource) Fetch(ctx context.Context) ([]byte, int, error) {
+	return []byte(s.data), s.status, s.err
+}
+
+// Sends logs at all levels to the returned buffer
+func setLogCapture(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	oldLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(oldLogger)
+	})
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(
+		&buf,
+		&slog.HandlerOptions{Level: slog.LevelDebug},
+	)))
+	return &buf
+}
+
+func TestGetPromMetricsLogging(t *testing.T) {
+	oldSource := metricsSource
+	oldFailCount := failCount
+	t.Cleanup(func() {
+		metricsSource = oldSource
+		failCount = oldFailCount
+		scrapeFailures = 0
+		scrapeErrLast = ""
+	})
+	scrapeFailures = 0
+	scrapeErrLast = ""
+	logs := setLogCapture(t)
+	source := &fakeMetricsSource{}
+	metricsSource = source
+	testDefs := []struct {
+		source   fakeMetricsSource
+		expected []string
+	}{
+		// A failure is logged at debug level
+		{
+			source: fakeMetricsSource{err: errors.New("connection refused")},
+			expected: []string{
+				`level=DEBUG msg="failed to scrape node metrics" status=0 ` +
+					`error="connection refused" failures=1`,
+			},
+		},
+		// A repeat of the same failure is not
+		{
+			source: fakeMetricsSource{err: errors.New("connection refused")},
+		},
+		// A different failure is
+		{
+			source: fakeMetricsSource{status: 503},
+			expected: []string{
+				`level=DEBUG msg="failed to scrape node metrics" status=503 ` +
+					`error="Failed HTTP: 503" failures=3`,
+			},
+		},
+		// Recovering is logged once at info level
+		{
+			source: fakeMetricsSource{
+				data:   "cardano_node_metrics_blockNum_int 42\n",
+				status: 200,
+			},
+			expected: []string{
+				`level=INFO msg="node metrics connectivity recovered" ` +
+					`failures=3`,
+			},
+		},
+		{
+			source: fakeMetricsSource{
+				data:   "cardano_node_metrics_blockNum_int 43\n",
+				status: 200,
+			},
+		},
+	}
+	for i, testDef := range testDefs {
+		logs.Reset()
+		*source = testDef.source
+		metrics, err := getPromMetrics(context.Background())
+		if (err == nil) != (testDef.source.status == 200) {
+			t.Errorf("scrape %d: unexpected error: %v", i, err)
+		}
+		if err == nil && metrics.BlockNum == 0 {
+			t.Errorf("scrape %d: expected the block number to be set", i)
+		}
+		var got []string
+		for _, line := range strings.Split(logs.String(), "\n") {
+			// Drop the time, which varies
+			if _, entry, ok := strings.Cut(line, " "); ok {
+				got = append(got, entry)
+			}
+		}
+		if !reflect.DeepEqual(got, testDef.expected) {
+			t.Errorf("scrape %d: got %q, expected %q", i, got, testDef.expected)
+		}
+	}
+}
+
+func TestUpdatePromMetricsBackoff(t *testing.T) {
+	cfg := config.GetConfig()
+	oldRefresh := cfg.Prometheus.Refresh
+	oldSource := metricsSource
+	oldWait := waitForPromPoll
+	oldFailCount := failCount
+	oldMetrics := promMetrics
+	t.Cleanup(func() {
+		cfg.Prometheus.Refresh = oldRefresh
+		metricsSource = oldSource
+		waitForPromPoll = oldWait
+		failCount = oldFailCount
+		promMetrics = oldMetrics
+		promFailures.Store(0)
+		scrapeFailures = 0
+		scrapeErrLast = ""
+	})
+	setLogCapture(t)
+	down := fakeMetricsSource{err: errors.New("connection refused")}
+	up := fakeMetricsSource{
+		data:   "cardano_node_metrics_slotNum_int 100\n",
+		status: http.StatusOK,
+	}
+	testDefs := []struct {
+		refresh  uint32
+		results  []fakeMetricsSource
+		expected []time.Duration
+	}{
+		// Failures back off up to the maximum, and a success resets the
+		// backoff
+		{
+			refresh: 3,
+			results: []fakeMetricsSource{
+				down, down, down, down, down, down, up, down, up,
+			},
+			expected: []time.Duration{
+				3 * time.Second,
+				6 * time.Second,
+				12 * time.Second,
+				24 * time.Second,
+				48 * time.Second,
+				promBackoffMax,
+				3 * time.Second,
+				3 * time.Second,
+				3 * time.Second,
+			},
+		},
+		// A refresh interval beyond the maximum is never shortened
+		{
+			refresh: 90,
+			results: []fakeMetricsSource{down, down, up},
+			expected: []time.Duration{
+				90 * time.Second,
+				90 * time.Second,
+				90 * time.Second,
+			},
+		},
+	}
+	for _, testDef := range testDefs {
+		cfg.Prometheus.Refresh = testDef.refresh
+		source := &fakeMetricsSource{}
+		metricsSource = source
+		polls := 0
+		*source = testDef.results[0]
+		var waits []time.Duration
+		waitForPromPoll = func(ctx context.Context, d time.Duration) bool {
+			waits = append(waits, d)
+			// Failures are counted until the next success
+			if (source.err != nil) != (failCount > 0) {
+				t.Errorf("poll %d: got fail count %d", polls, failCount)
+			}
+			polls++
+			if polls == len(testDef.results) {
+				return false
+			}
+			*source = testDef.results[polls]
+			return true
+		}
+		updatePromMetrics(context.Background())
+		if !reflect.DeepEqual(waits, testDef.expected) {
+			t.Errorf("got waits %v, expected %v", waits, testDef.expected)
+		}
+		if promMetrics == nil || promMetrics.SlotNum != 100 {
+			t.Errorf("got %+v, expected the last metrics", promMetrics)
+		}
+	}
+}
+
+func TestGetPromMetricsFromFile(t *testing.T) {
+	oldSource := metricsSource
+	oldFailCount := failCount
+	t.Cleanup(func() {
+		metricsSource = oldSource
+		failCount = oldFailCount
+	})
+	t.Setenv(metricsFileEnv, filepath.Join("testdata", "metrics.prom"))
+	source, err := getMetricsSource()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := source.(*fileMetricsSource); !ok {
+		t.Fatalf("got %T, expected a file metrics source", source)
+	}
+	metricsSource = source
+	got, err := getPromMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := &PromMetrics{
+		BlockNum:            11612345,
+		EpochNum:            556,
+		SlotInEpoch:         215985,
+		SlotNum:             150121185,
+		Density:             0.04891,
+		MempoolTx:           12,
+		MempoolBytes:        34567,
+		KesPeriod:           1123,
+		RemainingKesPeriods: 42,
+		MemLive:             5 << 30,
+		GcMinor:             4321,
+		PeersCold:           40,
+		PeersWarm:           20,
+		PeersHot:            10,
+		ConnIncoming:        25,
+		ConnOutgoing:        30,
+	}
+	// Raw values include untracked metrics
+	if got.Raw["rts_gc_num_gcs"] != 4342 {
+		t.Errorf("got raw %v, expected untracked metrics", got.Raw)
+	}
+	got.Raw = nil
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, expected %+v", got, expected)
+	}
+}
+
+func TestGetPromMetricsFromMissingFile(t *testing.T) {
+	oldSource := metricsSource
+	oldFailCount := failCount
+	t.Cleanup(func() {
+		metricsSource = oldSource
+		failCount = oldFailCount
+		scrapeFailures = 0
+		scrapeErrLast = ""
+	})
+	metricsSource = &fileMetricsSource{
+		path: filepath.Join(t.TempDir(), "missing.prom"),
+	}
+	if _, err := getPromMetrics(context.Background()); err == nil {
+		t.Errorf("expected an error for a missing metrics file")
+	}
+}
+
+func TestSetCurrentEpoch(t *testing.T) {
+	oldNow := timeNow
+	oldEpoch := currentEpoch
+	oldMetrics := promMetrics
+	t.Cleanup(func() {
+		timeNow = oldNow
+		currentEpoch = oldEpoch
+		promMetrics = oldMetrics
+		epochMismatchLast = 0
+	})
+	setTestGenesis(t, "mainnet")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	testDefs := []struct {
+		name     string
+		metrics  *PromMetrics
+		expected uint32
+		err      bool
+	}{
+		// The epoch is derived from the clock until metrics are available
+		{name: "no metrics", expected: 561, err: true},
+		{
+			name:     "metrics",
+			metrics:  &PromMetrics{EpochNum: 561},
+			expected: 561,
+		},
+		// A syncing node is behind the derived epoch
+		{
+			name:     "syncing",
+			metrics:  &PromMetrics{EpochNum: 300},
+			expected: 300,
+		},
+	}
+	for _, testDef := range testDefs {
+		promMetrics = testDef.metrics
+		currentEpoch = 0
+		err := setCurrentEpoch()
+		if (err != nil) != testDef.err {
+			t.Errorf(
+				"%s: got error %v, expected %v",
+				testDef.name,
+				err,
+				testDef.err,
+			)
+		}
+		if currentEpoch != testDef.expected {
+			t.Errorf(
+				"%s: got epoch %d, expected %d",
+				testDef.name,
+				currentEpoch,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestSetCurrentEpochNoGenesis(t *testing.T) {
+	oldEpoch := currentEpoch
+	oldMetrics := promMetrics
+	t.Cleanup(func() {
+		currentEpoch = oldEpoch
+		promMetrics = oldMetrics
+	})
+	setTestGenesis(t, "mainnet")
+	config.GetConfig().Node.ShelleyGenesis.SlotLength = 0
+	promMetrics = nil
+	currentEpoch = 7
+	if err := setCurrentEpoch(); err == nil {
+		t.Errorf("expected an error without metrics or genesis values")
+	}
+	// The last known epoch is kept
+	if currentEpoch != 7 {
+		t.Errorf("got epoch %d, expected 7", currentEpoch)
+	}
+}
+
+func TestSetCurrentEpochAhead(t *testing.T) {
+	logBuf := setLogCapture(t)
+	oldNow := timeNow
+	oldEpoch := currentEpoch
+	oldMetrics := promMetrics
+	t.Cleanup(func() {
+		timeNow = oldNow
+		currentEpoch = oldEpoch
+		promMetrics = oldMetrics
+		epochMismatchLast = 0
+	})
+	setTestGenesis(t, "mainnet")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	epochMismatchLast = 0
+	// The node's epoch is still used, but with a warning for each new epoch
+	promMetrics = &PromMetrics{EpochNum: 900}
+	for i := 0; i < 3; i++ {
+		if err := setCurrentEpoch(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if currentEpoch != 900 {
+		t.Errorf("got epoch %d, expected 900", currentEpoch)
+	}
+	const warning = "node epoch is ahead of the epoch computed from genesis"
+	if got := strings.Count(logBuf.String(), warning); got != 1 {
+		t.Errorf("got %d warnings, expected 1:\n%s", got, logBuf)
+	}
+	promMetrics.EpochNum = 901
+	if err := setCurrentEpoch(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := strings.Count(logBuf.String(), warning); got != 2 {
+		t.Errorf("got %d warnings, expected 2:\n%s", got, logBuf)
+	}
+}
+
+func TestEpochTransition(t *testing.T) {
+	var tracker epochTransition
+	testDefs := []struct {
+		epoch    uint32
+		expected bool
+	}{
+		// Epochs aren't known until the first value
+		{epoch: 0, expected: false},
+		{epoch: 556, expected: false},
+		{epoch: 556, expected: false},
+		{epoch: 557, expected: true},
+		{epoch: 557, expected: false},
+		// Catching up while syncing
+		{epoch: 560, expected: true},
+		// An earlier epoch, such as from another node, isn't a new epoch
+		{epoch: 559, expected: false},
+		{epoch: 560, expected: true},
+	}
+	for i, testDef := range testDefs {
+		if got := tracker.update(testDef.epoch); got != testDef.expected {
+			t.Errorf(
+				"update %d with epoch %d: got %v, expected %v",
+				i,
+				testDef.epoch,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestOnEpochStarted(t *testing.T) {
+	setPanelFixtures(t)
+	logBuf := setLogCapture(t)
+	oldEvents := nodeEvents
+	t.Cleanup(func() {
+		nodeEvents = oldEvents
+		footerNotice = ""
+		luckBase = nil
+	})
+	testDefs := []struct {
+		name    string
+		tipDiff uint64
+		notice  string
+	}{
+		{name: "synced", tipDiff: 12, notice: "Epoch 557 started"},
+		// Epochs pass quickly while syncing, so only the event is recorded
+		{name: "syncing", tipDiff: 100000},
+	}
+	tipRef := promMetrics.SlotNum + 12
+	for _, testDef := range testDefs {
+		nodeEvents = newEventLog(10)
+		footerNotice = ""
+		logBuf.Reset()
+		luckBase = &luckBaseline{epoch: 556}
+		promMetrics.SlotNum = tipRef - testDef.tipDiff
+		onEpochStarted(557)
+		if luckBase != nil {
+			t.Errorf("%s: expected the luck baseline to be reset", testDef.name)
+		}
+		events := nodeEvents.list()
+		if len(events) != 1 || events[0].Message != "Epoch 557 started" {
+			t.Errorf("%s: got events %+v", testDef.name, events)
+		}
+		if footerNotice != testDef.notice {
+			t.Errorf(
+				"%s: got notice %q, expected %q",
+				testDef.name,
+				footerNotice,
+				testDef.notice,
+			)
+		}
+		logged := strings.Contains(logBuf.String(), "epoch started")
+		if logged != (testDef.notice != "") {
+			t.Errorf("%s: got log:\n%s", testDef.name, logBuf)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Full-screen raw metrics page, with a filter above the metrics
+var rawMetricsFilterInput = tview.NewInputField().
+	SetLabel(" Filter: ").
+	SetFieldWidth(0)
+var rawMetricsTextView = tview.NewTextView().
+	SetDynamicColors(true).
+	SetChangedFunc(func() {
+		app.Draw()
+	})
+var rawMetricsText string
+
+// Sets up the full-screen raw metrics page, which returns to the main page on
+// esc
+func setupRawMetricsPage() {
+	rawMetricsTextView.SetTitle(
+		"Raw Metrics (/ to filter, esc to return)",
+	).SetBorder(true)
+	rawMetricsFilterInput.SetChangedFunc(func(string) {
+		updateRawMetricsPage()
+		rawMetricsTextView.ScrollToBeginning()
+	})
+	// Enter moves to the metrics for scrolling, and esc returns
+	rawMetricsFilterInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			hideRawMetricsPage()
+			return
+		}
+		app.SetFocus(rawMetricsTextView)
+	})
+	rawMetricsTextView.SetInputCapture(
+		func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyEscape || event.Rune() == 'x' {
+				hideRawMetricsPage()
+				return nil
+			}
+			if event.Rune() == '/' {
+				app.SetFocus(rawMetricsFilterInput)
+				return nil
+			}
+			return event
+		},
+	)
+	page := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(rawMetricsFilterInput, 1, 0, false).
+		AddItem(rawMetricsTextView, 0, 1, true)
+	pages.AddPage("Raw Metrics", page, true, false)
+}
+
+// Shows the full-screen raw metrics page
+func showRawMetricsPage() {
+	pages.SwitchToPage("Raw Metrics")
+	updateRawMetricsPage()
+	app.SetFocus(rawMetricsTextView)
+	rawMetricsTextView.ScrollToBeginning()
+}
+
+// Returns from the raw metrics page to the main page
+func hideRawMetricsPage() {
+	pages.SwitchToPage("Main")
+	app.SetFocus(flex)
+}
+
+// Updates the full-screen raw metrics page when it's shown
+func updateRawMetricsPage() {
+	if name, _ := pages.GetFrontPage(); name != "Raw Metrics" {
+		return
+	}
+	var raw map[string]float64
+	if promMetrics != nil {
+		raw = promMetrics.Raw
+	}
+	tmpText := getRawMetricsText(raw, rawMetricsFilterInput.GetText())
+	if tmpText != rawMetricsText {
+		rawMetricsText = tmpText
+		rawMetricsTextView.SetText(rawMetricsText)
+	}
+}
+
+// Returns whether a metric name contains every space-separated term of a
+// filter, ignoring case
+func matchesMetricFilter(name string, filter string) bool {
+	name = strings.ToLower(name)
+	for _, term := range strings.Fields(strings.ToLower(filter)) {
+		if !strings.Contains(name, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the raw metrics text with a row per metric matching the filter,
+// sorted by name
+func getRawMetricsText(raw map[string]float64, filter string) string {
+	if len(raw) == 0 {
+		return " [yellow]No metrics yet\n"
+	}
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		if matchesMetricFilter(name, filter) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return " [yellow]No metrics match the filter\n"
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, " [green]%d of %d metrics\n", len(names), len(raw))
+	for _, name := range names {
+		fmt.Fprintf(
+			&sb,
+			" [green]%s [white]%s\n",
+			tview.Escape(name),
+			strconv.FormatFloat(raw[name], 'f', -1, 64),
+		)
+	}
+	return sb.String()
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestMatchesMetricFilter(t *testing.T) {
+	name := "cardano_node_metrics_slotInEpoch_int"
+	testDefs := []struct {
+		filter   string
+		expected bool
+	}{
+		// Everything matches an empty filter
+		{filter: "", expected: true},
+		{filter: "   ", expected: true},
+		{filter: "slot", expected: true},
+		{filter: "SLOTINEPOCH", expected: true},
+		// Every term must match, in any order
+		{filter: "int slot", expected: true},
+		{filter: "slot  metrics ", expected: true},
+		{filter: "slot block", expected: false},
+		{filter: "slots", expected: false},
+	}
+	for _, testDef := range testDefs {
+		got := matchesMetricFilter(name, testDef.filter)
+		if got != testDef.expected {
+			t.Errorf(
+				"%q: got %v, expected %v",
+				testDef.filter,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetRawMetricsText(t *testing.T) {
+	raw := map[string]float64{
+		"cardano_node_metrics_slotNum_int":     150121185,
+		"cardano_node_metrics_blockNum_int":    11612345,
+		"cardano_node_metrics_density_real":    0.04891,
+		"rts_gc_num_gcs":                       4342,
+		"cardano_node_metrics_[odd]_name_real": 1.5,
+	}
+	testDefs := []struct {
+		raw      map[string]float64
+		filter   string
+		expected string
+	}{
+		{raw: nil, expected: " [yellow]No metrics yet\n"},
+		// Matching metrics are sorted by name
+		{
+			raw:    raw,
+			filter: "cardano _int",
+			expected: " [green]2 of 5 metrics\n" +
+				" [green]cardano_node_metrics_blockNum_int [white]11612345\n" +
+				" [green]cardano_node_metrics_slotNum_int [white]150121185\n",
+		},
+		{
+			raw:    raw,
+			filter: "real",
+			expected: " [green]2 of 5 metrics\n" +
+				" [green]cardano_node_metrics_[odd[]_name_real [white]1.5\n" +
+				" [green]cardano_node_metrics_density_real [white]0.04891\n",
+		},
+		{
+			raw:      raw,
+			filter:   "mempool",
+			expected: " [yellow]No metrics match the filter\n",
+		},
+	}
+	for _, testDef := range testDefs {
+		got := getRawMetricsText(testDef.raw, testDef.filter)
+		if got != testDef.expected {
+			t.Errorf(
+				"%q: got %q, expected %q",
+				testDef.filter,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
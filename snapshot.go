@@ -0,0 +1,155 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+	"github.com/blinklabs-io/nview/internal/version"
+)
+
+// Node resource usage for a snapshot
+type snapshotResources struct {
+	cpuPercent float64
+	rss        uint64
+}
+
+// Returns a plain text summary of the node, for sharing
+func getSnapshotText(
+	now time.Time,
+	metrics *PromMetrics,
+	tipRef uint64,
+	resources *snapshotResources,
+) string {
+	cfg := config.GetConfig()
+	network := cfg.App.Network
+	if network == "" {
+		network = cfg.Node.Network
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(
+		"nview %s snapshot at %s\n",
+		version.GetVersionString(),
+		formatTime(now, time.RFC3339),
+	))
+	sb.WriteString(fmt.Sprintf("Node       : %s\n", getEffectiveNodeName()))
+	sb.WriteString(fmt.Sprintf("Role       : %s\n", role))
+	sb.WriteString(fmt.Sprintf("Network    : %s\n", network))
+	sb.WriteString(fmt.Sprintf("Uptime     : %s\n", timeFromSeconds(uptimes)))
+	if metrics == nil {
+		sb.WriteString("Sync       : no metrics\n")
+	} else {
+		tipDiff := getTipDiff(metrics, tipRef)
+		sb.WriteString(fmt.Sprintf("Epoch      : %d\n", metrics.EpochNum))
+		sb.WriteString(fmt.Sprintf("Block      : %d\n", metrics.BlockNum))
+		sb.WriteString(fmt.Sprintf("Slot       : %d\n", metrics.SlotNum))
+		sb.WriteString(fmt.Sprintf(
+			"Tip diff   : %d (%s)\n",
+			tipDiff,
+			getTipDiffBucket(tipDiff),
+		))
+	}
+	sb.WriteString(fmt.Sprintf(
+		"Peers      : %d (avg RTT %d ms)\n",
+		len(peerStats.RTTresultsSlice),
+		peerStats.RTTAVG,
+	))
+	if resources != nil {
+		sb.WriteString(fmt.Sprintf(
+			"CPU        : %.2f%%\n",
+			resources.cpuPercent,
+		))
+		sb.WriteString(fmt.Sprintf(
+			"Mem (RSS)  : %s\n",
+			formatMemory(resources.rss),
+		))
+	}
+	return sb.String()
+}
+
+// Returns the resource usage of the node process for a snapshot
+func getSnapshotResources(ctx context.Context) *snapshotResources {
+	if processMetrics == nil || processMetrics.Pid() == 0 {
+		return nil
+	}
+	cpuPercent, err := processMetrics.CPUPercent(ctx)
+	if err != nil {
+		return nil
+	}
+	memInfo, err := processMetrics.MemoryInfo(ctx)
+	if err != nil {
+		return nil
+	}
+	return &snapshotResources{cpuPercent: cpuPercent, rss: memInfo.RSS}
+}
+
+// Clipboard commands to try, in order, for each platform
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbcopy"}},
+	"windows": {{"clip.exe"}},
+	"linux": {
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	},
+}
+
+// Copies text to the system clipboard using the first available clipboard
+// command
+func copyToClipboard(text string) error {
+	for _, command := range clipboardCommands[runtime.GOOS] {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+		return nil
+	}
+	return errors.New("no clipboard available")
+}
+
+// Copies a snapshot to the clipboard, falling back to a temporary file, and
+// returns a notice for the footer
+func copySnapshot(ctx context.Context) string {
+	text := getSnapshotText(
+		time.Now(),
+		promMetrics,
+		getSlotTipRef(),
+		getSnapshotResources(ctx),
+	)
+	if err := copyToClipboard(text); err == nil {
+		return "Snapshot copied to clipboard"
+	}
+	f, err := os.CreateTemp("", "nview-snapshot-*.txt")
+	if err != nil {
+		return fmt.Sprintf("Failed to save snapshot: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		return fmt.Sprintf("Failed to save snapshot: %s", err)
+	}
+	return fmt.Sprintf("Snapshot saved to %s", f.Name())
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/blinklabs-io/nview/internal/config"
+	"github.com/blinklabs-io/nview/internal/version"
+)
+
+func TestGetSnapshotText(t *testing.T) {
+	setPanelFixtures(t)
+	setPeerFixture(4)
+	cfg := config.GetConfig()
+	oldNetwork := cfg.App.Network
+	t.Cleanup(func() {
+		cfg.App.Network = oldNetwork
+		peerStats = PeerStats{}
+		peersFiltered = nil
+	})
+	cfg.App.Network = "mainnet"
+	peerStats.RTTAVG = 42
+	tipRef := promMetrics.SlotNum + 12
+	got := getSnapshotText(
+		fixtureNow,
+		promMetrics,
+		tipRef,
+		&snapshotResources{cpuPercent: 123.456, rss: 12 << 30},
+	)
+	expected := "nview " + version.GetVersionString() +
+		" snapshot at 2025-06-01T12:00:00Z\n" +
+		"Node       : Fixture\n" +
+		"Role       : Core\n" +
+		"Network    : mainnet\n" +
+		"Uptime     : 1d 02:03:04\n" +
+		"Epoch      : 556\n" +
+		"Block      : 11612345\n" +
+		"Slot       : " + strconv.FormatUint(promMetrics.SlotNum, 10) + "\n" +
+		"Tip diff   : 12 (OK)\n" +
+		"Peers      : 4 (avg RTT 42 ms)\n" +
+		"CPU        : 123.46%\n" +
+		"Mem (RSS)  : " + formatMemory(12<<30) + "\n"
+	if got != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestGetSnapshotTextNoMetrics(t *testing.T) {
+	setPanelFixtures(t)
+	got := getSnapshotText(fixtureNow, nil, 0, nil)
+	if !strings.Contains(got, "Sync       : no metrics\n") {
+		t.Errorf("got:\n%s\nexpected no metrics", got)
+	}
+	for _, line := range []string{"Tip diff", "CPU", "Mem (RSS)"} {
+		if strings.Contains(got, line) {
+			t.Errorf("got:\n%s\nexpected no %s line", got, line)
+		}
+	}
+}
+
+func TestGetSnapshotResources(t *testing.T) {
+	oldProcessMetrics := processMetrics
+	t.Cleanup(func() {
+		processMetrics = oldProcessMetrics
+	})
+	processMetrics = &fakeProcess{pid: 1234, cpu: 12.5, rss: 1 << 30}
+	got := getSnapshotResources(context.Background())
+	if got == nil || got.cpuPercent != 12.5 || got.rss != 1<<30 {
+		t.Errorf("got %+v, expected 12.5%% and 1GiB", got)
+	}
+	// No node process
+	processMetrics = &fakeProcess{}
+	if got := getSnapshotResources(context.Background()); got != nil {
+		t.Errorf("got %+v, expected no resources", got)
+	}
+	processMetrics = nil
+	if got := getSnapshotResources(context.Background()); got != nil {
+		t.Errorf("got %+v, expected no resources", got)
+	}
+}
+
+// Sets the clipboard commands for this platform for the duration of a test
+func setClipboardCommands(t *testing.T, commands [][]string) {
+	t.Helper()
+	oldCommands := clipboardCommands
+	t.Cleanup(func() {
+		clipboardCommands = oldCommands
+	})
+	clipboardCommands = map[string][][]string{runtime.GOOS: commands}
+}
+
+func TestCopySnapshotClipboard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	setPanelFixtures(t)
+	path := filepath.Join(t.TempDir(), "clipboard.txt")
+	// The first available command is used
+	setClipboardCommands(t, [][]string{
+		{"nview-missing-clipboard"},
+		{"sh", "-c", "cat > " + path},
+	})
+	got := copySnapshot(context.Background())
+	if got != "Snapshot copied to clipboard" {
+		t.Errorf("got %q, expected the snapshot to be copied", got)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(string(data), "Node       : Fixture\n") {
+		t.Errorf("got clipboard:\n%s\nexpected the snapshot", data)
+	}
+}
+
+func TestCopySnapshotFallback(t *testing.T) {
+	setPanelFixtures(t)
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	setClipboardCommands(t, [][]string{{"nview-missing-clipboard"}})
+	got := copySnapshot(context.Background())
+	name, found := strings.CutPrefix(got, "Snapshot saved to ")
+	if !found {
+		t.Fatalf("got %q, expected the snapshot to be saved", got)
+	}
+	if filepath.Dir(name) != dir {
+		t.Errorf("got %s, expected a file in %s", name, dir)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(string(data), "Node       : Fixture\n") {
+		t.Errorf("got file:\n%s\nexpected the snapshot", data)
+	}
+}
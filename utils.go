@@ -17,10 +17,15 @@ package main
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"net"
 	"os/exec"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oschwald/geoip2-golang"
@@ -28,28 +33,136 @@ import (
 	"github.com/blinklabs-io/nview/internal/config"
 )
 
+// Cached node version for the node process it was fetched for. A version
+// from running the node binary is kept for the life of the process, while a
+// failure to run it, along with any version from the build info metric, is
+// kept until it expires.
+var nodeVersionCache struct {
+	pid      int32
+	version  string
+	revision string
+	err      error
+	expires  time.Time
+}
+
+// How long a failure to run the node binary is cached before retrying
+const nodeVersionRetry = 10 * time.Minute
+
+// Runs the node binary for its version, which is replaced in tests
+var runNodeVersion = execNodeVersion
+
+// Returns the node version and revision, from running the node binary unless
+// disabled, falling back to the node's build info metric
 func getNodeVersion() (version string, revision string, err error) {
 	cfg := config.GetConfig()
-	cmd := exec.Command(cfg.Node.Binary, "version")
+	var pid int32
+	if processMetrics != nil {
+		pid = processMetrics.Pid()
+	}
+	now := timeNow()
+	cache := &nodeVersionCache
+	cached := cache.pid == pid &&
+		(cache.version != "" || cache.err != nil) &&
+		(cache.expires.IsZero() || now.Before(cache.expires))
+	if cached && cache.version != "" {
+		return cache.version, cache.revision, nil
+	}
+	if !cached {
+		if !cfg.App.DisableVersionExec && !cfg.App.RemoteMode {
+			version, revision, err = runNodeVersion()
+			if err == nil {
+				cache.pid = pid
+				cache.version = version
+				cache.revision = revision
+				cache.err = nil
+				cache.expires = time.Time{}
+				return version, revision, nil
+			}
+		} else {
+			err = errors.New("running the node binary is disabled")
+		}
+		cache.pid = pid
+		cache.version = ""
+		cache.revision = ""
+		cache.err = err
+		cache.expires = now.Add(nodeVersionRetry)
+	}
+	if promMetrics != nil && promMetrics.BuildVersion != "" {
+		revision = promMetrics.BuildRevision
+		if revision == "" {
+			revision = "N/A"
+		} else if len(revision) > 8 {
+			revision = revision[0:8]
+		}
+		cache.version = promMetrics.BuildVersion
+		cache.revision = revision
+		return cache.version, cache.revision, nil
+	}
+	return "N/A", "N/A", cache.err
+}
+
+// Returns the node version and revision from running the node binary
+func execNodeVersion() (version string, revision string, err error) {
+	cmd := exec.Command(getEffectiveNodeBinary(), "version")
 	stdout, err := cmd.Output()
 	if err != nil {
 		return "N/A", "N/A", err
 	}
-	strArray := strings.Split(string(stdout), string(' '))
-	if strArray == nil {
-		return "N/A", "N/A", fmt.Errorf("error")
+	return parseNodeVersion(string(stdout))
+}
+
+// Patterns for the version and revision in node version output, like
+// "cardano-node 10.1.4 - linux-x86_64 - ghc-8.10\ngit rev 1f63dbf2..." or
+// "dingo v0.4.0 (commit 1f63dbf2)"
+var (
+	nodeVersionRegexp = regexp.MustCompile(
+		`\bv?\d+\.\d+(?:\.\d+)*(?:[-+][0-9A-Za-z.-]+)?`,
+	)
+	nodeRevisionRegexp = regexp.MustCompile(
+		`(?:git rev(?:ision)?|\(commit)[\s:]+([0-9a-fA-F]{7,40})`,
+	)
+)
+
+// Parses the version and revision from node version output, with a revision
+// of "N/A" when there is none
+func parseNodeVersion(output string) (version string, revision string, err error) {
+	version = nodeVersionRegexp.FindString(output)
+	if version == "" {
+		return "N/A", "N/A", fmt.Errorf(
+			"no version found in node version output: %q",
+			strings.TrimSpace(output),
+		)
 	}
-	version = strArray[1]
-	revision = strArray[7]
-	if len(revision) > 8 {
-		revision = revision[0:8]
+	revision = "N/A"
+	if m := nodeRevisionRegexp.FindStringSubmatch(output); m != nil {
+		revision = m[1]
+		if len(revision) > 8 {
+			revision = revision[0:8]
+		}
 	}
 	return version, revision, nil
 }
 
 var publicIP *net.IP
 
+// Public IP lookup retry and refresh intervals
+const (
+	publicIPRetryMin = 5 * time.Second
+	publicIPRetryMax = 5 * time.Minute
+	publicIPRefresh  = time.Hour
+)
+
+// Returns our configured static public IP, if any
+func getStaticPublicIP() net.IP {
+	cfg := config.GetConfig()
+	if cfg.App.PublicIP == "" {
+		return nil
+	}
+	return net.ParseIP(cfg.App.PublicIP)
+}
+
 func getPublicIP(ctx context.Context) (net.IP, error) {
+	cfg := config.GetConfig()
 	// First, check for external address using custom resolver so we can
 	// use a given DNS server to resolve our public address
 	r := &net.Resolver{
@@ -58,18 +171,110 @@ func getPublicIP(ctx context.Context) (net.IP, error) {
 			d := net.Dialer{
 				Timeout: time.Second * time.Duration(3),
 			}
-			return d.DialContext(ctx, network, "resolver1.opendns.com:53")
+			return d.DialContext(ctx, network, cfg.App.PublicIPResolver)
 		},
 	}
 	// Lookup special address to get our public IP
-	ips, err := r.LookupIP(ctx, "ip4", "myip.opendns.com")
+	ips, err := r.LookupIP(ctx, "ip4", cfg.App.PublicIPQuery)
 	if err != nil {
 		return nil, err
 	}
-	if ips != nil {
+	if len(ips) > 0 {
 		return ips[0], nil
 	}
-	return nil, nil
+	return nil, fmt.Errorf("no public IP found")
+}
+
+// Looks up our public IP and waits between lookups, which are replaced in
+// tests
+var (
+	lookupPublicIP  = getPublicIP
+	waitForPublicIP = sleepWithContext
+)
+
+// Looks up our public IP in the background, retrying with backoff on failure
+// and refreshing periodically
+func updatePublicIP(ctx context.Context) {
+	cfg := config.GetConfig()
+	if ip := getStaticPublicIP(); ip != nil {
+		publicIP = &ip
+		return
+	}
+	// Air-gapped mode makes no outbound DNS queries
+	if cfg.App.DisablePublicIP || cfg.App.AirGapped {
+		return
+	}
+	retry := publicIPRetryMin
+	for {
+		wait := publicIPRefresh
+		ip, err := lookupPublicIP(ctx)
+		if err != nil {
+			slog.Debug("failed to get public IP", "error", err)
+			wait = retry
+			retry = min(retry*2, publicIPRetryMax)
+		} else {
+			publicIP = &ip
+			retry = publicIPRetryMin
+		}
+		if !waitForPublicIP(ctx, wait) {
+			return
+		}
+	}
+}
+
+// Waits for the given duration, returning false if the context is cancelled
+// first
+func sleepWithContext(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Broadcasts requests for an immediate refresh to background workers
+type refreshSignal struct {
+	mutex sync.Mutex
+	ch    chan struct{}
+}
+
+func newRefreshSignal() *refreshSignal {
+	return &refreshSignal{ch: make(chan struct{})}
+}
+
+// Returns a channel which is closed on the next refresh request
+func (r *refreshSignal) C() <-chan struct{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.ch
+}
+
+// Wakes every worker waiting on the signal
+func (r *refreshSignal) trigger() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	close(r.ch)
+	r.ch = make(chan struct{})
+}
+
+var refreshRequests = newRefreshSignal()
+
+// Waits for the given duration or until a refresh is requested, returning
+// false if the context is cancelled first
+func sleepOrRefresh(
+	ctx context.Context,
+	d time.Duration,
+	refresh *refreshSignal,
+) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-refresh.C():
+		return true
+	case <-time.After(d):
+		return true
+	}
 }
 
 // MaxMind database (20240206), available from https://www.maxmind.com
@@ -77,12 +282,60 @@ func getPublicIP(ctx context.Context) (net.IP, error) {
 //go:embed resources/GeoLite2-City.mmdb
 var MaxmindDB []byte
 
+// Shared GeoIP reader, opened on first use
+var (
+	geoIPReader     *geoip2.Reader
+	geoIPReaderErr  error
+	geoIPReaderOnce sync.Once
+)
+
+func getGeoIPReader() (*geoip2.Reader, error) {
+	geoIPReaderOnce.Do(func() {
+		geoIPReader, geoIPReaderErr = geoip2.FromBytes(MaxmindDB)
+	})
+	return geoIPReader, geoIPReaderErr
+}
+
+// Closes the shared GeoIP reader, if it was opened
+func closeGeoIP() {
+	if geoIPReader != nil {
+		geoIPReader.Close()
+	}
+}
+
+// Resolver used for peer reverse DNS lookups
+var peerResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+} = net.DefaultResolver
+
+// Timeout for a single peer reverse DNS lookup
+const peerReverseDNSTimeout = 2 * time.Second
+
+// Returns the hostname for an IP address from a reverse DNS lookup, or an
+// empty string when it can't be resolved
+func getPeerHostname(ctx context.Context, address string) string {
+	ctx, cancel := context.WithTimeout(ctx, peerReverseDNSTimeout)
+	defer cancel()
+	names, err := peerResolver.LookupAddr(ctx, address)
+	if err != nil {
+		slog.Debug(
+			"failed reverse DNS lookup",
+			"address", address,
+			"error", err,
+		)
+		return ""
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
 func getGeoIP(ctx context.Context, address string) string {
-	db, err := geoip2.FromBytes(MaxmindDB)
+	db, err := getGeoIPReader()
 	if err != nil {
 		return "---"
 	}
-	defer db.Close()
 	ip := net.ParseIP(address)
 	record, err := db.City(ip)
 	if err != nil {
@@ -101,3 +354,173 @@ func getGeoIP(ctx context.Context, address string) string {
 		record.Country.IsoCode,
 	)
 }
+
+// Returns the latitude and longitude of an IP address from the GeoIP
+// database, with false when the location is unknown
+func getGeoIPCoordinates(address string) (float64, float64, bool) {
+	db, err := getGeoIPReader()
+	if err != nil {
+		return 0, 0, false
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return 0, 0, false
+	}
+	record, err := db.City(ip)
+	if err != nil {
+		return 0, 0, false
+	}
+	lat := record.Location.Latitude
+	lon := record.Location.Longitude
+	if lat == 0 && lon == 0 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// Returns the configured latitude and longitude of our node, falling back to
+// the location of our public IP, with false when neither is known
+func getHomeCoordinates() (float64, float64, bool) {
+	cfg := config.GetConfig()
+	if cfg.App.HomeLatitude != 0 || cfg.App.HomeLongitude != 0 {
+		return cfg.App.HomeLatitude, cfg.App.HomeLongitude, true
+	}
+	if publicIP == nil {
+		return 0, 0, false
+	}
+	return getGeoIPCoordinates(publicIP.String())
+}
+
+// Mean radius of the Earth in kilometers
+const earthRadiusKm = 6371.0
+
+// Returns the great-circle distance in kilometers between two coordinates
+// using the haversine formula
+func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(deg float64) float64 {
+		return deg * math.Pi / 180
+	}
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*
+			math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Returns the distance in kilometers from our node to a peer, or 0 when
+// either location is unknown
+func getPeerDistance(address string) float64 {
+	homeLat, homeLon, ok := getHomeCoordinates()
+	if !ok {
+		return 0
+	}
+	lat, lon, ok := getGeoIPCoordinates(address)
+	if !ok {
+		return 0
+	}
+	return haversineDistance(homeLat, homeLon, lat, lon)
+}
+
+// Current time, which can be replaced in tests
+var timeNow = time.Now
+
+// Formats a timestamp for display in the configured time zone
+func formatTime(t time.Time, layout string) string {
+	return t.In(config.GetConfig().Location()).Format(layout)
+}
+
+// Truncates a string to a maximum number of runes, with an ellipsis
+func truncateString(s string, maxLen int) string {
+	r := []rune(s)
+	if len(r) <= maxLen {
+		return s
+	}
+	if maxLen < 1 {
+		return ""
+	}
+	return string(r[:maxLen-1]) + "…"
+}
+
+// Byte size units, in increasing magnitude
+var (
+	binaryByteUnits  = []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	decimalByteUnits = []string{"B", "KB", "MB", "GB", "TB"}
+)
+
+// Returns a byte size scaled to the largest fitting unit, as separate value
+// and unit strings. Precision shrinks as the value grows, so sizes stay a
+// similar width.
+func getByteSize(n uint64, decimal bool) (string, string) {
+	base := 1024.0
+	units := binaryByteUnits
+	if decimal {
+		base = 1000.0
+		units = decimalByteUnits
+	}
+	if float64(n) < base {
+		return fmt.Sprintf("%d", n), units[0]
+	}
+	div := 1.0
+	idx := 0
+	for float64(n)/div >= base && idx < len(units)-1 {
+		div *= base
+		idx++
+	}
+	for {
+		// Round before picking the precision, so a value like 9.995 becomes
+		// 10.0 rather than 10.00
+		precision := 2
+		value := roundByteSize(n, div, precision)
+		for precision > 0 && value >= math.Pow10(3-precision) {
+			precision--
+			value = roundByteSize(n, div, precision)
+		}
+		// Rounding up to the base moves to the next unit, so 1024KiB is
+		// shown as 1.00MiB
+		if value >= base && idx < len(units)-1 {
+			div *= base
+			idx++
+			continue
+		}
+		return fmt.Sprintf("%.*f", precision, value), units[idx]
+	}
+}
+
+// Returns n/div rounded to the given number of decimal places
+func roundByteSize(n uint64, div float64, precision int) float64 {
+	scale := math.Pow10(precision)
+	return math.Round(float64(n)*scale/div) / scale
+}
+
+// Formats a byte size with binary units, like 1.50GiB
+func formatBytes(n uint64) string {
+	value, unit := getByteSize(n, false)
+	return value + unit
+}
+
+// Formats a byte size with decimal units, like 1.61GB
+func formatBytesDecimal(n uint64) string {
+	value, unit := getByteSize(n, true)
+	return value + unit
+}
+
+// Returns whether memory sizes use decimal units
+func useDecimalMemoryUnits() bool {
+	cfg := config.GetConfig()
+	return strings.ToUpper(cfg.App.MemoryUnit) == "GB"
+}
+
+// Returns a memory size in the configured binary or decimal units, as
+// separate value and unit strings
+func getMemorySize(n uint64) (string, string) {
+	return getByteSize(n, useDecimalMemoryUnits())
+}
+
+// Formats a memory size in the configured binary or decimal units
+func formatMemory(n uint64) string {
+	if useDecimalMemoryUnits() {
+		return formatBytesDecimal(n)
+	}
+	return formatBytes(n)
+}
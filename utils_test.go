@@ -0,0 +1,1029 @@
+// Copyright 2025 Blink Labs Software
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blinklabs-io/nview/internal/config"
+)
+
+func TestParseNodeVersion(t *testing.T) {
+	testDefs := []struct {
+		output   string
+		version  string
+		revision string
+	}{
+		// cardano-node
+		{
+			output: "cardano-node 10.1.4 - linux-x86_64 - ghc-8.10\n" +
+				"git rev 1f63dbf2ab39e0b32bf6901dc203866d3e37de08\n",
+			version:  "10.1.4",
+			revision: "1f63dbf2",
+		},
+		{
+			output: "cardano-node 10.2.0-rc1 - darwin-aarch64 - ghc-9.6\n" +
+				"git revision: 0123456789abcdef\n",
+			version:  "10.2.0-rc1",
+			revision: "01234567",
+		},
+		{
+			output:   "cardano-node 8.7.3 - linux-x86_64 - ghc-8.10\n",
+			version:  "8.7.3",
+			revision: "N/A",
+		},
+		// Dingo
+		{
+			output:   "dingo v0.4.1 (commit 4e3a1d2)\n",
+			version:  "v0.4.1",
+			revision: "4e3a1d2",
+		},
+		{
+			output:   "dingo v0.5.0-beta.2 (commit 9f8e7d6c5b4a39281706)",
+			version:  "v0.5.0-beta.2",
+			revision: "9f8e7d6c",
+		},
+		{
+			output:   "dingo 0.4.1",
+			version:  "0.4.1",
+			revision: "N/A",
+		},
+	}
+	for _, testDef := range testDefs {
+		version, revision, err := parseNodeVersion(testDef.output)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", testDef.output, err)
+			continue
+		}
+		if version != testDef.version {
+			t.Errorf(
+				"%q: got version %q, expected %q",
+				testDef.output,
+				version,
+				testDef.version,
+			)
+		}
+		if revision != testDef.revision {
+			t.Errorf(
+				"%q: got revision %q, expected %q",
+				testDef.output,
+				revision,
+				testDef.revision,
+			)
+		}
+	}
+}
+
+func TestParseNodeVersionInvalid(t *testing.T) {
+	for _, output := range []string{"", "command not found", "Usage: node"} {
+		version, revision, err := parseNodeVersion(output)
+		if err == nil {
+			t.Errorf("%q: expected an error", output)
+		}
+		if version != "N/A" || revision != "N/A" {
+			t.Errorf(
+				"%q: got %q, %q, expected N/A for both",
+				output,
+				version,
+				revision,
+			)
+		}
+	}
+}
+
+// Stubs running the node binary, returning a count of the times it's run
+func setNodeVersionFixture(
+	t *testing.T,
+	now *time.Time,
+	err *error,
+) *int {
+	t.Helper()
+	oldNow := timeNow
+	oldRunNodeVersion := runNodeVersion
+	oldNodeVersionCache := nodeVersionCache
+	t.Cleanup(func() {
+		timeNow = oldNow
+		runNodeVersion = oldRunNodeVersion
+		nodeVersionCache = oldNodeVersionCache
+		promMetrics = nil
+		processMetrics = nil
+	})
+	nodeVersionCache.pid = 0
+	nodeVersionCache.version = ""
+	nodeVersionCache.revision = ""
+	nodeVersionCache.err = nil
+	nodeVersionCache.expires = time.Time{}
+	promMetrics = nil
+	processMetrics = nil
+	timeNow = func() time.Time { return *now }
+	runs := new(int)
+	runNodeVersion = func() (string, string, error) {
+		*runs++
+		if *err != nil {
+			return "N/A", "N/A", *err
+		}
+		return "10.1.4", "1f63dbf2", nil
+	}
+	return runs
+}
+
+func TestGetNodeVersionFailure(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	execErr := errors.New("executable file not found in $PATH")
+	runs := setNodeVersionFixture(t, &now, &execErr)
+	checkVersion := func(
+		expectedVersion, expectedRevision string,
+		expectedRuns int,
+	) {
+		t.Helper()
+		version, revision, _ := getNodeVersion()
+		if version != expectedVersion || revision != expectedRevision {
+			t.Errorf(
+				"got %q, %q, expected %q, %q",
+				version,
+				revision,
+				expectedVersion,
+				expectedRevision,
+			)
+		}
+		if *runs != expectedRuns {
+			t.Errorf("got %d runs, expected %d", *runs, expectedRuns)
+		}
+	}
+	// The failure is returned, and cached rather than run every refresh
+	if _, _, err := getNodeVersion(); !errors.Is(err, execErr) {
+		t.Errorf("got error %v, expected %v", err, execErr)
+	}
+	checkVersion("N/A", "N/A", 1)
+	now = now.Add(nodeVersionRetry - time.Second)
+	checkVersion("N/A", "N/A", 1)
+	// The build info metric is used while the failure is cached, and kept
+	promMetrics = &PromMetrics{
+		BuildVersion:  "10.1.3",
+		BuildRevision: "0123456789abcdef",
+	}
+	checkVersion("10.1.3", "01234567", 1)
+	promMetrics = nil
+	checkVersion("10.1.3", "01234567", 1)
+	// The node binary is retried once the failure expires
+	now = now.Add(time.Second)
+	checkVersion("N/A", "N/A", 2)
+	// A version from the node binary is kept for the life of the process
+	execErr = nil
+	now = now.Add(nodeVersionRetry)
+	checkVersion("10.1.4", "1f63dbf2", 3)
+	now = now.Add(24 * time.Hour)
+	checkVersion("10.1.4", "1f63dbf2", 3)
+	// A new node process runs the node binary again
+	processMetrics = &fakeProcess{pid: 5678}
+	checkVersion("10.1.4", "1f63dbf2", 4)
+}
+
+func TestGetNodeVersionExecDisabled(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var execErr error
+	runs := setNodeVersionFixture(t, &now, &execErr)
+	cfg := config.GetConfig()
+	oldRemoteMode := cfg.App.RemoteMode
+	t.Cleanup(func() {
+		cfg.App.RemoteMode = oldRemoteMode
+	})
+	cfg.App.RemoteMode = true
+	version, revision, err := getNodeVersion()
+	if version != "N/A" || revision != "N/A" || err == nil {
+		t.Errorf(
+			"got %q, %q, %v, expected N/A and an error",
+			version,
+			revision,
+			err,
+		)
+	}
+	promMetrics = &PromMetrics{BuildVersion: "10.1.3"}
+	version, revision, err = getNodeVersion()
+	if version != "10.1.3" || revision != "N/A" || err != nil {
+		t.Errorf("got %q, %q, %v, expected 10.1.3, N/A", version, revision, err)
+	}
+	if *runs != 0 {
+		t.Errorf("got %d runs, expected the node binary not to be run", *runs)
+	}
+}
+
+func TestRefreshSignalWakesAll(t *testing.T) {
+	refresh := newRefreshSignal()
+	var ready, woken sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		ready.Add(1)
+		woken.Add(1)
+		go func() {
+			defer woken.Done()
+			ch := refresh.C()
+			ready.Done()
+			<-ch
+		}()
+	}
+	ready.Wait()
+	refresh.trigger()
+	done := make(chan struct{})
+	go func() {
+		woken.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for workers to wake")
+	}
+	// Later waiters block until the next trigger
+	select {
+	case <-refresh.C():
+		t.Errorf("refresh signal still closed after trigger")
+	default:
+	}
+}
+
+func TestSleepOrRefresh(t *testing.T) {
+	testDefs := []struct {
+		name     string
+		duration time.Duration
+		trigger  bool
+		cancel   bool
+		expected bool
+	}{
+		{name: "timeout", duration: time.Millisecond, expected: true},
+		{name: "refresh", duration: time.Hour, trigger: true, expected: true},
+		{name: "cancelled", duration: time.Hour, cancel: true, expected: false},
+	}
+	for _, testDef := range testDefs {
+		t.Run(testDef.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if testDef.cancel {
+				cancel()
+			}
+			refresh := newRefreshSignal()
+			done := make(chan struct{})
+			defer close(done)
+			if testDef.trigger {
+				// Keep triggering, since the sleep may not be waiting yet
+				go func() {
+					for {
+						select {
+						case <-done:
+							return
+						case <-time.After(time.Millisecond):
+							refresh.trigger()
+						}
+					}
+				}()
+			}
+			got := sleepOrRefresh(ctx, testDef.duration, refresh)
+			if got != testDef.expected {
+				t.Errorf("got %v, expected %v", got, testDef.expected)
+			}
+		})
+	}
+}
+
+func TestGetByteSize(t *testing.T) {
+	testDefs := []struct {
+		n       uint64
+		decimal bool
+		value   string
+		unit    string
+	}{
+		{n: 0, value: "0", unit: "B"},
+		{n: 1023, value: "1023", unit: "B"},
+		{n: 1024, value: "1.00", unit: "KiB"},
+		{n: 1536, value: "1.50", unit: "KiB"},
+		// 9.994KiB and 9.995KiB, which rounds up to the next width
+		{n: 10234, value: "9.99", unit: "KiB"},
+		{n: 10235, value: "10.0", unit: "KiB"},
+		// 99.949KiB and 99.950KiB
+		{n: 102348, value: "99.9", unit: "KiB"},
+		{n: 102349, value: "100", unit: "KiB"},
+		// 1023.999KiB, which rounds up to the next unit
+		{n: 1048575, value: "1.00", unit: "MiB"},
+		{n: 1048576, value: "1.00", unit: "MiB"},
+		{n: 12 * 1024 * 1024 * 1024, value: "12.0", unit: "GiB"},
+		{n: 5 << 50, value: "5120", unit: "TiB"},
+		{n: 999, decimal: true, value: "999", unit: "B"},
+		{n: 1000, decimal: true, value: "1.00", unit: "KB"},
+		{n: 9994, decimal: true, value: "9.99", unit: "KB"},
+		{n: 9995, decimal: true, value: "10.0", unit: "KB"},
+		{n: 99949, decimal: true, value: "99.9", unit: "KB"},
+		{n: 99950, decimal: true, value: "100", unit: "KB"},
+		{n: 999499, decimal: true, value: "999", unit: "KB"},
+		{n: 999500, decimal: true, value: "1.00", unit: "MB"},
+		{n: 1610612736, decimal: true, value: "1.61", unit: "GB"},
+	}
+	for _, testDef := range testDefs {
+		value, unit := getByteSize(testDef.n, testDef.decimal)
+		if value != testDef.value || unit != testDef.unit {
+			t.Errorf(
+				"%d (decimal %v): got %s%s, expected %s%s",
+				testDef.n,
+				testDef.decimal,
+				value,
+				unit,
+				testDef.value,
+				testDef.unit,
+			)
+		}
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	testDefs := []struct {
+		s        string
+		maxLen   int
+		expected string
+	}{
+		{s: "Cardano Node", maxLen: 19, expected: "Cardano Node"},
+		{
+			s:        "Nineteen chars long",
+			maxLen:   19,
+			expected: "Nineteen chars long",
+		},
+		{
+			s:        "Twenty chars is long",
+			maxLen:   19,
+			expected: "Twenty chars is lo…",
+		},
+		// Multi-byte characters count as one each
+		{
+			s:        "Nœud Cardano ☕☕☕☕☕☕",
+			maxLen:   19,
+			expected: "Nœud Cardano ☕☕☕☕☕☕",
+		},
+		{
+			s:        "Nœud Cardano ☕☕☕☕☕☕☕",
+			maxLen:   19,
+			expected: "Nœud Cardano ☕☕☕☕☕…",
+		},
+		{s: "abc", maxLen: 1, expected: "…"},
+		{s: "abc", maxLen: 0, expected: ""},
+		{s: "", maxLen: 0, expected: ""},
+	}
+	for _, testDef := range testDefs {
+		got := truncateString(testDef.s, testDef.maxLen)
+		if got != testDef.expected {
+			t.Errorf(
+				"%q to %d: got %q, expected %q",
+				testDef.s,
+				testDef.maxLen,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetNodeTextLongName(t *testing.T) {
+	setPanelFixtures(t)
+	cfg := config.GetConfig()
+	cfg.App.NodeName = "Blink Labs Relay Frankfurt 1"
+	got := getNodeText(context.Background())
+	expected := " [green]Name       : [white]Blink Labs Relay F…\n"
+	if !strings.HasPrefix(got, expected) {
+		t.Errorf("got %q, expected it to start with %q", got, expected)
+	}
+}
+
+func TestUpdatePublicIPAirGapped(t *testing.T) {
+	cfg := config.GetConfig()
+	oldAirGapped := cfg.App.AirGapped
+	oldLookup := lookupPublicIP
+	t.Cleanup(func() {
+		cfg.App.AirGapped = oldAirGapped
+		lookupPublicIP = oldLookup
+		publicIP = nil
+	})
+	cfg.App.AirGapped = true
+	publicIP = nil
+	var lookups int
+	lookupPublicIP = func(ctx context.Context) (net.IP, error) {
+		lookups++
+		return net.ParseIP("198.51.100.7"), nil
+	}
+	updatePublicIP(context.Background())
+	if lookups != 0 || publicIP != nil {
+		t.Errorf(
+			"got %d lookups and public IP %v, expected none",
+			lookups,
+			publicIP,
+		)
+	}
+}
+
+func TestUpdatePublicIPRetry(t *testing.T) {
+	oldLookup := lookupPublicIP
+	oldWait := waitForPublicIP
+	t.Cleanup(func() {
+		lookupPublicIP = oldLookup
+		waitForPublicIP = oldWait
+		publicIP = nil
+	})
+	publicIP = nil
+	// Results of each lookup, with nil for a failure
+	results := []net.IP{
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		net.ParseIP("198.51.100.7"),
+		nil,
+		net.ParseIP("198.51.100.8"),
+	}
+	lookups := 0
+	lookupPublicIP = func(ctx context.Context) (net.IP, error) {
+		ip := results[lookups]
+		lookups++
+		if ip == nil {
+			return nil, errors.New("i/o timeout")
+		}
+		return ip, nil
+	}
+	var waits []time.Duration
+	waitForPublicIP = func(ctx context.Context, d time.Duration) bool {
+		waits = append(waits, d)
+		// The last lookup is kept until the next one
+		if lookups == 10 &&
+			(publicIP == nil || publicIP.String() != "198.51.100.7") {
+			t.Errorf("got public IP %v after a failed refresh", publicIP)
+		}
+		return lookups < len(results)
+	}
+	updatePublicIP(context.Background())
+	// Failures back off up to the maximum, and a success resets the backoff
+	expected := []time.Duration{
+		5 * time.Second,
+		10 * time.Second,
+		20 * time.Second,
+		40 * time.Second,
+		80 * time.Second,
+		160 * time.Second,
+		publicIPRetryMax,
+		publicIPRetryMax,
+		publicIPRefresh,
+		publicIPRetryMin,
+		publicIPRefresh,
+	}
+	if !reflect.DeepEqual(waits, expected) {
+		t.Errorf("got waits %v, expected %v", waits, expected)
+	}
+	if publicIP == nil || publicIP.String() != "198.51.100.8" {
+		t.Errorf("got public IP %v, expected 198.51.100.8", publicIP)
+	}
+}
+
+func TestUpdatePublicIPCancelled(t *testing.T) {
+	oldLookup := lookupPublicIP
+	t.Cleanup(func() {
+		lookupPublicIP = oldLookup
+		publicIP = nil
+	})
+	lookups := 0
+	lookupPublicIP = func(ctx context.Context) (net.IP, error) {
+		lookups++
+		return nil, errors.New("i/o timeout")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		updatePublicIP(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the lookup to stop once cancelled")
+	}
+	if lookups != 1 {
+		t.Errorf("got %d lookups, expected 1", lookups)
+	}
+}
+
+// Starts a DNS server which answers A queries with the given IP, returning
+// its address and a channel of the names queried
+func startFakeDNSServer(t *testing.T, ip net.IP) (string, <-chan string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen for DNS queries: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	queries := make(chan string, 10)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			// Header, then the question's name labels, type, and class
+			if n < 17 {
+				continue
+			}
+			var labels []string
+			pos := 12
+			for pos < n && buf[pos] != 0 {
+				size := int(buf[pos])
+				labels = append(labels, string(buf[pos+1:pos+1+size]))
+				pos += size + 1
+			}
+			question := buf[12 : pos+5]
+			queries <- strings.Join(labels, ".")
+			resp := []byte{
+				buf[0], buf[1], // ID
+				0x81, 0x80, // Response, recursion desired and available
+				0, 1, // Questions
+				0, 1, // Answers
+				0, 0, // Authority records
+				0, 0, // Additional records
+			}
+			resp = append(resp, question...)
+			resp = append(resp,
+				0xc0, 12, // Name, pointing to the question
+				0, 1, // Type A
+				0, 1, // Class IN
+				0, 0, 0, 60, // TTL
+				0, 4, // Data length
+			)
+			resp = append(resp, ip.To4()...)
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), queries
+}
+
+func TestGetPublicIPCustomResolver(t *testing.T) {
+	cfg := config.GetConfig()
+	oldResolver := cfg.App.PublicIPResolver
+	oldQuery := cfg.App.PublicIPQuery
+	t.Cleanup(func() {
+		cfg.App.PublicIPResolver = oldResolver
+		cfg.App.PublicIPQuery = oldQuery
+	})
+	addr, queries := startFakeDNSServer(t, net.ParseIP("203.0.113.9"))
+	cfg.App.PublicIPResolver = addr
+	cfg.App.PublicIPQuery = "whoami.example.com"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ip, err := getPublicIP(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !ip.Equal(net.ParseIP("203.0.113.9")) {
+		t.Errorf("got %v, expected 203.0.113.9", ip)
+	}
+	select {
+	case query := <-queries:
+		if query != "whoami.example.com" {
+			t.Errorf("got query %q, expected whoami.example.com", query)
+		}
+	default:
+		t.Errorf("expected the configured resolver to be queried")
+	}
+}
+
+func TestUpdatePublicIPSkipsLookup(t *testing.T) {
+	cfg := config.GetConfig()
+	oldPublicIP := cfg.App.PublicIP
+	oldDisable := cfg.App.DisablePublicIP
+	oldLookup := lookupPublicIP
+	t.Cleanup(func() {
+		cfg.App.PublicIP = oldPublicIP
+		cfg.App.DisablePublicIP = oldDisable
+		lookupPublicIP = oldLookup
+		publicIP = nil
+	})
+	lookupPublicIP = func(ctx context.Context) (net.IP, error) {
+		t.Errorf("expected no DNS lookup")
+		return nil, errors.New("unexpected lookup")
+	}
+	testDefs := []struct {
+		publicIP string
+		disable  bool
+		expected string
+	}{
+		// A static public IP is used without a lookup, even when disabled
+		{publicIP: "198.51.100.7", expected: "198.51.100.7"},
+		{publicIP: "2001:db8::7", disable: true, expected: "2001:db8::7"},
+		// Disabling the lookup leaves the public IP unknown
+		{disable: true},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.PublicIP = testDef.publicIP
+		cfg.App.DisablePublicIP = testDef.disable
+		publicIP = nil
+		updatePublicIP(context.Background())
+		got := ""
+		if publicIP != nil {
+			got = publicIP.String()
+		}
+		if got != testDef.expected {
+			t.Errorf(
+				"%q, disabled %v: got %q, expected %q",
+				testDef.publicIP,
+				testDef.disable,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+type fakePeerResolver struct {
+	names map[string][]string
+	err   error
+	wait  bool
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *fakePeerResolver) LookupAddr(
+	ctx context.Context,
+	addr string,
+) ([]string, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, addr)
+	r.mu.Unlock()
+	if r.wait {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.names[addr], nil
+}
+
+func (r *fakePeerResolver) getCalls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func setPeerResolverFixture(t *testing.T, resolver *fakePeerResolver) {
+	oldResolver := peerResolver
+	t.Cleanup(func() {
+		peerResolver = oldResolver
+	})
+	peerResolver = resolver
+}
+
+func TestGetPeerHostname(t *testing.T) {
+	resolver := &fakePeerResolver{
+		names: map[string][]string{
+			"192.0.2.1":   {"relay1.example.com.", "relay1.example.net."},
+			"2001:db8::1": {"relay6.example.com."},
+		},
+	}
+	setPeerResolverFixture(t, resolver)
+	testDefs := []struct {
+		address  string
+		expected string
+	}{
+		// The trailing dot is trimmed from the first name
+		{address: "192.0.2.1", expected: "relay1.example.com"},
+		{address: "2001:db8::1", expected: "relay6.example.com"},
+		// No PTR record
+		{address: "192.0.2.2", expected: ""},
+	}
+	for _, testDef := range testDefs {
+		got := getPeerHostname(context.Background(), testDef.address)
+		if got != testDef.expected {
+			t.Errorf(
+				"%s: got %q, expected %q",
+				testDef.address,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetPeerHostnameFailure(t *testing.T) {
+	testDefs := []struct {
+		resolver *fakePeerResolver
+	}{
+		{
+			resolver: &fakePeerResolver{
+				err: &net.DNSError{
+					Err:        "no such host",
+					Name:       "2.2.0.192.in-addr.arpa.",
+					IsNotFound: true,
+				},
+			},
+		},
+		// The lookup is abandoned when the context is done
+		{resolver: &fakePeerResolver{wait: true}},
+	}
+	for _, testDef := range testDefs {
+		setPeerResolverFixture(t, testDef.resolver)
+		ctx, cancel := context.WithTimeout(
+			context.Background(),
+			50*time.Millisecond,
+		)
+		got := getPeerHostname(ctx, "192.0.2.2")
+		cancel()
+		if got != "" {
+			t.Errorf("got %q, expected an empty hostname", got)
+		}
+	}
+}
+
+func TestGetGeoIP(t *testing.T) {
+	testDefs := []struct {
+		address  string
+		expected string
+	}{
+		{address: "8.8.8.8", expected: "US"},
+		{address: "2001:4860:4860::8888", expected: "US"},
+		{address: "2001:67c:2e8:22::c100:68b", expected: "Amsterdam, NL"},
+		// Documentation and malformed addresses have no location
+		{address: "2001:db8::1", expected: "---"},
+		{address: "[2001:4860:4860::8888]", expected: "---"},
+	}
+	for _, testDef := range testDefs {
+		got := getGeoIP(context.Background(), testDef.address)
+		if got != testDef.expected {
+			t.Errorf(
+				"%s: got %q, expected %q",
+				testDef.address,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestHaversineDistance(t *testing.T) {
+	testDefs := []struct {
+		lat1, lon1 float64
+		lat2, lon2 float64
+		expected   float64
+	}{
+		// Same point
+		{lat1: 51.5074, lon1: -0.1278, lat2: 51.5074, lon2: -0.1278},
+		// London to Paris
+		{
+			lat1: 51.5074, lon1: -0.1278,
+			lat2: 48.8566, lon2: 2.3522,
+			expected: 343.6,
+		},
+		// New York to Los Angeles
+		{
+			lat1: 40.7128, lon1: -74.0060,
+			lat2: 34.0522, lon2: -118.2437,
+			expected: 3935.7,
+		},
+		// Sydney to Tokyo
+		{
+			lat1: -33.8688, lon1: 151.2093,
+			lat2: 35.6762, lon2: 139.6503,
+			expected: 7826.6,
+		},
+		// Antipodal points and pole to pole are half the circumference
+		{lat1: 0, lon1: 0, lat2: 0, lon2: 180, expected: 20015.1},
+		{lat1: 90, lon1: 0, lat2: -90, lon2: 0, expected: 20015.1},
+	}
+	for _, testDef := range testDefs {
+		got := haversineDistance(
+			testDef.lat1,
+			testDef.lon1,
+			testDef.lat2,
+			testDef.lon2,
+		)
+		if math.Abs(got-testDef.expected) > 1 {
+			t.Errorf(
+				"(%v, %v) to (%v, %v): got %.1f, expected %.1f",
+				testDef.lat1,
+				testDef.lon1,
+				testDef.lat2,
+				testDef.lon2,
+				got,
+				testDef.expected,
+			)
+		}
+		// Distance is the same in both directions
+		reverse := haversineDistance(
+			testDef.lat2,
+			testDef.lon2,
+			testDef.lat1,
+			testDef.lon1,
+		)
+		if math.Abs(got-reverse) > 1e-6 {
+			t.Errorf("got %.1f in reverse, expected %.1f", reverse, got)
+		}
+	}
+}
+
+func TestGetPeerDistance(t *testing.T) {
+	cfg := config.GetConfig()
+	oldLat := cfg.App.HomeLatitude
+	oldLon := cfg.App.HomeLongitude
+	t.Cleanup(func() {
+		cfg.App.HomeLatitude = oldLat
+		cfg.App.HomeLongitude = oldLon
+		publicIP = nil
+	})
+	// Nothing is known about our own location
+	cfg.App.HomeLatitude = 0
+	cfg.App.HomeLongitude = 0
+	publicIP = nil
+	if _, _, ok := getHomeCoordinates(); ok {
+		t.Errorf("expected unknown home coordinates")
+	}
+	if got := getPeerDistance("8.8.8.8"); got != 0 {
+		t.Errorf("got %v, expected 0 without home coordinates", got)
+	}
+	// Our public IP location is the fallback
+	ip := net.ParseIP("2001:67c:2e8:22::c100:68b")
+	publicIP = &ip
+	lat, lon, ok := getHomeCoordinates()
+	expectedLat, expectedLon, _ := getGeoIPCoordinates(ip.String())
+	if !ok || lat != expectedLat || lon != expectedLon {
+		t.Errorf(
+			"got (%v, %v, %v), expected (%v, %v, true)",
+			lat,
+			lon,
+			ok,
+			expectedLat,
+			expectedLon,
+		)
+	}
+	// Configured coordinates take precedence
+	cfg.App.HomeLatitude = 51.5074
+	cfg.App.HomeLongitude = -0.1278
+	lat, lon, ok = getHomeCoordinates()
+	if !ok || lat != 51.5074 || lon != -0.1278 {
+		t.Errorf(
+			"got (%v, %v, %v), expected configured coordinates",
+			lat,
+			lon,
+			ok,
+		)
+	}
+	peerLat, peerLon, ok := getGeoIPCoordinates("2001:67c:2e8:22::c100:68b")
+	if !ok {
+		t.Fatalf("expected coordinates for the peer")
+	}
+	expected := haversineDistance(51.5074, -0.1278, peerLat, peerLon)
+	if got := getPeerDistance("2001:67c:2e8:22::c100:68b"); got != expected {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+	// Peers without a location have no distance
+	if got := getPeerDistance("2001:db8::1"); got != 0 {
+		t.Errorf("got %v, expected 0 for an unknown location", got)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	cfg := config.GetConfig()
+	oldCfg := *cfg
+	t.Cleanup(func() {
+		*config.GetConfig() = oldCfg
+	})
+	instant := time.Date(2025, 6, 1, 12, 34, 56, 0, time.UTC)
+	testDefs := []struct {
+		timezone string
+		layout   string
+		expected string
+	}{
+		{timezone: "UTC", layout: time.TimeOnly, expected: "12:34:56"},
+		{
+			timezone: "UTC",
+			layout:   time.RFC3339,
+			expected: "2025-06-01T12:34:56Z",
+		},
+		{
+			timezone: "America/New_York",
+			layout:   time.TimeOnly,
+			expected: "08:34:56",
+		},
+		{
+			timezone: "Asia/Tokyo",
+			layout:   time.RFC3339,
+			expected: "2025-06-01T21:34:56+09:00",
+		},
+	}
+	for _, testDef := range testDefs {
+		t.Setenv("TIMEZONE", testDef.timezone)
+		if _, err := config.LoadConfig(""); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		got := formatTime(instant, testDef.layout)
+		if got != testDef.expected {
+			t.Errorf(
+				"%s: got %s, expected %s",
+				testDef.timezone,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}
+
+func TestGetMemorySize(t *testing.T) {
+	cfg := config.GetConfig()
+	oldUnit := cfg.App.MemoryUnit
+	t.Cleanup(func() {
+		cfg.App.MemoryUnit = oldUnit
+	})
+	testDefs := []struct {
+		unit     string
+		n        uint64
+		value    string
+		label    string
+		expected string
+	}{
+		// Binary units divide by 1024
+		{
+			unit:     "GiB",
+			n:        5 << 30,
+			value:    "5.00",
+			label:    "GiB",
+			expected: "5.00GiB",
+		},
+		// Decimal units divide by 1000
+		{
+			unit:     "GB",
+			n:        5 << 30,
+			value:    "5.37",
+			label:    "GB",
+			expected: "5.37GB",
+		},
+		{
+			unit:     "gb",
+			n:        5000000000,
+			value:    "5.00",
+			label:    "GB",
+			expected: "5.00GB",
+		},
+		{
+			unit:     "GB",
+			n:        512 << 20,
+			value:    "537",
+			label:    "MB",
+			expected: "537MB",
+		},
+		// Binary units are the default
+		{
+			unit:     "",
+			n:        512 << 20,
+			value:    "512",
+			label:    "MiB",
+			expected: "512MiB",
+		},
+	}
+	for _, testDef := range testDefs {
+		cfg.App.MemoryUnit = testDef.unit
+		value, label := getMemorySize(testDef.n)
+		if value != testDef.value || label != testDef.label {
+			t.Errorf(
+				"%q, %d: got %s %s, expected %s %s",
+				testDef.unit,
+				testDef.n,
+				value,
+				label,
+				testDef.value,
+				testDef.label,
+			)
+		}
+		if got := formatMemory(testDef.n); got != testDef.expected {
+			t.Errorf(
+				"%q, %d: got %s, expected %s",
+				testDef.unit,
+				testDef.n,
+				got,
+				testDef.expected,
+			)
+		}
+	}
+}